@@ -7,10 +7,18 @@ import (
 	"adminservice/internal/middleware"
 	"adminservice/internal/repository"
 	"adminservice/internal/services"
+	"adminservice/migrations"
 	"adminservice/utils"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"platform/client"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/migrate"
+	"platform/shutdown"
+	"platform/tracing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -21,12 +29,32 @@ func init() {
 }
 
 func main() {
+	logging.Init("admin-service")
+
 	cfg := config.New()
 
 	// Initialize database connection
 	db, err := repository.InitDB(cfg.DatabaseConfig)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(db, "admin-service", migrations.FS, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrate.OnStart(db, "admin-service", migrations.FS); err != nil {
+		slog.Error("Refusing to start against this schema", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Init("admin-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
 	}
 
 	// Initialize the app
@@ -46,12 +74,13 @@ func main() {
 	// Initialize repositories
 	refundRepo := repository.NewRefundRepository(db)
 
-	refundService := services.NewRefundService(refundRepo, cfg.ExternalServices.GoogleService, cfg.APIKey)
+	refundService := services.NewRefundService(refundRepo, client.NewEmailClient(cfg.ExternalServices.GoogleService, cfg.APIKey))
 
 	// Initialize handlers
 	adminHandler := handlers.NewAdminHandler(cfg)
 	refundHandler := handlers.NewRefundHandler(refundService)
 
+	app.Use(tracing.Middleware("admin-service"), logging.Middleware(), metrics.Middleware())
 	app.Use(cors.New())
 
 	// Health check endpoint
@@ -61,6 +90,13 @@ func main() {
 			"service": "admin-service",
 		})
 	})
+	app.Get("/livez", health.Livez("admin-service"))
+	app.Get("/readyz", health.Readyz("admin-service",
+		health.Database(db),
+		health.HTTP("user-service", cfg.ExternalServices.UserService+"/livez"),
+		health.HTTP("google-service", cfg.ExternalServices.GoogleService+"/livez"),
+	))
+	app.Get("/metrics", metrics.Handler())
 
 	// API routes with API key middleware
 	api := app.Group("/api", middleware.Middleware(cfg.APIKey))
@@ -82,7 +118,10 @@ func main() {
 		port = "8083" // default port
 	}
 
-	if err := app.Listen(":" + port); err != nil {
+	if err := shutdown.Serve(app, ":"+port,
+		shutdown.CloseDB(db),
+		shutdown.Step{Name: "tracing", Run: shutdownTracing},
+	); err != nil {
 		panic(fmt.Sprintf("Failed to start server: %v", err))
 	}
 }
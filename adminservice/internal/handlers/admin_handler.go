@@ -3,6 +3,8 @@ package handlers
 import (
 	"adminservice/internal/config"
 	"adminservice/internal/services"
+	"platform/client"
+	"platform/contracts"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -30,24 +32,26 @@ func (a AdminHandler) GetAllUsersHandler() fiber.Handler {
 		page, _ := strconv.Atoi(c.Query("page", "1"))
 		limit, _ := strconv.Atoi(c.Query("limit", "10"))
 
-		role := c.Query("role")
-		status := c.Query("status")
-		search := c.Query("search")
-		sort := c.Query("sort", "created_at")
-		sortDir := c.Query("sort_dir", "DESC")
-		dateFrom := c.Query("created_from")
-		dateTo := c.Query("created_to")
+		filter := client.UserFilter{
+			Page:        page,
+			Limit:       limit,
+			Role:        contracts.Role(c.Query("role")),
+			Status:      contracts.Status(c.Query("status")),
+			Search:      c.Query("search"),
+			Sort:        c.Query("sort", "created_at"),
+			SortDir:     c.Query("sort_dir", "DESC"),
+			CreatedFrom: c.Query("created_from"),
+			CreatedTo:   c.Query("created_to"),
+		}
 
-		var isVerified *bool
 		if verified := c.Query("is_verified"); verified != "" {
 			verifiedBool, err := strconv.ParseBool(verified)
 			if err == nil {
-				isVerified = &verifiedBool
+				filter.IsVerified = &verifiedBool
 			}
 		}
 
-		response, err := a.adminService.GetAllUsers(page, limit, role, status, search,
-			isVerified, dateFrom, dateTo, sort, sortDir)
+		response, err := a.adminService.GetAllUsers(c.UserContext(), filter)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to retrieve users: " + err.Error(),
@@ -4,6 +4,7 @@ import (
 	"adminservice/internal/models"
 	"adminservice/internal/services"
 	"fmt"
+	"platform/validation"
 	"strconv"
 	"time"
 
@@ -30,29 +31,8 @@ func (h *RefundHandler) HandleCreateRefundRequest() fiber.Handler {
 		username := c.Query("username")
 		email := c.Query("email")
 		var input models.RefundRequestInput
-		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body: " + err.Error(),
-			})
-		}
-
-		// Basic validation
-		if input.OrderID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Order ID is required",
-			})
-		}
-
-		if input.Amount <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Amount must be greater than 0",
-			})
-		}
-
-		if input.CardNumber == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Card number is required",
-			})
+		if verr := validation.ParseBody(c, &input); verr != nil {
+			return verr.Send(c)
 		}
 
 		// Create the refund request
@@ -172,21 +152,12 @@ func (h *RefundHandler) HandleProcessRefundRequest() fiber.Handler {
 
 		// Parse request body
 		var input models.RefundProcessInput
-		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body: " + err.Error(),
-			})
-		}
-
-		// Validate status
-		if input.Status != models.RefundStatusApproved && input.Status != models.RefundStatusRejected {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Status must be either 'approved' or 'rejected'",
-			})
+		if verr := validation.ParseBody(c, &input); verr != nil {
+			return verr.Send(c)
 		}
 
 		// Process the refund request
-		if err := h.refundService.ProcessRefundRequest(uint(id), uint(adminid), input); err != nil {
+		if err := h.refundService.ProcessRefundRequest(c.UserContext(), uint(id), uint(adminid), input); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to process refund request: " + err.Error(),
 			})
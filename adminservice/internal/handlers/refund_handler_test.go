@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"adminservice/internal/models"
+	"context"
+	"net/http/httptest"
+	"platform/contracts"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recordingRefundService keeps the decisions it is asked to process
+type recordingRefundService struct {
+	processed []models.RefundProcessInput
+}
+
+func (s *recordingRefundService) CreateRefundRequest(uint, string, string, models.RefundRequestInput) (*models.RefundRequest, error) {
+	return &models.RefundRequest{}, nil
+}
+
+func (s *recordingRefundService) GetRefundRequestByID(uint) (*models.RefundRequest, error) {
+	return &models.RefundRequest{}, nil
+}
+
+func (s *recordingRefundService) GetAllRefundRequests(int, int, map[string]interface{}) (*contracts.Page[models.RefundRequest], error) {
+	return &contracts.Page[models.RefundRequest]{}, nil
+}
+
+func (s *recordingRefundService) ProcessRefundRequest(_ context.Context, _, _ uint, input models.RefundProcessInput) error {
+	s.processed = append(s.processed, input)
+	return nil
+}
+
+func (s *recordingRefundService) GetRefundStatistics() (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func TestProcessRefundRequestStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status     string
+		wantCode   int
+		wantStatus models.RefundStatus
+	}{
+		{"APPROVED", fiber.StatusOK, models.RefundStatusApproved},
+		{"REJECTED", fiber.StatusOK, models.RefundStatusRejected},
+		{"approved", fiber.StatusUnprocessableEntity, ""},
+		{"PENDING", fiber.StatusUnprocessableEntity, ""},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			service := &recordingRefundService{}
+			app := fiber.New()
+			app.Put("/refunds/:id/process", NewRefundHandler(service).HandleProcessRefundRequest())
+
+			req := httptest.NewRequest(fiber.MethodPut, "/refunds/7/process?role=Admin&adminid=1",
+				strings.NewReader(`{"status":"`+tc.status+`","admin_note":"checked"}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tc.wantCode {
+				t.Fatalf("status %q answered %d, want %d", tc.status, resp.StatusCode, tc.wantCode)
+			}
+			if tc.wantStatus == "" {
+				if len(service.processed) != 0 {
+					t.Errorf("status %q reached the service, want it rejected first", tc.status)
+				}
+				return
+			}
+			if len(service.processed) != 1 || service.processed[0].Status != tc.wantStatus {
+				t.Errorf("service processed %+v, want one %s decision", service.processed, tc.wantStatus)
+			}
+		})
+	}
+}
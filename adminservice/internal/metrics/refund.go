@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var refundsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "refunds_processed_total",
+	Help: "Refund requests an admin approved or rejected, by the resulting status.",
+}, []string{"status"})
+
+func RecordRefundProcessed(status string) {
+	refundsProcessed.WithLabelValues(status).Inc()
+}
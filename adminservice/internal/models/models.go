@@ -1,69 +1,10 @@
 package models
 
 import (
+	"platform/contracts"
 	"time"
-
-	"gorm.io/gorm"
 )
 
-// UserRole represents the role of a user
-type UserRole string
-
-const (
-	RoleParent UserRole = "Parent"
-	RoleChild  UserRole = "Children"
-	RoleTutor  UserRole = "Tutor"
-	RoleAdmin  UserRole = "Admin"
-)
-
-// UserStatus represents the status of a user account
-type UserStatus string
-
-const (
-	StatusActive    UserStatus = "Active"
-	StatusSuspended UserStatus = "Suspended"
-	StatusBanned    UserStatus = "Banned"
-)
-
-// User model represents the user table in the database
-type User struct {
-	gorm.Model
-	Username          string     `json:"username" gorm:"uniqueIndex;not null"`
-	Password          string     `json:"-" gorm:"not null"`
-	Email             string     `json:"email" gorm:"uniqueIndex;not null"`
-	Role              UserRole   `json:"role" gorm:"type:ENUM('Parent', 'Children', 'Tutor', 'Admin');default:'Parent'"`
-	Phone             *string    `json:"phone" gorm:"type:varchar(20)"`
-	FullName          *string    `json:"full_name" gorm:"type:varchar(255)"`
-	Picture           string     `json:"picture" gorm:"type:varchar(255)"`
-	IsVerified        bool       `json:"is_verified" gorm:"default:false"`
-	Status            UserStatus `json:"status" gorm:"type:ENUM('Active', 'Suspended', 'Banned');default:'Active'"`
-	LastLoginAt       *int64     `json:"last_login_at,omitempty"`
-	AccountLocked     bool       `json:"account_locked" gorm:"default:false"`
-	PasswordChangedAt *int64     `json:"-"`
-
-	// Relations
-	Tutor *Tutor `json:"tutor,omitempty" gorm:"foreignKey:ID"`
-}
-
-// Tutor model represents a tutor profile
-type Tutor struct {
-	ID             uint    `json:"id" gorm:"primaryKey"`
-	Bio            string  `json:"bio"`
-	Qualifications string  `json:"qualifications"`
-	TeachingStyle  string  `json:"teaching_style"`
-	IsAvailable    bool    `json:"is_available" gorm:"default:false"`
-	DemoVideoURL   *string `json:"demo_video_url"`
-	Image          *string `json:"image"`
-
-	// Relations
-	ProfileID      uint             `json:"-" gorm:"column:id"`
-	Profile        User             `json:"profile" gorm:"foreignKey:ProfileID;references:ID"`
-	TutorSpecialty []TutorSpecialty `json:"specialties,omitempty" gorm:"foreignKey:TutorID"`
-	Courses        []Course         `json:"courses,omitempty" gorm:"foreignKey:TutorID"`
-	TutorReviews   []TutorReview    `json:"reviews,omitempty" gorm:"foreignKey:TutorID"`
-	Availability   *Availability    `json:"availability,omitempty" gorm:"foreignKey:TutorID"`
-}
-
 // Parent model represents a parent profile
 type Parent struct {
 	ID                  uint   `json:"id" gorm:"primaryKey"`
@@ -92,19 +33,6 @@ type Children struct {
 	CourseSubscriptions []CourseSubscription `json:"course_subscriptions,omitempty" gorm:"foreignKey:ChildrenID"`
 }
 
-// TutorSpecialty model represents a tutor's subject specialization
-type TutorSpecialty struct {
-	ID              uint   `json:"id" gorm:"primaryKey;autoIncrement"`
-	Subject         string `json:"subject"`
-	Level           string `json:"level"`
-	Certification   string `json:"certification"`
-	YearsExperience int    `json:"years_experience"`
-
-	// Relations
-	TutorID uint  `json:"tutor_id"`
-	Tutor   Tutor `json:"-" gorm:"foreignKey:TutorID"`
-}
-
 // CourseStatus represents the status of a course
 type CourseStatus string
 
@@ -126,8 +54,8 @@ type Course struct {
 	Image        *string      `json:"image"`
 
 	// Relations
-	TutorID uint  `json:"tutor_id"`
-	Tutor   Tutor `json:"tutor,omitempty" gorm:"foreignKey:TutorID"`
+	TutorID uint             `json:"tutor_id"`
+	Tutor   *contracts.Tutor `json:"tutor,omitempty" gorm:"-"`
 
 	Lessons             []Lesson             `json:"lessons,omitempty" gorm:"foreignKey:CourseID"`
 	CourseSubscriptions []CourseSubscription `json:"subscriptions,omitempty" gorm:"foreignKey:CourseID"`
@@ -244,7 +172,6 @@ type TutorReview struct {
 
 	// Relations
 	TutorID  uint   `json:"tutor_id"`
-	Tutor    Tutor  `json:"-" gorm:"foreignKey:TutorID"`
 	ParentID uint   `json:"parent_id"`
 	Parent   Parent `json:"-" gorm:"foreignKey:ParentID"`
 }
@@ -293,17 +220,3 @@ type RecentActivity struct {
 	EntityType   string    `json:"entity_type"` // Type of the related entity
 	Timestamp    time.Time `json:"timestamp"`
 }
-
-// Pagination struct for handling paginated responses
-type Pagination struct {
-	Total      int64 `json:"total"`
-	Page       int   `json:"page"`
-	PageSize   int   `json:"page_size"`
-	TotalPages int   `json:"total_pages"`
-}
-
-// PaginatedResponse is a generic struct for paginated responses
-type PaginatedResponse struct {
-	Data       interface{} `json:"data"`
-	Pagination Pagination  `json:"pagination"`
-}
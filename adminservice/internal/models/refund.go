@@ -1,18 +1,19 @@
 package models
 
 import (
+	"platform/contracts"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 // RefundStatus represents the status of a refund request
-type RefundStatus string
+type RefundStatus = contracts.RefundStatus
 
 const (
-	RefundStatusPending  RefundStatus = "PENDING"
-	RefundStatusApproved RefundStatus = "APPROVED"
-	RefundStatusRejected RefundStatus = "REJECTED"
+	RefundStatusPending  = contracts.RefundStatusPending
+	RefundStatusApproved = contracts.RefundStatusApproved
+	RefundStatusRejected = contracts.RefundStatusRejected
 )
 
 // RefundRequest represents a user's request for a refund
@@ -25,7 +26,7 @@ type RefundRequest struct {
 	Amount           float64      `json:"amount" gorm:"not null"`
 	CardNumber       string       `json:"card_number" gorm:"not null"` // Last 4 digits only
 	Reason           string       `json:"reason" gorm:"type:text"`
-	Status           RefundStatus `json:"status" gorm:"type:enum('pending','approved','rejected');default:'pending'"`
+	Status           RefundStatus `json:"status" gorm:"type:enum('PENDING','APPROVED','REJECTED');default:'PENDING'"`
 	ProcessedBy      *uint        `json:"processed_by"`
 	ProcessedAt      *time.Time   `json:"processed_at"`
 	AdminNote        string       `json:"admin_note" gorm:"type:text"`
@@ -38,21 +39,7 @@ func (RefundRequest) TableName() string {
 }
 
 // RefundRequestInput represents the input for creating a refund request
-type RefundRequestInput struct {
-	OrderID    string  `json:"order_id" validate:"required"`
-	Amount     float64 `json:"amount" validate:"required,gt=0"`
-	CardNumber string  `json:"card_number" validate:"required"`
-	Reason     string  `json:"reason"`
-}
+type RefundRequestInput = contracts.RefundRequestInput
 
 // RefundProcessInput represents the input for processing a refund request
-type RefundProcessInput struct {
-	Status    RefundStatus `json:"status" validate:"required,oneof=approved rejected"`
-	AdminNote string       `json:"admin_note"`
-}
-
-// PaginatedRefundResponse represents a paginated list of refund requests
-type PaginatedRefundResponse struct {
-	Data       []RefundRequest `json:"data"`
-	Pagination Pagination      `json:"pagination"`
-}
+type RefundProcessInput = contracts.RefundProcessInput
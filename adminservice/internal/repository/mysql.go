@@ -3,6 +3,8 @@ package repository
 import (
 	"adminservice/internal/config"
 	"fmt"
+	"log/slog"
+	"platform/metrics"
 	"time"
 
 	"gorm.io/driver/mysql"
@@ -29,7 +31,7 @@ func InitDB(config config.DatabaseConfig) (*gorm.DB, error) {
 		if err == nil {
 			break
 		}
-		fmt.Printf("Failed to connect to database (attempt %d/%d): %v\n", i+1, maxRetries, err)
+		slog.Warn("Failed to connect to database", "attempt", i+1, "max_attempts", maxRetries, "error", err)
 		time.Sleep(time.Second * 5)
 	}
 
@@ -46,8 +48,12 @@ func InitDB(config config.DatabaseConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+
 	DB = db
 
-	fmt.Println("Database connected successfully")
+	slog.Info("Database connected")
 	return db, nil
 }
@@ -1,36 +1,33 @@
 package services
 
 import (
+	"adminservice/internal/metrics"
 	"adminservice/internal/models"
 	"adminservice/internal/repository"
-	"adminservice/utils"
+	"context"
 	"fmt"
-	"math"
-	"net/url"
-
-	"github.com/valyala/fasthttp"
+	"platform/client"
+	"platform/contracts"
 )
 
 type RefundService interface {
 	CreateRefundRequest(userID uint, username, email string, input models.RefundRequestInput) (*models.RefundRequest, error)
 	GetRefundRequestByID(id uint) (*models.RefundRequest, error)
-	GetAllRefundRequests(page, pageSize int, filters map[string]interface{}) (*models.PaginatedRefundResponse, error)
-	ProcessRefundRequest(id, adminID uint, input models.RefundProcessInput) error
+	GetAllRefundRequests(page, pageSize int, filters map[string]interface{}) (*contracts.Page[models.RefundRequest], error)
+	ProcessRefundRequest(ctx context.Context, id, adminID uint, input models.RefundProcessInput) error
 	GetRefundStatistics() (map[string]interface{}, error)
 }
 
 type refundService struct {
-	refundRepo       repository.RefundRepository
-	googleServiceURL string
-	apiKey           string
+	refundRepo repository.RefundRepository
+	email      *client.EmailClient
 }
 
 // NewRefundService creates a new instance of RefundService
-func NewRefundService(refundRepo repository.RefundRepository, googleServiceURL, apiKey string) RefundService {
+func NewRefundService(refundRepo repository.RefundRepository, email *client.EmailClient) RefundService {
 	return &refundService{
-		refundRepo:       refundRepo,
-		googleServiceURL: googleServiceURL,
-		apiKey:           apiKey,
+		refundRepo: refundRepo,
+		email:      email,
 	}
 }
 
@@ -68,7 +65,7 @@ func (s *refundService) GetRefundRequestByID(id uint) (*models.RefundRequest, er
 }
 
 // GetAllRefundRequests retrieves all refund requests with pagination and filters
-func (s *refundService) GetAllRefundRequests(page, pageSize int, filters map[string]interface{}) (*models.PaginatedRefundResponse, error) {
+func (s *refundService) GetAllRefundRequests(page, pageSize int, filters map[string]interface{}) (*contracts.Page[models.RefundRequest], error) {
 	if page <= 0 {
 		page = 1
 	}
@@ -81,21 +78,14 @@ func (s *refundService) GetAllRefundRequests(page, pageSize int, filters map[str
 		return nil, err
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-
-	return &models.PaginatedRefundResponse{
-		Data: refunds,
-		Pagination: models.Pagination{
-			Total:      total,
-			Page:       page,
-			PageSize:   pageSize,
-			TotalPages: totalPages,
-		},
+	return &contracts.Page[models.RefundRequest]{
+		Data:       refunds,
+		Pagination: contracts.NewPagination(total, page, pageSize),
 	}, nil
 }
 
 // ProcessRefundRequest processes a refund request (approve or reject)
-func (s *refundService) ProcessRefundRequest(id, adminID uint, input models.RefundProcessInput) error {
+func (s *refundService) ProcessRefundRequest(ctx context.Context, id, adminID uint, input models.RefundProcessInput) error {
 	// Get the refund request to make sure it exists and check its current status
 	refund, err := s.refundRepo.GetByID(id)
 	if err != nil {
@@ -111,10 +101,11 @@ func (s *refundService) ProcessRefundRequest(id, adminID uint, input models.Refu
 	if err := s.refundRepo.UpdateStatus(id, input.Status, adminID, input.AdminNote); err != nil {
 		return err
 	}
+	metrics.RecordRefundProcessed(string(input.Status))
 
 	// If approved, send an email notification
 	if input.Status == models.RefundStatusApproved {
-		if err := s.sendRefundNotification(refund); err != nil {
+		if err := s.sendRefundNotification(ctx, refund); err != nil {
 			return fmt.Errorf("refund status updated but failed to send notification: %w", err)
 		}
 	}
@@ -123,12 +114,7 @@ func (s *refundService) ProcessRefundRequest(id, adminID uint, input models.Refu
 }
 
 // sendRefundNotification sends an email notification about the refund
-func (s *refundService) sendRefundNotification(refund *models.RefundRequest) error {
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
-
+func (s *refundService) sendRefundNotification(ctx context.Context, refund *models.RefundRequest) error {
 	// Prepare email content
 	emailTitle := "Your Refund Request Has Been Approved"
 	emailBody := fmt.Sprintf(
@@ -139,23 +125,8 @@ func (s *refundService) sendRefundNotification(refund *models.RefundRequest) err
 		refund.CardNumber[len(refund.CardNumber)-4:],
 	)
 
-	// Build the request to the email service
-	url := fmt.Sprintf("%s/api/email/send?to=%s&title=%s&body=%s",
-		s.googleServiceURL,
-		url.QueryEscape(refund.Email),
-		url.QueryEscape(emailTitle),
-		url.QueryEscape(emailBody),
-	)
-
-	fmt.Println(url)
-	utils.BuildRequest(req, "POST", nil, s.apiKey, url)
-
-	if err := fasthttp.Do(req, resp); err != nil {
-		return fmt.Errorf("email service unavailable: %w", err)
-	}
-
-	if resp.StatusCode() != fasthttp.StatusOK {
-		return fmt.Errorf("failed to send email: %s", resp.Body())
+	if err := s.email.Send(ctx, refund.Email, emailTitle, emailBody); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
 	// Mark notification as sent
@@ -0,0 +1,26 @@
+-- Baseline for the refund table, written to match the Prisma mirror column for column so
+-- it adopts a table nodeservice already pushed.
+CREATE TABLE IF NOT EXISTS `RefundRequests` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL,
+    `deleted_at` DATETIME(3) NULL,
+    `user_id` INTEGER NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `email` VARCHAR(191) NOT NULL,
+    `order_id` VARCHAR(191) NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `card_number` VARCHAR(191) NOT NULL,
+    `reason` TEXT NULL,
+    `status` ENUM('PENDING', 'APPROVED', 'REJECTED') NOT NULL DEFAULT 'PENDING',
+    `processed_by` INTEGER NULL,
+    `processed_at` DATETIME(3) NULL,
+    `admin_note` TEXT NULL,
+    `notification_sent` BOOLEAN NOT NULL DEFAULT false,
+
+    INDEX `RefundRequests_user_id_idx`(`user_id`),
+    INDEX `RefundRequests_order_id_idx`(`order_id`),
+    INDEX `RefundRequests_status_idx`(`status`),
+    INDEX `RefundRequests_created_at_idx`(`created_at`),
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
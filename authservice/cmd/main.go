@@ -2,9 +2,20 @@ package main
 
 import (
 	"authservice/internal/handlers"
+	"authservice/internal/keys"
+	"authservice/internal/repository"
+	"authservice/migrations"
 	"authservice/utils"
 	"fmt"
+	"log/slog"
 	"os"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/migrate"
+	"platform/shutdown"
+	"platform/tracing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,7 +27,98 @@ func init() {
 	API_KEY = utils.API_KEY
 }
 
+type purger interface {
+	PurgeExpired() error
+}
+
+// newStores picks where revocations and refresh tokens live, REVOCATION_STORE=memory keeps them in process
+func newStores() (repository.RevocationStore, repository.RefreshTokenStore) {
+	switch os.Getenv("REVOCATION_STORE") {
+	case "memory":
+		slog.Warn("Using in-memory token stores, revocations and sessions will not survive a restart")
+		return repository.NewMemoryRevocationStore(), repository.NewMemoryRefreshTokenStore()
+	default:
+		db, err := repository.InitDB()
+		if err != nil {
+			panic(fmt.Sprintf("Failed to initialize database: %v", err))
+		}
+		if err := migrate.OnStart(db, "auth-service", migrations.FS); err != nil {
+			panic(fmt.Sprintf("Refusing to start against this schema: %v", err))
+		}
+		return repository.NewMySQLRevocationStore(db), repository.NewMySQLRefreshTokenStore(db)
+	}
+}
+
+// runMigrate connects on its own, the in-memory token stores have no schema to migrate
+func runMigrate(args []string) error {
+	db, err := repository.InitDB()
+	if err != nil {
+		return err
+	}
+	return migrate.Command(db, "auth-service", migrations.FS, args)
+}
+
+func purgeExpired(stop <-chan struct{}, stores []purger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, store := range stores {
+			if err := store.PurgeExpired(); err != nil {
+				slog.Error("Failed to purge expired entries", "error", err)
+			}
+		}
+	}
+}
+
+// readinessChecks covers user-service, which every login goes through, and MySQL unless
+// the token stores are kept in memory
+func readinessChecks() []health.Check {
+	checks := []health.Check{
+		health.HTTP("user-service", utils.SERVICES_ROUTES.UserService+"/livez"),
+	}
+	if repository.DB != nil {
+		checks = append(checks, health.Database(repository.DB))
+	}
+	return checks
+}
+
 func main() {
+	logging.Init("auth-service")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Init("auth-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
+	}
+
+	if utils.TOKEN_ISSUE_KEY == "" {
+		slog.Warn("TOKEN_ISSUE_KEY is not set, Google sign-ins cannot be issued sessions")
+	}
+	if utils.JWT_REFRESH_SECRET == "" {
+		slog.Warn("JWT_REFRESH_SECRET is not set, refresh tokens are hashed without a secret")
+	}
+
+	keyManager, err := keys.LoadKeyManager(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"))
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load signing keys: %v", err))
+	}
+
+	revocationStore, refreshTokenStore := newStores()
+	stopPurge := make(chan struct{})
+	go purgeExpired(stopPurge, []purger{revocationStore, refreshTokenStore}, 10*time.Minute)
+
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -29,27 +131,50 @@ func main() {
 		},
 	})
 
+	app.Use(tracing.Middleware("auth-service"), logging.Middleware(), metrics.Middleware())
+
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status":  "ok",
 			"service": "auth-service",
 		})
 	})
+	app.Get("/livez", health.Livez("auth-service"))
+	app.Get("/readyz", health.Readyz("auth-service", readinessChecks()...))
+	app.Get("/metrics", metrics.Handler())
+
+	app.Get("/.well-known/jwks.json", handlers.HandleJWKS(keyManager))
+
+	// Registered ahead of the API key group so that key alone cannot reach it
+	app.Post("/token/issue", IssuerMiddleware(utils.TOKEN_ISSUE_KEY), handlers.HandleIssueToken(revocationStore, refreshTokenStore, keyManager))
 
 	auth := app.Group("", Middleware(API_KEY))
 
 	// Routes
-	auth.Post("/login", handlers.HandleLogin())
+	auth.Post("/login", handlers.HandleLogin(revocationStore, refreshTokenStore, keyManager))
+	auth.Post("/login/2fa", handlers.HandleLoginTwoFactor(revocationStore, refreshTokenStore, keyManager))
+	auth.Post("/2fa/enroll", handlers.HandleTwoFactorEnroll(revocationStore, keyManager))
+	auth.Post("/2fa/activate", handlers.HandleTwoFactorActivate(revocationStore, refreshTokenStore, keyManager))
+	auth.Post("/2fa/disable", handlers.HandleTwoFactorDisable())
+	auth.Post("/refresh", handlers.HandleRefresh(revocationStore, refreshTokenStore, keyManager))
+	auth.Post("/logout", handlers.HandleLogout(revocationStore, refreshTokenStore))
+	auth.Post("/password/forgot", handlers.HandleForgotPassword())
+	auth.Post("/password/reset", handlers.HandleResetPassword(revocationStore))
 	auth.Post("/register", handlers.HandleRegister())
-	auth.Post("/block", handlers.HandleBlockToken())
-	auth.Post("/unblock", handlers.HandleUnblockToken())
+	auth.Post("/block", handlers.HandleBlockToken(revocationStore))
+	auth.Post("/unblock", handlers.HandleUnblockToken(revocationStore))
+	auth.Get("/revoked", handlers.HandleCheckRevoked(revocationStore))
 
 	port := os.Getenv("AUTH_SERVICE_PORT")
 	if port == "" {
 		port = "8081" // default port
 	}
 
-	if err := app.Listen(":" + port); err != nil {
+	if err := shutdown.Serve(app, ":"+port,
+		shutdown.Func("token purge", func() { close(stopPurge) }),
+		shutdown.CloseDB(repository.DB),
+		shutdown.Step{Name: "tracing", Run: shutdownTracing},
+	); err != nil {
 		panic(fmt.Sprintf("Failed to start server: %v", err))
 	}
 }
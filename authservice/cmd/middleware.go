@@ -1,17 +1,32 @@
 package main
 
 import (
-	"log"
+	"crypto/subtle"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func Middleware(apiKey string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		log.Println("Address", c.IP(), "Calling", c.Method(), "Request", c.OriginalURL())
 		requestKey := c.Get("API_KEY")
 		if requestKey != apiKey {
-			log.Println("Unauthorized access attempt at", c.IP(), "with API key:", requestKey)
+			slog.WarnContext(c.UserContext(), "Rejected request with an invalid API key", "ip", c.IP(), "path", c.Path())
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// IssuerMiddleware guards /token/issue with a key only google-service holds. The API key is
+// shared by every service, which is not enough to mint a session for any username.
+func IssuerMiddleware(issueKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestKey := c.Get("API_KEY")
+		if issueKey == "" || subtle.ConstantTimeCompare([]byte(requestKey), []byte(issueKey)) != 1 {
+			slog.WarnContext(c.UserContext(), "Rejected token issue without the issuer key", "ip", c.IP())
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Unauthorized",
 			})
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"authservice/internal/keys"
+	"authservice/internal/metrics"
 	"authservice/internal/repository"
 	"authservice/internal/services"
 	"authservice/utils"
-	"fmt"
-	"os"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"platform/client"
+	"platform/contracts"
+	"platform/validation"
 	"strconv"
 	"time"
 
@@ -13,51 +20,40 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-type Claims struct {
-	jwt.RegisteredClaims
-	UserID   uint   `json:"userId"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-}
-
-var (
-	tokens  map[string]*jwt.Token  = make(map[string]*jwt.Token)
-	blocked map[string]bool        = make(map[string]bool)
-	claims  map[*jwt.Token]*Claims = make(map[*jwt.Token]*Claims)
-)
+// inactiveUserBlockTime is how long a non-active account that reached login stays blocked
+const inactiveUserBlockTime = 24 * time.Hour
 
-func BlockUser(username string) {
-	blocked_status := blocked[username]
-	if blocked_status {
-		fmt.Println("User is already blocked")
-		return
-	}
-	blocked[username] = true
+func BlockUser(store repository.RevocationStore, username string, duration time.Duration) error {
+	return store.Revoke(repository.RevokeUser, username, time.Now().Add(duration))
 }
 
-func UnBlockUser(username string) {
-	blocked[username] = false
+func UnBlockUser(store repository.RevocationStore, username string) error {
+	return store.Release(repository.RevokeUser, username)
 }
 
-func HandleBlockToken() fiber.Handler {
+func HandleBlockToken(store repository.RevocationStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		username := c.Query("username")
+		if username == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "username is required",
+			})
+		}
 		block_time_str := c.Query("time")
 		block_time_int, err := strconv.Atoi(block_time_str)
-		if err != nil {
+		if err != nil || block_time_int <= 0 {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "invalid block time",
 			})
 		}
-		block_time := time.Now().Unix() + int64(block_time_int)
-		BlockUser(username)
-		fmt.Println("User JWT Token blocked:", username, "and will be unblocked at", time.Unix(block_time, 0))
-
-		go func(block_time_int int, username string) {
-			time.Sleep(time.Duration(block_time_int) * time.Second)
-			UnBlockUser(username)
-		}(block_time_int, username)
+		block_duration := time.Duration(block_time_int) * time.Second
+		if err := BlockUser(store, username, block_duration); err != nil {
+			slog.ErrorContext(c.UserContext(), "Block user jwt error", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to block user",
+			})
+		}
+		slog.InfoContext(c.UserContext(), "User JWT blocked", "username", username, "until", time.Now().Add(block_duration))
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"message": "user is blocked",
@@ -65,7 +61,7 @@ func HandleBlockToken() fiber.Handler {
 	}
 }
 
-func HandleUnblockToken() fiber.Handler {
+func HandleUnblockToken(store repository.RevocationStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		username := c.Query("username")
 		if username == "" {
@@ -73,106 +69,196 @@ func HandleUnblockToken() fiber.Handler {
 				"error": "username is required",
 			})
 		}
-		UnBlockUser(username)
-		fmt.Println("Unblock user jwt attempt successfully")
+		if err := UnBlockUser(store, username); err != nil {
+			slog.ErrorContext(c.UserContext(), "Unblock user jwt error", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to unblock user",
+			})
+		}
+		slog.InfoContext(c.UserContext(), "User JWT unblocked", "username", username)
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"message": "user jwt is unblocked",
 		})
 	}
 }
 
-func generateNewToken(user *repository.User) *jwt.Token {
-	claim := Claims{
+// HandleCheckRevoked reports whether a token, identified by its owner and jti, has been revoked.
+// iat, when given, also catches tokens issued before the owner's last password reset.
+func HandleCheckRevoked(store repository.RevocationStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		username := c.Query("username")
+		jti := c.Query("jti")
+		sid := c.Query("sid")
+		if username == "" && jti == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "username or jti is required",
+			})
+		}
+
+		revoked := false
+		if username != "" {
+			userRevoked, err := store.IsRevoked(repository.RevokeUser, username)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "Revocation lookup error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to check revocation",
+				})
+			}
+			revoked = userRevoked
+		}
+		if !revoked && jti != "" {
+			tokenRevoked, err := store.IsRevoked(repository.RevokeToken, jti)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "Revocation lookup error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to check revocation",
+				})
+			}
+			revoked = tokenRevoked
+		}
+		if !revoked && username != "" {
+			if iat, err := strconv.ParseInt(c.Query("iat"), 10, 64); err == nil {
+				passwordRevoked, err := store.IsRevokedAt(repository.RevokePassword, username, time.Unix(iat, 0))
+				if err != nil {
+					slog.ErrorContext(c.UserContext(), "Revocation lookup error", "error", err)
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+						"error": "failed to check revocation",
+					})
+				}
+				revoked = passwordRevoked
+			}
+		}
+		if !revoked && sid != "" {
+			sessionRevoked, err := store.IsRevoked(repository.RevokeSession, sid)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "Revocation lookup error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to check revocation",
+				})
+			}
+			revoked = sessionRevoked
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"revoked": revoked,
+		})
+	}
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func generateNewToken(km *keys.KeyManager, user *contracts.User, sessionID string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	claim := contracts.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(utils.ACCESS_TOKEN_TTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "auth-service",
 		},
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		TokenUse:  contracts.TokenUseAccess,
+	}
+	return km.Sign(claim)
+}
+
+// checkUserAllowed refuses users that are blocked or no longer active
+func checkUserAllowed(store repository.RevocationStore, user *contracts.User) *fiber.Error {
+	is_blocked, err := store.IsRevoked(repository.RevokeUser, user.Username)
+	if err != nil {
+		slog.Error("Revocation lookup error", "error", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Could not verify account status")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-	claims[token] = &claim
-	return token
+
+	if is_blocked {
+		return fiber.NewError(fiber.StatusForbidden, "user jwt is blocked")
+	}
+
+	if user.Status != contracts.StatusActive {
+		slog.Warn("Login by inactive user that is not blocked", "username", user.Username)
+		if err := BlockUser(store, user.Username, inactiveUserBlockTime); err != nil {
+			slog.Error("Block user jwt error", "error", err)
+		}
+		return fiber.NewError(fiber.StatusForbidden, "invalid login attempt")
+	}
+	return nil
 }
 
-func HandleLogin() fiber.Handler {
+func HandleLogin(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req LoginRequest
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request",
-			})
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
 
 		// Forward to user service
-		user, err := services.GetUserFromUserService(utils.SERVICES_ROUTES.UserService, req.Username, req.Password)
+		user, err := services.Users.Login(c.UserContext(), req.Username, req.Password)
 		if err != nil {
-			fmt.Println("Login error:", err)
+			slog.ErrorContext(c.UserContext(), "Login error", "error", err)
+			if errors.Is(err, client.ErrAccountLocked) {
+				metrics.RecordLogin("locked")
+				return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+					"error": "Account is temporarily locked, try again later",
+				})
+			}
+			metrics.RecordLogin("failed")
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid credentials",
 			})
 		}
 
-		is_blocked := blocked[user.Username]
-
-		if is_blocked {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "user jwt is blocked",
+		if e := checkUserAllowed(revocations, user); e != nil {
+			metrics.RecordLogin("blocked")
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
 			})
 		}
 
-		if user.Status != "Active" {
-			fmt.Println("Serious error attempt: user status is not active but is not black list, username:", user.Username)
-			BlockUser(user.Username)
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "invalid login attempt",
-			})
-		}
-
-		var token *jwt.Token
-		if existingToken, ok := tokens[user.Username]; ok {
-			claim, ok := claims[existingToken]
-			if !ok || time.Now().After(claim.ExpiresAt.Time) {
-				fmt.Println("token is expired, generate a new one")
-				token = generateNewToken(user)
-				tokens[user.Username] = token
-			} else {
-				token = existingToken
-			}
-		} else {
-			token = generateNewToken(user)
-			tokens[user.Username] = token
-		}
-
-		tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Could not generate token",
-			})
-		}
-
-		return c.JSON(LoginResponse{
-			Token: tokenString,
-			User:  *user,
-		})
+		metrics.RecordLogin("succeeded")
+		return beginSession(c, refreshTokens, km, user, req.DeviceID)
 	}
 }
 
 func HandleRegister() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req RegisterRequest
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Request"})
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
 
-		err := services.AddUserUserService(utils.SERVICES_ROUTES.UserService, req.Username, req.Password, req.Email, req.Role, req.Fullname, req.Phone)
+		err := services.Users.Add(c.UserContext(), contracts.NewUser{
+			Username: req.Username,
+			Password: req.Password,
+			Email:    req.Email,
+			Role:     contracts.Role(req.Role),
+			FullName: req.Fullname,
+			Phone:    req.Phone,
+		})
 		if err != nil {
-			fmt.Println("Register Error:", err)
+			slog.ErrorContext(c.UserContext(), "Register Error", "error", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "User Created"})
 	}
 }
+
+// HandleJWKS publishes the public signing keys so other services can verify tokens without being able to mint them
+func HandleJWKS(km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(km.JWKS())
+	}
+}
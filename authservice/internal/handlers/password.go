@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"authservice/internal/repository"
+	"authservice/internal/services"
+	"authservice/utils"
+	"log/slog"
+	"platform/validation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func HandleForgotPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ForgotPasswordRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		body, err := services.Users.ForgotPassword(c.UserContext(), req.Email)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Forgot password error", "error", err)
+			return relayUserService(c, err)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(fiber.StatusOK).Send(body)
+	}
+}
+
+// HandleResetPassword sets a new password from an emailed reset token, then revokes every
+// access token issued before the change. Refresh tokens are turned away by HandleRefresh.
+func HandleResetPassword(revocations repository.RevocationStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ResetPasswordRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		user, err := services.Users.ResetPassword(c.UserContext(), req.Token, req.NewPassword)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Reset password error", "error", err)
+			return relayUserService(c, err)
+		}
+
+		changedAt := time.Now()
+		if user.PasswordChangedAt != nil {
+			changedAt = time.Unix(*user.PasswordChangedAt, 0)
+		}
+		if err := revocations.RevokeIssuedBefore(repository.RevokePassword, user.Username, changedAt, changedAt.Add(utils.ACCESS_TOKEN_TTL)); err != nil {
+			slog.ErrorContext(c.UserContext(), "Revoke tokens after password reset error", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Password was reset but sessions could not be ended",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Password has been reset, please sign in again",
+		})
+	}
+}
@@ -1,22 +1,43 @@
 package handlers
 
-import "authservice/internal/repository"
+import "platform/contracts"
 
-type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+type LoginRequest = contracts.LoginRequest
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 type LoginResponse struct {
-	Token string          `json:"token"`
-	User  repository.User `json:"user"`
+	TokenResponse
+	User contracts.User `json:"user"`
+}
+
+type RefreshRequest = contracts.RefreshRequest
+
+type IssueTokenRequest struct {
+	Username string `json:"username" validate:"required"`
+	DeviceID string `json:"device_id"`
 }
 
-type RegisterRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	Fullname string `json:"full_name"`
-	Phone    string `json:"phone"`
+type LogoutRequest = contracts.LogoutRequest
+
+// TwoFactorChallengeResponse replaces the tokens of a login that still needs a second factor
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int64  `json:"expires_in"`
 }
+
+type TwoFactorLoginRequest = contracts.TwoFactorLoginRequest
+
+type TwoFactorEnrollRequest = contracts.TwoFactorEnrollRequest
+
+type ForgotPasswordRequest = contracts.ForgotPasswordRequest
+
+type ResetPasswordRequest = contracts.ResetPasswordRequest
+
+type RegisterRequest = contracts.RegisterRequest
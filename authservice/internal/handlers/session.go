@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"authservice/internal/keys"
+	"authservice/internal/repository"
+	"authservice/internal/services"
+	"authservice/utils"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"platform/contracts"
+	"platform/validation"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func hashRefreshToken(token string) string {
+	mac := hmac.New(sha256.New, []byte(utils.JWT_REFRESH_SECRET))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// issueTokenPair signs an access token and stores a fresh refresh token in the given family
+func issueTokenPair(refreshTokens repository.RefreshTokenStore, km *keys.KeyManager, user *contracts.User, familyID, deviceID string) (*TokenResponse, error) {
+	tokenString, err := generateNewToken(km, user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = refreshTokens.Create(&repository.RefreshToken{
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		UserID:    user.ID,
+		Username:  user.Username,
+		DeviceID:  deviceID,
+		ExpiresAt: time.Now().Add(utils.REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.ACCESS_TOKEN_TTL.Seconds()),
+	}, nil
+}
+
+// revokeSession kills a refresh token family and every access token minted from it
+func revokeSession(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, familyID string) error {
+	if err := refreshTokens.RevokeFamily(familyID); err != nil {
+		return err
+	}
+	return revocations.Revoke(repository.RevokeSession, familyID, time.Now().Add(utils.ACCESS_TOKEN_TTL))
+}
+
+func HandleRefresh(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req RefreshRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		stored, err := refreshTokens.FindByHash(hashRefreshToken(req.RefreshToken))
+		if err != nil {
+			if !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+				slog.ErrorContext(c.UserContext(), "Refresh token lookup error", "error", err)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid refresh token",
+			})
+		}
+
+		if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid refresh token",
+			})
+		}
+
+		// A rotated token coming back means it leaked, so the whole family goes
+		fresh := stored.UsedAt == nil
+		if fresh {
+			fresh, err = refreshTokens.MarkUsed(stored.ID)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "Refresh token rotation error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Could not refresh token",
+				})
+			}
+		}
+		if !fresh {
+			slog.WarnContext(c.UserContext(), "Refresh token reuse detected, revoking family", "username", stored.Username)
+			if err := revokeSession(revocations, refreshTokens, stored.FamilyID); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke session error", "error", err)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Refresh token reuse detected",
+			})
+		}
+
+		// Re-read the user so role and status changes apply from the next access token on
+		user, err := services.Users.ByUsername(c.UserContext(), stored.Username)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Refresh user lookup error", "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid refresh token",
+			})
+		}
+
+		if e := checkUserAllowed(revocations, user); e != nil {
+			if err := revokeSession(revocations, refreshTokens, stored.FamilyID); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke session error", "error", err)
+			}
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		// A password change ends every session started with the old password
+		if user.PasswordChangedAt != nil && stored.CreatedAt.Unix() < *user.PasswordChangedAt {
+			if err := revokeSession(revocations, refreshTokens, stored.FamilyID); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke session error", "error", err)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Password has changed, please sign in again",
+			})
+		}
+
+		// Sessions that predate the 2FA policy end here instead of living on through refreshes
+		if twoFactorRequiredForRole(user.Role) && !user.TwoFactorEnabled {
+			if err := revokeSession(revocations, refreshTokens, stored.FamilyID); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke session error", "error", err)
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Two-factor authentication is required for this account, please sign in again",
+			})
+		}
+
+		tokens, err := issueTokenPair(refreshTokens, km, user, stored.FamilyID, stored.DeviceID)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Issue token error", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not refresh token",
+			})
+		}
+
+		return c.JSON(tokens)
+	}
+}
+
+// HandleIssueToken mints a session for a user another service has already authenticated, such as a Google sign-in.
+// Role and status come from user-service, never from the caller, and 2FA applies just as it does to a password login.
+func HandleIssueToken(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req IssueTokenRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		user, err := services.Users.ByUsername(c.UserContext(), req.Username)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Issue token user lookup error", "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid user",
+			})
+		}
+
+		if e := checkUserAllowed(revocations, user); e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		return beginSession(c, refreshTokens, km, user, req.DeviceID)
+	}
+}
+
+// HandleLogout revokes the caller's access token and refresh token family.
+// The gateway passes the access token's jti, sid and exp as query parameters.
+func HandleLogout(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req LogoutRequest
+		if len(c.Body()) > 0 {
+			if verr := validation.ParseBody(c, &req); verr != nil {
+				return verr.Send(c)
+			}
+		}
+
+		jti := c.Query("jti")
+		sid := c.Query("sid")
+		if jti == "" && sid == "" && req.RefreshToken == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "nothing to log out",
+			})
+		}
+
+		if jti != "" {
+			expiresAt := time.Now().Add(utils.ACCESS_TOKEN_TTL)
+			if exp, err := strconv.ParseInt(c.Query("exp"), 10, 64); err == nil {
+				expiresAt = time.Unix(exp, 0)
+			}
+			if err := revocations.Revoke(repository.RevokeToken, jti, expiresAt); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke token error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Could not log out",
+				})
+			}
+		}
+
+		families := []string{}
+		if sid != "" {
+			families = append(families, sid)
+		}
+		if req.RefreshToken != "" {
+			stored, err := refreshTokens.FindByHash(hashRefreshToken(req.RefreshToken))
+			if err == nil && stored.FamilyID != sid {
+				families = append(families, stored.FamilyID)
+			}
+		}
+		for _, familyID := range families {
+			if err := revokeSession(revocations, refreshTokens, familyID); err != nil {
+				slog.ErrorContext(c.UserContext(), "Revoke session error", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Could not log out",
+				})
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "logged out",
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"authservice/internal/keys"
+	"authservice/internal/repository"
+	"authservice/internal/services"
+	"authservice/utils"
+	"errors"
+	"log/slog"
+	"platform/client"
+	"platform/contracts"
+	"platform/validation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// A challenge only has to outlive a trip to the authenticator app
+const twoFactorChallengeTTL = 5 * time.Minute
+
+const (
+	tokenUseChallenge = "2fa_challenge"
+
+	// challengeVerify asks for a code from an enrolled authenticator,
+	// challengeEnroll lets a user the policy forces into 2FA enroll before their first session
+	challengeVerify = "verify"
+	challengeEnroll = "enroll"
+)
+
+// ChallengeClaims prove the password step of a login succeeded. They carry no userId or role,
+// so neither the gateway nor node-service will accept one as an access token.
+type ChallengeClaims struct {
+	jwt.RegisteredClaims
+	Username string `json:"username"`
+	TokenUse string `json:"token_use"`
+	Purpose  string `json:"purpose"`
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+func twoFactorRequiredForRole(role contracts.Role) bool {
+	for _, required := range utils.TWO_FACTOR_REQUIRED_ROLES {
+		if string(role) == required {
+			return true
+		}
+	}
+	return false
+}
+
+func newChallengeToken(km *keys.KeyManager, user *contracts.User, purpose, deviceID string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	return km.Sign(ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.Username,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "auth-service",
+		},
+		Username: user.Username,
+		TokenUse: tokenUseChallenge,
+		Purpose:  purpose,
+		DeviceID: deviceID,
+	})
+}
+
+// parseChallenge rejects anything but an unexpired, unused challenge issued for purpose
+func parseChallenge(revocations repository.RevocationStore, km *keys.KeyManager, token, purpose string) (*ChallengeClaims, *fiber.Error) {
+	claims := &ChallengeClaims{}
+	if err := km.Parse(token, claims); err != nil || claims.TokenUse != tokenUseChallenge || claims.Purpose != purpose {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired challenge")
+	}
+
+	used, err := revocations.IsRevoked(repository.RevokeToken, claims.ID)
+	if err != nil {
+		slog.Error("Revocation lookup error", "error", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Could not verify challenge")
+	}
+	if used {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired challenge")
+	}
+	return claims, nil
+}
+
+// burnChallenge makes a challenge single use. A wrong code burns it too, so guessing
+// means going back through the password step and its lockout.
+func burnChallenge(revocations repository.RevocationStore, claims *ChallengeClaims) {
+	if err := revocations.Revoke(repository.RevokeToken, claims.ID, claims.ExpiresAt.Time); err != nil {
+		slog.Error("Revoke challenge error", "error", err)
+	}
+}
+
+// beginSession finishes a login whose first factor has been checked. Users with 2FA,
+// or whose role requires it, get a challenge instead of tokens.
+func beginSession(c *fiber.Ctx, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager, user *contracts.User, deviceID string) error {
+	purpose := ""
+	switch {
+	case user.TwoFactorEnabled:
+		purpose = challengeVerify
+	case twoFactorRequiredForRole(user.Role):
+		purpose = challengeEnroll
+	}
+	if purpose == "" {
+		return startSession(c, refreshTokens, km, user, deviceID)
+	}
+
+	challenge, err := newChallengeToken(km, user, purpose, deviceID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Issue challenge error", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+	return c.JSON(TwoFactorChallengeResponse{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: purpose == challengeEnroll,
+		ChallengeToken:     challenge,
+		ExpiresIn:          int64(twoFactorChallengeTTL.Seconds()),
+	})
+}
+
+func startSession(c *fiber.Ctx, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager, user *contracts.User, deviceID string) error {
+	familyID, err := newTokenID()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+
+	tokens, err := issueTokenPair(refreshTokens, km, user, familyID, deviceID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Issue token error", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not generate token",
+		})
+	}
+
+	return c.JSON(LoginResponse{
+		TokenResponse: *tokens,
+		User:          *user,
+	})
+}
+
+// relayUserService writes user-service's refusal back to the client as is, or a 502 when
+// user-service could not be reached
+func relayUserService(c *fiber.Ctx, err error) error {
+	var refusal *client.Error
+	if errors.As(err, &refusal) {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(refusal.Status).Send(refusal.Body)
+	}
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+		"error": "Could not reach user service",
+	})
+}
+
+// relayTwoFactor runs a user-service 2FA action and writes its error back to the client.
+// It returns true when the action succeeded and the caller should carry on.
+func relayTwoFactor(c *fiber.Ctx, action, username, code string) (bool, []byte, error) {
+	body, err := services.Users.TwoFactor(c.UserContext(), action, username, code)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Two factor call failed", "action", action, "error", err)
+		return false, nil, relayUserService(c, err)
+	}
+	return true, body, nil
+}
+
+// HandleLoginTwoFactor trades a verify challenge and a TOTP or recovery code for a session
+func HandleLoginTwoFactor(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req TwoFactorLoginRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+		if req.ChallengeToken == "" {
+			return validation.Missing("challenge_token").Send(c)
+		}
+
+		claims, e := parseChallenge(revocations, km, req.ChallengeToken, challengeVerify)
+		if e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+		burnChallenge(revocations, claims)
+
+		ok, _, err := relayTwoFactor(c, "verify", claims.Username, req.Code)
+		if !ok {
+			return err
+		}
+
+		user, err := services.Users.ByUsername(c.UserContext(), claims.Username)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Two factor user lookup error", "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid user",
+			})
+		}
+		if e := checkUserAllowed(revocations, user); e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		return startSession(c, refreshTokens, km, user, claims.DeviceID)
+	}
+}
+
+// twoFactorUsername resolves who is enrolling: the holder of an enroll challenge, or the
+// signed-in user the gateway names in the query
+func twoFactorUsername(c *fiber.Ctx, revocations repository.RevocationStore, km *keys.KeyManager, challengeToken string) (string, *ChallengeClaims, *fiber.Error) {
+	if challengeToken != "" {
+		claims, e := parseChallenge(revocations, km, challengeToken, challengeEnroll)
+		if e != nil {
+			return "", nil, e
+		}
+		return claims.Username, claims, nil
+	}
+	if username := c.Query("username"); username != "" {
+		return username, nil, nil
+	}
+	return "", nil, fiber.NewError(fiber.StatusBadRequest, "challenge_token is required")
+}
+
+func HandleTwoFactorEnroll(revocations repository.RevocationStore, km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req TwoFactorEnrollRequest
+		if len(c.Body()) > 0 {
+			if verr := validation.ParseBody(c, &req); verr != nil {
+				return verr.Send(c)
+			}
+		}
+
+		username, _, e := twoFactorUsername(c, revocations, km, req.ChallengeToken)
+		if e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		ok, body, err := relayTwoFactor(c, "enroll", username, "")
+		if !ok {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(fiber.StatusOK).Send(body)
+	}
+}
+
+// HandleTwoFactorActivate confirms an enrollment. When it completes a forced enrollment
+// during login, the response is the session the login was waiting on.
+func HandleTwoFactorActivate(revocations repository.RevocationStore, refreshTokens repository.RefreshTokenStore, km *keys.KeyManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req TwoFactorLoginRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		username, claims, e := twoFactorUsername(c, revocations, km, req.ChallengeToken)
+		if e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		ok, body, err := relayTwoFactor(c, "activate", username, req.Code)
+		if !ok {
+			return err
+		}
+		if claims == nil {
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.Status(fiber.StatusOK).Send(body)
+		}
+		burnChallenge(revocations, claims)
+
+		user, err := services.Users.ByUsername(c.UserContext(), username)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Two factor user lookup error", "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid user",
+			})
+		}
+		if e := checkUserAllowed(revocations, user); e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+
+		return startSession(c, refreshTokens, km, user, claims.DeviceID)
+	}
+}
+
+// HandleTwoFactorDisable turns 2FA off for the signed-in user, unless their role requires it
+func HandleTwoFactorDisable() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		username := c.Query("username")
+		if username == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "username is required",
+			})
+		}
+		var req TwoFactorLoginRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		user, err := services.Users.ByUsername(c.UserContext(), username)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Two factor user lookup error", "error", err)
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		if twoFactorRequiredForRole(user.Role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Two-factor authentication is required for the " + string(user.Role) + " role",
+			})
+		}
+
+		ok, body, err := relayTwoFactor(c, "disable", username, req.Code)
+		if !ok {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(fiber.StatusOK).Send(body)
+	}
+}
@@ -0,0 +1,242 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey is one private key the auth service can sign with, identified by its RFC 7638 thumbprint
+type SigningKey struct {
+	KID    string
+	Method jwt.SigningMethod
+	Signer crypto.Signer
+}
+
+// JWK is the public half of a SigningKey as published in the JWKS document
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyManager holds every loaded key. Only the active one signs; the others stay published
+// so tokens signed before a rotation keep verifying until they expire.
+type KeyManager struct {
+	keys   map[string]*SigningKey
+	active *SigningKey
+}
+
+// LoadKeyManager reads PEM encoded PKCS#8 RSA or Ed25519 private keys from dir.
+// activeKID selects the signing key; when empty the last file in name order signs.
+// With no keys configured an ephemeral RSA key is generated, which only suits a single replica.
+func LoadKeyManager(dir, activeKID string) (*KeyManager, error) {
+	km := &KeyManager{keys: make(map[string]*SigningKey)}
+
+	var files []string
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = matches
+	}
+
+	if len(files) == 0 {
+		slog.Warn("No signing keys found, using an ephemeral key that changes on every restart", "dir", dir)
+		key, err := ephemeralKey()
+		if err != nil {
+			return nil, err
+		}
+		km.keys[key.KID] = key
+		km.active = key
+		return km, nil
+	}
+
+	var last *SigningKey
+	for _, file := range files {
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", file, err)
+		}
+		km.keys[key.KID] = key
+		last = key
+		slog.Info("Loaded signing key", "kid", key.KID, "file", filepath.Base(file))
+	}
+
+	if activeKID == "" {
+		km.active = last
+	} else {
+		key, ok := km.keys[activeKID]
+		if !ok {
+			return nil, fmt.Errorf("active key %s not found in %s", activeKID, dir)
+		}
+		km.active = key
+	}
+	slog.Info("Signing tokens", "kid", km.active.KID)
+	return km, nil
+}
+
+func ephemeralKey() (*SigningKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(priv)
+}
+
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("key is not a signing key")
+	}
+	return newSigningKey(signer)
+}
+
+func newSigningKey(signer crypto.Signer) (*SigningKey, error) {
+	var method jwt.SigningMethod
+	switch k := signer.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", signer)
+	}
+
+	key := &SigningKey{Method: method, Signer: signer}
+	kid, err := thumbprint(key.publicJWK())
+	if err != nil {
+		return nil, err
+	}
+	key.KID = kid
+	return key, nil
+}
+
+func (k *SigningKey) publicJWK() JWK {
+	switch pub := k.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return JWK{}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, members in lexicographic order
+func thumbprint(jwk JWK) (string, error) {
+	var members string
+	switch jwk.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Crv, jwk.X)
+	default:
+		return "", fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Sign signs the claims with the active key and stamps its kid in the header
+func (km *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(km.active.Method, claims)
+	token.Header["kid"] = km.active.KID
+	return token.SignedString(km.active.Signer)
+}
+
+// Parse verifies a token signed by any loaded key
+func (km *KeyManager) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := km.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.Signer.Public(), nil
+	})
+	return err
+}
+
+// JWKS returns the public keys, active key first
+func (km *KeyManager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	kids := make([]string, 0, len(km.keys))
+	for kid := range km.keys {
+		kids = append(kids, kid)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if kids[i] == km.active.KID || kids[j] == km.active.KID {
+			return kids[i] == km.active.KID
+		}
+		return kids[i] < kids[j]
+	})
+	for _, kid := range kids {
+		jwk := km.keys[kid].publicJWK()
+		jwk.Kid = kid
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
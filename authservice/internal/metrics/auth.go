@@ -0,0 +1,17 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var logins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_logins_total",
+	Help: "Password logins, by result: succeeded, failed, locked or blocked.",
+}, []string{"result"})
+
+// RecordLogin counts one password login. A login that goes on to a 2FA challenge has
+// still succeeded here, the second factor is not part of this count.
+func RecordLogin(result string) {
+	logins.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"platform/metrics"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var DB *gorm.DB
+
+func InitDB() (*gorm.DB, error) {
+	dbUser := os.Getenv("DB_USER")
+	dbPass := os.Getenv("DB_PASSWORD")
+	dbHost := os.Getenv("DB_HOST")
+	dbPort := os.Getenv("DB_PORT")
+	dbName := os.Getenv("DB_NAME")
+
+	if dbUser == "" || dbPass == "" || dbHost == "" || dbName == "" {
+		slog.Warn("Some database environment variables are missing, using default values")
+		dbUser = "appuser"
+		dbPass = "apppassword"
+		dbHost = "mysql"
+		dbName = "online_tutoring_platform"
+	}
+	if dbPort == "" {
+		dbPort = "3306"
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		dbUser, dbPass, dbHost, dbPort, dbName)
+
+	var db *gorm.DB
+	var err error
+	maxRetries := 5
+
+	for i := 0; i < maxRetries; i++ {
+		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		if err == nil {
+			break
+		}
+		slog.Warn("Failed to connect to database", "attempt", i+1, "max_attempts", maxRetries, "error", err)
+		time.Sleep(time.Second * 5)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("database connection failed after %d attempts: %w", maxRetries, err)
+	}
+
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+
+	DB = db
+	slog.Info("Database connected")
+	return db, nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshToken is one link of a rotation chain. Every token issued from the same login shares a FamilyID,
+// only the hash of the opaque token is stored.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	FamilyID  string     `gorm:"type:varchar(64);not null;index" json:"family_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	Username  string     `gorm:"type:varchar(50);not null;index" json:"username"`
+	DeviceID  string     `gorm:"type:varchar(255)" json:"device_id"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "RefreshTokens"
+}
+
+type RefreshTokenStore interface {
+	Create(token *RefreshToken) error
+	FindByHash(hash string) (*RefreshToken, error)
+	// MarkUsed flags a token as rotated. It returns false when the token was already used,
+	// which means someone is replaying it.
+	MarkUsed(id uint) (bool, error)
+	RevokeFamily(familyID string) error
+	PurgeExpired() error
+}
+
+type mysqlRefreshTokenStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLRefreshTokenStore creates a RefreshTokenStore backed by the RefreshTokens table
+func NewMySQLRefreshTokenStore(db *gorm.DB) RefreshTokenStore {
+	return &mysqlRefreshTokenStore{
+		db: db,
+	}
+}
+
+func (s *mysqlRefreshTokenStore) Create(token *RefreshToken) error {
+	return s.db.Create(token).Error
+}
+
+func (s *mysqlRefreshTokenStore) FindByHash(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	result := s.db.Where("token_hash = ?", hash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (s *mysqlRefreshTokenStore) MarkUsed(id uint) (bool, error) {
+	result := s.db.Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *mysqlRefreshTokenStore) RevokeFamily(familyID string) error {
+	return s.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *mysqlRefreshTokenStore) PurgeExpired() error {
+	return s.db.Where("expires_at <= ?", time.Now()).Delete(&RefreshToken{}).Error
+}
+
+type memoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	nextID uint
+	tokens map[uint]*RefreshToken
+}
+
+// NewMemoryRefreshTokenStore creates a process-local RefreshTokenStore, meant for tests and single-instance runs
+func NewMemoryRefreshTokenStore() RefreshTokenStore {
+	return &memoryRefreshTokenStore{
+		tokens: make(map[uint]*RefreshToken),
+	}
+}
+
+func (s *memoryRefreshTokenStore) Create(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	token.ID = s.nextID
+	token.CreatedAt = time.Now()
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) FindByHash(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range s.tokens {
+		if token.TokenHash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, ErrRefreshTokenNotFound
+}
+
+func (s *memoryRefreshTokenStore) MarkUsed(id uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[id]
+	if !ok {
+		return false, ErrRefreshTokenNotFound
+	}
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) PurgeExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, token := range s.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RevocationKind string
+
+const (
+	// RevokeUser blocks every token issued to a username.
+	RevokeUser RevocationKind = "user"
+	// RevokeToken blocks a single token by its jti.
+	RevokeToken RevocationKind = "token"
+	// RevokeSession blocks every access token minted from one refresh token family.
+	RevokeSession RevocationKind = "session"
+	// RevokePassword blocks the tokens a username was issued before its password changed.
+	RevokePassword RevocationKind = "password"
+)
+
+// RevokedToken is a revocation entry. It stops applying once ExpiresAt has passed.
+// Entries with IssuedBefore set only cover tokens issued before that moment.
+type RevokedToken struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Kind         RevocationKind `gorm:"type:varchar(20);not null;index:idx_revoked_kind_subject" json:"kind"`
+	Subject      string         `gorm:"type:varchar(255);not null;index:idx_revoked_kind_subject" json:"subject"`
+	IssuedBefore *time.Time     `json:"issued_before,omitempty"`
+	ExpiresAt    time.Time      `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+func (RevokedToken) TableName() string {
+	return "RevokedTokens"
+}
+
+type RevocationStore interface {
+	Revoke(kind RevocationKind, subject string, expiresAt time.Time) error
+	// RevokeIssuedBefore revokes the subject's tokens issued before issuedBefore
+	RevokeIssuedBefore(kind RevocationKind, subject string, issuedBefore, expiresAt time.Time) error
+	Release(kind RevocationKind, subject string) error
+	IsRevoked(kind RevocationKind, subject string) (bool, error)
+	// IsRevokedAt is IsRevoked for a token issued at issuedAt
+	IsRevokedAt(kind RevocationKind, subject string, issuedAt time.Time) (bool, error)
+	PurgeExpired() error
+}
+
+type mysqlRevocationStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLRevocationStore creates a RevocationStore backed by the RevokedTokens table
+func NewMySQLRevocationStore(db *gorm.DB) RevocationStore {
+	return &mysqlRevocationStore{
+		db: db,
+	}
+}
+
+// Revoke replaces any existing entry for the subject so the latest expiry wins
+func (s *mysqlRevocationStore) Revoke(kind RevocationKind, subject string, expiresAt time.Time) error {
+	return s.replace(&RevokedToken{
+		Kind:      kind,
+		Subject:   subject,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *mysqlRevocationStore) RevokeIssuedBefore(kind RevocationKind, subject string, issuedBefore, expiresAt time.Time) error {
+	return s.replace(&RevokedToken{
+		Kind:         kind,
+		Subject:      subject,
+		IssuedBefore: &issuedBefore,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+func (s *mysqlRevocationStore) replace(entry *RevokedToken) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ? AND subject = ?", entry.Kind, entry.Subject).Delete(&RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func (s *mysqlRevocationStore) Release(kind RevocationKind, subject string) error {
+	return s.db.Where("kind = ? AND subject = ?", kind, subject).Delete(&RevokedToken{}).Error
+}
+
+func (s *mysqlRevocationStore) IsRevoked(kind RevocationKind, subject string) (bool, error) {
+	var count int64
+	err := s.db.Model(&RevokedToken{}).
+		Where("kind = ? AND subject = ? AND expires_at > ? AND issued_before IS NULL", kind, subject, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mysqlRevocationStore) IsRevokedAt(kind RevocationKind, subject string, issuedAt time.Time) (bool, error) {
+	var count int64
+	err := s.db.Model(&RevokedToken{}).
+		Where("kind = ? AND subject = ? AND expires_at > ?", kind, subject, time.Now()).
+		Where("issued_before IS NULL OR issued_before > ?", issuedAt).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mysqlRevocationStore) PurgeExpired() error {
+	return s.db.Where("expires_at <= ?", time.Now()).Delete(&RevokedToken{}).Error
+}
+
+type memoryRevocationEntry struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+type memoryRevocationStore struct {
+	mu      sync.RWMutex
+	entries map[string]memoryRevocationEntry
+}
+
+// NewMemoryRevocationStore creates a process-local RevocationStore, meant for tests and single-instance runs
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{
+		entries: make(map[string]memoryRevocationEntry),
+	}
+}
+
+func memoryKey(kind RevocationKind, subject string) string {
+	return string(kind) + ":" + subject
+}
+
+func (s *memoryRevocationStore) Revoke(kind RevocationKind, subject string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[memoryKey(kind, subject)] = memoryRevocationEntry{expiresAt: expiresAt}
+	return nil
+}
+
+func (s *memoryRevocationStore) RevokeIssuedBefore(kind RevocationKind, subject string, issuedBefore, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[memoryKey(kind, subject)] = memoryRevocationEntry{issuedBefore: issuedBefore, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *memoryRevocationStore) Release(kind RevocationKind, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, memoryKey(kind, subject))
+	return nil
+}
+
+func (s *memoryRevocationStore) IsRevoked(kind RevocationKind, subject string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[memoryKey(kind, subject)]
+	return ok && entry.issuedBefore.IsZero() && time.Now().Before(entry.expiresAt), nil
+}
+
+func (s *memoryRevocationStore) IsRevokedAt(kind RevocationKind, subject string, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[memoryKey(kind, subject)]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return false, nil
+	}
+	return entry.issuedBefore.IsZero() || issuedAt.Before(entry.issuedBefore), nil
+}
+
+func (s *memoryRevocationStore) PurgeExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
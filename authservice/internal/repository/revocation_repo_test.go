@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRevocationStoreRevokeAndRelease(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	if err := store.Revoke(RevokeUser, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked(RevokeUser, "alice"); !revoked {
+		t.Error("alice is not revoked after Revoke")
+	}
+	if revoked, _ := store.IsRevoked(RevokeToken, "alice"); revoked {
+		t.Error("a user revocation covers a token with the same subject")
+	}
+	if revoked, _ := store.IsRevoked(RevokeUser, "bob"); revoked {
+		t.Error("bob is revoked without a Revoke")
+	}
+
+	if err := store.Release(RevokeUser, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked(RevokeUser, "alice"); revoked {
+		t.Error("alice is still revoked after Release")
+	}
+}
+
+func TestMemoryRevocationStoreExpiry(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	store.Revoke(RevokeToken, "expired", time.Now().Add(-time.Second))
+	store.Revoke(RevokeToken, "live", time.Now().Add(time.Hour))
+
+	if revoked, _ := store.IsRevoked(RevokeToken, "expired"); revoked {
+		t.Error("an expired entry still revokes")
+	}
+
+	if err := store.PurgeExpired(); err != nil {
+		t.Fatal(err)
+	}
+	entries := store.(*memoryRevocationStore).entries
+	if _, ok := entries[memoryKey(RevokeToken, "expired")]; ok {
+		t.Error("PurgeExpired kept the expired entry")
+	}
+	if _, ok := entries[memoryKey(RevokeToken, "live")]; !ok {
+		t.Error("PurgeExpired dropped the live entry")
+	}
+}
+
+func TestMemoryRevocationStoreIssuedBefore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	changed := time.Now()
+	store.RevokeIssuedBefore(RevokePassword, "alice", changed, changed.Add(time.Hour))
+
+	for _, tc := range []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"token from before the change", changed.Add(-time.Minute), true},
+		{"token from after the change", changed.Add(time.Minute), false},
+	} {
+		if got, _ := store.IsRevokedAt(RevokePassword, "alice", tc.issuedAt); got != tc.want {
+			t.Errorf("%s: revoked %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// IsRevoked has no issue time to compare, so only blanket revocations answer it
+	if revoked, _ := store.IsRevoked(RevokePassword, "alice"); revoked {
+		t.Error("IsRevoked reports an issued-before entry")
+	}
+}
+
+func TestMemoryRevocationStoreLatestRevokeWins(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	changed := time.Now()
+	store.RevokeIssuedBefore(RevokeUser, "alice", changed, changed.Add(time.Hour))
+	store.Revoke(RevokeUser, "alice", changed.Add(time.Hour))
+
+	if revoked, _ := store.IsRevokedAt(RevokeUser, "alice", changed.Add(time.Minute)); !revoked {
+		t.Error("a blanket revocation did not replace the issued-before one")
+	}
+}
@@ -0,0 +1,33 @@
+-- Baseline, matching what prisma db push created before migrations existed.
+-- IF NOT EXISTS keeps it harmless when nodeservice pushed the tables first.
+CREATE TABLE IF NOT EXISTS `RevokedTokens` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `kind` VARCHAR(20) NOT NULL,
+    `subject` VARCHAR(255) NOT NULL,
+    `issued_before` DATETIME(3) NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+    INDEX `idx_revoked_kind_subject`(`kind`, `subject`),
+    INDEX `RevokedTokens_expires_at_idx`(`expires_at`),
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `RefreshTokens` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `family_id` VARCHAR(64) NOT NULL,
+    `token_hash` VARCHAR(64) NOT NULL,
+    `user_id` INTEGER NOT NULL,
+    `username` VARCHAR(50) NOT NULL,
+    `device_id` VARCHAR(255) NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    `used_at` DATETIME(3) NULL,
+    `revoked_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+    UNIQUE INDEX `RefreshTokens_token_hash_key`(`token_hash`),
+    INDEX `RefreshTokens_family_id_idx`(`family_id`),
+    INDEX `RefreshTokens_username_idx`(`username`),
+    INDEX `RefreshTokens_expires_at_idx`(`expires_at`),
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
@@ -0,0 +1,8 @@
+// Package migrations holds auth-service's schema history, embedded so the binary can
+// migrate and check its own tables
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,8 +1,10 @@
 package utils
 
 import (
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,24 +13,59 @@ type ServicesRoute struct {
 }
 
 var (
-	API_KEY         string
-	SERVICES_ROUTES ServicesRoute
+	API_KEY            string
+	TOKEN_ISSUE_KEY    string
+	SERVICES_ROUTES    ServicesRoute
+	JWT_REFRESH_SECRET string
+	ACCESS_TOKEN_TTL   time.Duration
+	REFRESH_TOKEN_TTL  time.Duration
+	// Roles that cannot finish a login without a verified TOTP code
+	TWO_FACTOR_REQUIRED_ROLES []string
 )
 
 func init() {
 	API_KEY = os.Getenv("API_KEY")
+	// Only google-service holds this one, the API key every service has cannot reach /token/issue
+	TOKEN_ISSUE_KEY = os.Getenv("TOKEN_ISSUE_KEY")
 	SERVICES_ROUTES.UserService = "http://user-service:" + os.Getenv("USER_SERVICE_PORT")
+	JWT_REFRESH_SECRET = os.Getenv("JWT_REFRESH_SECRET")
+	ACCESS_TOKEN_TTL = time.Duration(getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute
+	REFRESH_TOKEN_TTL = time.Duration(getEnvInt("REFRESH_TOKEN_TTL_HOURS", 720)) * time.Hour
+	TWO_FACTOR_REQUIRED_ROLES = getEnvList("TWO_FACTOR_REQUIRED_ROLES", "Admin")
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList reads a comma separated list
+func getEnvList(key, defaultValue string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
 
 func SetupTimeZone() {
 	// Set default timezone to Asia/Ho_Chi_Minh
 	loc, err := time.LoadLocation(os.Getenv("TZ"))
 	if err != nil {
-		log.Printf("Failed to load %s location: %v", loc.String(), err)
+		slog.Warn("Failed to load time zone", "tz", os.Getenv("TZ"), "error", err)
 		return
 	}
 
 	// Set the default timezone
 	time.Local = loc
-	log.Printf("Default timezone set to: %s", time.Local.String())
+	slog.Info("Default time zone set", "tz", time.Local.String())
 }
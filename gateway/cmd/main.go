@@ -5,6 +5,9 @@ import (
 	"gateway/internal/config"
 	"gateway/internal/server"
 	"gateway/utils"
+	"platform/logging"
+	"platform/shutdown"
+	"platform/tracing"
 )
 
 func init() {
@@ -12,6 +15,13 @@ func init() {
 }
 
 func main() {
+	logging.Init("gateway")
+
+	shutdownTracing, err := tracing.Init("gateway")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
+	}
+
 	cfg := config.New()
 	gateway := server.NewGateway(cfg)
 
@@ -20,7 +30,8 @@ func main() {
 		port = "8080"
 	}
 
-	if err := gateway.Start(":" + port); err != nil {
+	// Spans from the last requests are flushed after they have drained
+	if err := gateway.Start(":"+port, ":"+cfg.ServerCfg.MetricsPort, shutdown.Step{Name: "tracing", Run: shutdownTracing}); err != nil {
 		panic(fmt.Sprintf("Failed to start server: %v", err))
 	}
 }
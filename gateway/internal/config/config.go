@@ -1,9 +1,10 @@
 package config
 
 import (
-	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,26 +16,55 @@ type Config struct {
 	AdminServiceURL   string
 	PaymentServiceURL string
 	SubscriptionURL   string
-	JWTSecret         string
-	ServerCfg         ServerConfig
+	// Where the auth service publishes its token signing keys
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	// How long the gateway trusts a revocation answer from the auth service
+	RevocationCacheTTL time.Duration
+	RateLimit          RateLimitConfig
+	RouteManifest      RouteManifestConfig
+	Upstream           UpstreamConfig
+	ServerCfg          ServerConfig
 }
 
 func New() *Config {
-	return &Config{
-		AuthServiceURL:    os.Getenv("AUTH_SERVICE_URL"),
-		NodeServiceURL:    os.Getenv("NODE_SERVICE_URL"),
-		GoogleServiceURL:  os.Getenv("GOOGLE_SERVICE_URL"),
-		UserServiceURL:    os.Getenv("USER_SERVICE_URL"),
-		AdminServiceURL:   os.Getenv("ADMIN_SERVICE_URL"),
-		PaymentServiceURL: os.Getenv("PAYMENT_SERVICE_URL"),
-		SubscriptionURL:   os.Getenv("SUBSCRIPTION_SERVICE_URL"),
-		JWTSecret:         os.Getenv("JWT_SECRET"),
-		ServerCfg:         loadServerConfig(),
+	cfg := &Config{
+		AuthServiceURL:     os.Getenv("AUTH_SERVICE_URL"),
+		NodeServiceURL:     os.Getenv("NODE_SERVICE_URL"),
+		GoogleServiceURL:   os.Getenv("GOOGLE_SERVICE_URL"),
+		UserServiceURL:     os.Getenv("USER_SERVICE_URL"),
+		AdminServiceURL:    os.Getenv("ADMIN_SERVICE_URL"),
+		PaymentServiceURL:  os.Getenv("PAYMENT_SERVICE_URL"),
+		SubscriptionURL:    os.Getenv("SUBSCRIPTION_SERVICE_URL"),
+		JWKSURL:            getEnvOrDefault("JWKS_URL", os.Getenv("AUTH_SERVICE_URL")+"/.well-known/jwks.json"),
+		JWKSCacheTTL:       loadJWKSCacheTTL(),
+		RevocationCacheTTL: loadRevocationCacheTTL(),
+		RateLimit:          loadRateLimitConfig(),
+		RouteManifest:      loadRouteManifestConfig(),
+		ServerCfg:          loadServerConfig(),
+	}
+	cfg.Upstream = loadUpstreamConfig(cfg.Upstreams())
+	return cfg
+}
+
+// Upstreams names every service the gateway forwards to
+func (c *Config) Upstreams() map[string]string {
+	return map[string]string{
+		"auth":         c.AuthServiceURL,
+		"node":         c.NodeServiceURL,
+		"google":       c.GoogleServiceURL,
+		"user":         c.UserServiceURL,
+		"admin":        c.AdminServiceURL,
+		"payment":      c.PaymentServiceURL,
+		"subscription": c.SubscriptionURL,
 	}
 }
 
 type ServerConfig struct {
-	Port         string
+	Port string
+	// MetricsPort serves /metrics apart from the public port, it is only meant to be
+	// reachable from inside the network
+	MetricsPort  string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 }
@@ -42,10 +72,15 @@ type ServerConfig struct {
 func loadServerConfig() ServerConfig {
 	port := os.Getenv("PORT")
 	if port == "" {
-		fmt.Println("PORT env is empty")
+		slog.Warn("PORT is not set, using 8080")
 		port = "8080"
 	}
 
+	metricsPort := os.Getenv("METRICS_PORT")
+	if metricsPort == "" {
+		metricsPort = "9091"
+	}
+
 	readTimeout, err := strconv.Atoi(os.Getenv("SERVER_READ_TIMEOUT"))
 	if err != nil {
 		readTimeout = 10 // default 10 seconds
@@ -58,7 +93,170 @@ func loadServerConfig() ServerConfig {
 
 	return ServerConfig{
 		Port:         port,
+		MetricsPort:  metricsPort,
 		ReadTimeout:  time.Duration(readTimeout) * time.Second,
 		WriteTimeout: time.Duration(writeTimeout) * time.Second,
 	}
 }
+
+// UpstreamClientConfig sets the connection limits for one upstream service
+type UpstreamClientConfig struct {
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxConns     int
+}
+
+type UpstreamConfig struct {
+	Default UpstreamClientConfig
+	// Clients overrides Default per upstream name, e.g. a slower google-service
+	Clients map[string]UpstreamClientConfig
+	// Retries is how many times a failed GET or HEAD is tried again
+	Retries      int
+	RetryBackoff time.Duration
+	// BreakerFailures consecutive failures open an upstream's circuit for BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// loadUpstreamConfig reads UPSTREAM_* settings. Every client setting can be overridden for a
+// single upstream with UPSTREAM_<NAME>_*, e.g. UPSTREAM_GOOGLE_READ_TIMEOUT_MS.
+func loadUpstreamConfig(upstreams map[string]string) UpstreamConfig {
+	defaults := loadUpstreamClientConfig("UPSTREAM", UpstreamClientConfig{
+		DialTimeout:  time.Second,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		MaxConns:     512,
+	})
+
+	clients := make(map[string]UpstreamClientConfig)
+	for name := range upstreams {
+		clients[name] = loadUpstreamClientConfig("UPSTREAM_"+strings.ToUpper(name), defaults)
+	}
+
+	retries, err := strconv.Atoi(os.Getenv("UPSTREAM_RETRIES"))
+	if err != nil || retries < 0 {
+		retries = 2
+	}
+
+	return UpstreamConfig{
+		Default:         defaults,
+		Clients:         clients,
+		Retries:         retries,
+		RetryBackoff:    time.Duration(getEnvInt("UPSTREAM_RETRY_BACKOFF_MS", 50)) * time.Millisecond,
+		BreakerFailures: getEnvInt("UPSTREAM_BREAKER_FAILURES", 5),
+		BreakerCooldown: time.Duration(getEnvInt("UPSTREAM_BREAKER_COOLDOWN", 30)) * time.Second,
+	}
+}
+
+func loadUpstreamClientConfig(prefix string, defaults UpstreamClientConfig) UpstreamClientConfig {
+	millis := func(key string, fallback time.Duration) time.Duration {
+		return time.Duration(getEnvInt(prefix+key, int(fallback.Milliseconds()))) * time.Millisecond
+	}
+	return UpstreamClientConfig{
+		DialTimeout:  millis("_DIAL_TIMEOUT_MS", defaults.DialTimeout),
+		ReadTimeout:  millis("_READ_TIMEOUT_MS", defaults.ReadTimeout),
+		WriteTimeout: millis("_WRITE_TIMEOUT_MS", defaults.WriteTimeout),
+		MaxConns:     getEnvInt(prefix+"_MAX_CONNS", defaults.MaxConns),
+	}
+}
+
+// RouteManifestConfig points at the declarative route table. A ReloadInterval of zero
+// reads the manifest once at startup.
+type RouteManifestConfig struct {
+	Path           string
+	ReloadInterval time.Duration
+}
+
+func loadRouteManifestConfig() RouteManifestConfig {
+	reload, err := strconv.Atoi(os.Getenv("ROUTE_MANIFEST_RELOAD"))
+	if err != nil || reload < 0 {
+		reload = 10 // default 10 seconds
+	}
+	return RouteManifestConfig{
+		Path:           getEnvOrDefault("ROUTE_MANIFEST_PATH", "routes.json"),
+		ReloadInterval: time.Duration(reload) * time.Second,
+	}
+}
+
+// Rate limit keys: who a bucket belongs to
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByUser  = "user"
+	RateLimitByRoute = "route"
+)
+
+// RateLimitPolicy is a token bucket: Requests tokens refill every Period and at most Burst pile up
+type RateLimitPolicy struct {
+	Name     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+	Key      string
+}
+
+type RateLimitConfig struct {
+	// memory keeps buckets per replica, redis shares them between replicas
+	Backend  string
+	RedisURL string
+	Policies map[string]RateLimitPolicy
+}
+
+// loadRateLimitConfig declares one policy per route group. Each can be tuned with
+// RATE_LIMIT_<GROUP>_REQUESTS (per minute) and RATE_LIMIT_<GROUP>_BURST.
+func loadRateLimitConfig() RateLimitConfig {
+	defaults := []RateLimitPolicy{
+		// Credential endpoints, per client IP since the caller is not signed in yet
+		{Name: "auth", Requests: 10, Burst: 10, Key: RateLimitByIP},
+		// Each call sends an email
+		{Name: "email", Requests: 3, Burst: 3, Key: RateLimitByUser},
+		{Name: "public", Requests: 300, Burst: 100, Key: RateLimitByIP},
+		{Name: "api", Requests: 120, Burst: 60, Key: RateLimitByUser},
+	}
+
+	policies := make(map[string]RateLimitPolicy, len(defaults))
+	for _, policy := range defaults {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(policy.Name)
+		policy.Requests = getEnvInt(prefix+"_REQUESTS", policy.Requests)
+		policy.Burst = getEnvInt(prefix+"_BURST", policy.Burst)
+		policy.Period = time.Minute
+		policies[policy.Name] = policy
+	}
+
+	return RateLimitConfig{
+		Backend:  getEnvOrDefault("RATE_LIMIT_BACKEND", "memory"),
+		RedisURL: getEnvOrDefault("REDIS_URL", "redis://redis:6379/0"),
+		Policies: policies,
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func loadRevocationCacheTTL() time.Duration {
+	ttl, err := strconv.Atoi(os.Getenv("REVOCATION_CACHE_TTL"))
+	if err != nil || ttl < 0 {
+		ttl = 5 // default 5 seconds
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func loadJWKSCacheTTL() time.Duration {
+	ttl, err := strconv.Atoi(os.Getenv("JWKS_CACHE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 300 // default 5 minutes
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -185,6 +185,25 @@ func (a *AdminServiceHandler) HandleAssignRole() fiber.Handler {
 	}
 }
 
+func (a *AdminServiceHandler) HandleUnlockUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		username := c.Params("username")
+		if username == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Username is required",
+			})
+		}
+
+		query := fmt.Sprintf("?username=%s", username)
+		return routes.AdminUnlockUser(req, resp, c, a.userServiceURL+"/user/admin/unlock"+query)
+	}
+}
+
 func (a *AdminServiceHandler) HandleAdminBlockJWT() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := fasthttp.AcquireRequest()
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"fmt"
+	"gateway/internal/middleware"
 	"gateway/internal/routes"
+	"net/url"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp"
@@ -36,3 +39,95 @@ func (h *AuthHandler) HandleRegister() fiber.Handler {
 		return routes.RegisterRoute(req, resp, c, h.authServiceURL+"/register")
 	}
 }
+
+func (h *AuthHandler) HandleRefresh() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		return routes.RefreshRoute(req, resp, c, h.authServiceURL+"/refresh")
+	}
+}
+
+func (h *AuthHandler) HandleLogout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		claims, ok := c.Locals("user").(*middleware.Claims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		query := url.Values{}
+		query.Set("jti", claims.ID)
+		query.Set("sid", claims.SessionID)
+		if claims.ExpiresAt != nil {
+			query.Set("exp", fmt.Sprintf("%d", claims.ExpiresAt.Unix()))
+		}
+		return routes.LogoutRoute(req, resp, c, h.authServiceURL+"/logout?"+query.Encode())
+	}
+}
+
+func (h *AuthHandler) HandleForgotPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		return routes.PasswordRoute(req, resp, c, h.authServiceURL+"/password/forgot")
+	}
+}
+
+func (h *AuthHandler) HandleResetPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		return routes.PasswordRoute(req, resp, c, h.authServiceURL+"/password/reset")
+	}
+}
+
+// HandleLoginTwoFactor completes a login that answered with a 2FA challenge
+func (h *AuthHandler) HandleLoginTwoFactor() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		return routes.TwoFactorRoute(req, resp, c, h.authServiceURL+"/login/2fa")
+	}
+}
+
+// HandleTwoFactorChallenge serves enrollment for a login the policy holds back until 2FA is set up.
+// The challenge token in the body identifies the user, so nothing from the query is forwarded.
+func (h *AuthHandler) HandleTwoFactorChallenge(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+		return routes.TwoFactorRoute(req, resp, c, h.authServiceURL+"/2fa/"+action)
+	}
+}
+
+// HandleTwoFactor manages 2FA for the signed-in user
+func (h *AuthHandler) HandleTwoFactor(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		claims, ok := c.Locals("user").(*middleware.Claims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		query := url.Values{}
+		query.Set("username", claims.Username)
+		return routes.TwoFactorRoute(req, resp, c, h.authServiceURL+"/2fa/"+action+"?"+query.Encode())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"gateway/internal/config"
 	"gateway/internal/middleware"
 	"gateway/internal/routes"
+	"log/slog"
 	"os"
 	"time"
 
@@ -48,8 +49,6 @@ func (h *GoogleHandler) HandleLogin() fiber.Handler {
 			Path:     "/",
 		})
 
-		fmt.Println("Set cookie:", c.Response().Header.Peek("Set-Cookie"))
-
 		req := fasthttp.AcquireRequest()
 		resp := fasthttp.AcquireResponse()
 		defer fasthttp.ReleaseRequest(req)
@@ -62,15 +61,11 @@ func (h *GoogleHandler) HandleLogin() fiber.Handler {
 
 func (h *GoogleHandler) HandleCallback() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		fmt.Println("Incoming cookies:", string(c.Request().Header.Peek("Cookie")))
-
 		stateCookie := c.Cookies("oauth_state")
 		stateParam := c.Query("state")
 
-		fmt.Println("State from cookie:", stateCookie)
-		fmt.Println("State from param:", stateParam)
-
 		if stateCookie == "" || stateParam == "" || stateCookie != stateParam {
+			slog.WarnContext(c.UserContext(), "OAuth state mismatch", "has_cookie", stateCookie != "", "has_param", stateParam != "")
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error":        "Invalid state parameter",
 				"cookie_state": stateCookie,
@@ -85,7 +80,7 @@ func (h *GoogleHandler) HandleCallback() fiber.Handler {
 		defer fasthttp.ReleaseResponse(resp)
 		err := routes.GoogleLoginRoute(req, resp, c, h.googleServiceURL+"/api/auth/google/callback?"+query)
 		if err != nil {
-			fmt.Println("GOOGLE SERVICE CALL BACK FAILED:", err)
+			slog.ErrorContext(c.UserContext(), "Google service callback failed", "error", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to get user information",
 			})
@@ -98,6 +93,30 @@ func (h *GoogleHandler) HandleCallback() fiber.Handler {
 			})
 		}
 
+		if resp.StatusCode() >= fiber.StatusBadRequest {
+			return c.Status(resp.StatusCode()).JSON(fiber.Map{
+				"error": responseData["error"],
+			})
+		}
+
+		// The frontend finishes the sign-in through /auth/login/2fa or the enrollment routes
+		if required, _ := responseData["two_factor_required"].(bool); required {
+			challenge, _ := responseData["challenge_token"].(string)
+			c.Cookie(&fiber.Cookie{
+				Name:     "twoFactorChallenge",
+				Value:    challenge,
+				Path:     "/",
+				Expires:  time.Now().Add(5 * time.Minute),
+				Secure:   true,
+				SameSite: "Strict",
+			})
+			step := "verify"
+			if enroll, _ := responseData["enrollment_required"].(bool); enroll {
+				step = "enroll"
+			}
+			return c.Redirect(os.Getenv("REDIRECT_URL")+"?two_factor="+step, fiber.StatusTemporaryRedirect)
+		}
+
 		token, hasToken := responseData["token"].(string)
 		userData, hasUserData := responseData["user"].(map[string]interface{})
 
@@ -107,15 +126,31 @@ func (h *GoogleHandler) HandleCallback() fiber.Handler {
 			})
 		}
 
+		tokenTTL := 15 * time.Minute
+		if expiresIn, ok := responseData["expires_in"].(float64); ok && expiresIn > 0 {
+			tokenTTL = time.Duration(expiresIn) * time.Second
+		}
 		c.Cookie(&fiber.Cookie{
 			Name:     "authToken",
 			Value:    token,
 			Path:     "/",
-			Expires:  time.Now().Add(24 * time.Hour), // 24 hour expiration
+			Expires:  time.Now().Add(tokenTTL),
 			Secure:   true,
 			SameSite: "Strict",
 		})
 
+		if refreshToken, ok := responseData["refresh_token"].(string); ok && refreshToken != "" {
+			c.Cookie(&fiber.Cookie{
+				Name:     "refreshToken",
+				Value:    refreshToken,
+				Path:     "/auth/refresh",
+				Expires:  time.Now().Add(30 * 24 * time.Hour),
+				Secure:   true,
+				HTTPOnly: true,
+				SameSite: "Strict",
+			})
+		}
+
 		userDataJSON, _ := json.Marshal(userData)
 		c.Cookie(&fiber.Cookie{
 			Name:     "user",
@@ -130,13 +165,7 @@ func (h *GoogleHandler) HandleCallback() fiber.Handler {
 	}
 }
 
-type OTP struct {
-	code         string
-	expired_time int64
-}
-
-var verification_code map[string]*OTP = make(map[string]*OTP)
-
+// HandleSendVerificationEmail asks user-service to email a code to the address in the caller's token
 func (h *GoogleHandler) HandleSendVerificationEmail() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := fasthttp.AcquireRequest()
@@ -145,41 +174,41 @@ func (h *GoogleHandler) HandleSendVerificationEmail() fiber.Handler {
 		defer fasthttp.ReleaseResponse(resp)
 		claims, ok := c.Locals("user").(*middleware.Claims)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find username in token claim"})
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find email in token claim"})
 		}
-		current_email := claims.Email
-		code := generateRandomOTP()
-		code = code[:len(code)-4]
-		expired_time := time.Now().Add(10 * time.Minute).Unix()
-		otp := &OTP{code: code, expired_time: expired_time}
 
-		verification_code[current_email] = otp
-		fmt.Println("VERIFICATION CODE DEBUG:", verification_code[current_email])
-
-		query_url := fmt.Sprintf("?to=%s&body=%s", current_email, otp.code)
-		return routes.SendVerificationEmail(req, resp, c, h.googleServiceURL+"/api/email/send/verify/email"+query_url)
+		body, _ := json.Marshal(fiber.Map{"email": claims.Email})
+		return routes.SendVerificationEmail(req, resp, c, h.userServiceURL+"/user/verify-email/send", body)
 	}
 }
 
+// HandleVerifyEmail checks the code against the one sent to the address in the caller's token
 func (h *GoogleHandler) HandleVerifyEmail() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := fasthttp.AcquireRequest()
 		resp := fasthttp.AcquireResponse()
 		defer fasthttp.ReleaseRequest(req)
 		defer fasthttp.ReleaseResponse(resp)
-		code := c.Query("code")
 		claims, ok := c.Locals("user").(*middleware.Claims)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find username in token claim"})
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find email in token claim"})
+		}
+
+		code := c.Query("code")
+		if code == "" {
+			var payload struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(c.Body(), &payload); err == nil {
+				code = payload.Code
+			}
 		}
-		current_email := claims.Email
-		if verification_code[current_email].code != code {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "wrong otp code"})
+		if code == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "code is required"})
 		}
-		delete(verification_code, current_email)
 
-		query := fmt.Sprintf("?username=%s", claims.Username)
-		return routes.VerifyEmail(req, resp, c, h.userServiceURL+"/user/verify"+query)
+		body, _ := json.Marshal(fiber.Map{"email": claims.Email, "code": code})
+		return routes.VerifyEmail(req, resp, c, h.userServiceURL+"/user/verify-email/verify", body)
 	}
 }
 
@@ -204,9 +233,3 @@ func generateRandomState() string {
 	rand.Read(b)
 	return base64.URLEncoding.EncodeToString(b)
 }
-
-func generateRandomOTP() string {
-	b := make([]byte, 8)
-	rand.Read(b)
-	return base64.URLEncoding.EncodeToString(b)
-}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"gateway/internal/config"
+	"gateway/internal/manifest"
+	"gateway/internal/middleware"
+	"gateway/internal/routes"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// ManifestHandler serves the routes declared in the route manifest
+type ManifestHandler struct {
+	loader     *manifest.Loader
+	jwks       *middleware.JWKSCache
+	revocation middleware.RevocationChecker
+	rateLimits middleware.RateLimitStore
+	policies   map[string]config.RateLimitPolicy
+}
+
+func NewManifestHandler(loader *manifest.Loader, jwks *middleware.JWKSCache, revocation middleware.RevocationChecker, rateLimits middleware.RateLimitStore, policies map[string]config.RateLimitPolicy) *ManifestHandler {
+	return &ManifestHandler{
+		loader:     loader,
+		jwks:       jwks,
+		revocation: revocation,
+		rateLimits: rateLimits,
+		policies:   policies,
+	}
+}
+
+// HandleManifestRoute looks the request up in the current route table. It is registered
+// after every hand written route, so those always win.
+func (h *ManifestHandler) HandleManifestRoute() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		route, params, pathMatched := h.loader.Table().Match(c.Method(), c.Path())
+		if route == nil {
+			if pathMatched {
+				return c.Status(fiber.StatusMethodNotAllowed).JSON(fiber.Map{
+					"error": "Method not allowed",
+				})
+			}
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Cannot " + c.Method() + " " + c.Path(),
+			})
+		}
+		c.Locals("route", route.Path)
+
+		values := params
+		if route.Auth {
+			// A group middleware such as /api may already have checked the token
+			claims, ok := c.Locals("user").(*middleware.Claims)
+			if !ok {
+				var e *fiber.Error
+				claims, e = middleware.Authenticate(c, h.jwks, h.revocation)
+				if e != nil {
+					return c.Status(e.Code).JSON(fiber.Map{
+						"error": e.Message,
+					})
+				}
+				c.Locals("user", claims)
+			}
+			if len(route.Roles) > 0 && !middleware.HasRole(claims, route.Roles...) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Access denied: Insufficient privileges",
+				})
+			}
+			values["claims.username"] = claims.Username
+			values["claims.userId"] = strconv.FormatUint(uint64(claims.UserID), 10)
+			values["claims.email"] = claims.Email
+			values["claims.role"] = string(claims.Role)
+		}
+
+		if route.RateLimit != "" {
+			if e := middleware.CheckRateLimit(c, h.rateLimits, h.policies[route.RateLimit]); e != nil {
+				return c.Status(e.Code).JSON(fiber.Map{
+					"error": e.Message,
+				})
+			}
+		}
+
+		clientQuery, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid query string",
+			})
+		}
+		target := route.UpstreamURL(values, route.UpstreamQuery(clientQuery, values))
+
+		var body []byte
+		if route.Method != fiber.MethodGet && route.Method != fiber.MethodHead {
+			body = c.Body()
+		}
+
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		if route.Transform == manifest.TransformRaw {
+			return routes.RawForwardRequest(req, resp, c, target, route.Method, body)
+		}
+		return routes.ForwardRequest(req, resp, c, target, route.Method, body)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gateway/internal/routes"
+	"log/slog"
 	"net/url"
 	"os"
 	"time"
@@ -35,7 +36,6 @@ func (p *PaymentHandler) HandleCreatePayment() fiber.Handler {
 		params.Add("orderId", c.Query("orderId"))
 
 		endpoint := fmt.Sprintf("%s/api/payment/paypal/create?%s", p.paymentServiceURL, params.Encode())
-		fmt.Println(endpoint)
 
 		return routes.CreatePayment(req, resp, c, endpoint)
 	}
@@ -56,7 +56,7 @@ func (h *PaymentHandler) HandleCompletePayPalPayment() fiber.Handler {
 		endpoint := fmt.Sprintf("%s/api/payment/paypal/success?%s", h.paymentServiceURL, params.Encode())
 		err := routes.CompletePayment(req, resp, c, endpoint)
 		if err != nil {
-			fmt.Println("PAYMENT COMPLETEMENT FAILED:", err)
+			slog.ErrorContext(c.UserContext(), "Completing payment failed", "error", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "payment is success but failed to complete payment in final steps",
 			})
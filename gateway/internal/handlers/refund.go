@@ -31,9 +31,9 @@ func (h *RefundHandler) HandleCreateRefundRequest() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find username in token claim"})
 		}
 		current_email := claims.Email
-		current_id := claims.Id
+		current_id := claims.UserID
 		current_username := claims.Username
-		url := fmt.Sprintf("%s/api/refunds?email=%s&id=%s&username=%s", h.adminServiceURL, current_email, current_id, current_username)
+		url := fmt.Sprintf("%s/api/refunds?email=%s&id=%d&username=%s", h.adminServiceURL, current_email, current_id, current_username)
 		return routes.ForwardRequest(req, resp, c, url, "POST", c.Body())
 	}
 }
@@ -94,7 +94,7 @@ func (h *RefundHandler) HandleProcessRefundRequest() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "cannot find username in token claim"})
 		}
 		current_role := claims.Role
-		current_id := claims.Id
+		current_id := claims.ID
 		id := c.Params("id")
 		url := fmt.Sprintf("%s/api/admin/refunds/%s/process?adminid=%s&role=%s", h.adminServiceURL, id, current_id, current_role)
 		return routes.ForwardRequest(req, resp, c, url, "PUT", c.Body())
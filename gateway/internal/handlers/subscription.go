@@ -117,6 +117,20 @@ func (h *SubscriptionHandler) HandleChangePlan() fiber.Handler {
 	}
 }
 
+func (h *SubscriptionHandler) HandlePreviewPlanChange() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		id := c.Params("id")
+		url := fmt.Sprintf("%s/api/subscriptions/%s/change-plan/preview", h.subscriptionServiceURL, id)
+
+		return routes.ForwardRequest(req, resp, c, url, "POST", c.Body())
+	}
+}
+
 // Admin handlers
 
 func (h *SubscriptionHandler) HandleGetAllSubscriptions() fiber.Handler {
@@ -150,6 +164,20 @@ func (h *SubscriptionHandler) HandleUpdateSubscriptionStatus() fiber.Handler {
 	}
 }
 
+func (h *SubscriptionHandler) HandleGetSubscriptionEvents() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		id := c.Params("id")
+		url := fmt.Sprintf("%s/api/admin/subscriptions/%s/events", h.subscriptionServiceURL, id)
+
+		return routes.ForwardRequest(req, resp, c, url, "GET", nil)
+	}
+}
+
 func (h *SubscriptionHandler) HandleAdminCreatePlan() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		req := fasthttp.AcquireRequest()
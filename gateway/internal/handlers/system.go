@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gateway/internal/config"
+	"gateway/internal/upstream"
+	"platform/health"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// ServiceStatus is what the gateway saw when it asked one service for its readiness.
+// Checks is only filled for services that answer with a readiness report.
+type ServiceStatus struct {
+	Status    string                   `json:"status"`
+	LatencyMS int64                    `json:"latency_ms"`
+	Error     string                   `json:"error,omitempty"`
+	Checks    map[string]health.Result `json:"checks,omitempty"`
+}
+
+type SystemHandler struct {
+	probes    map[string]string
+	readiness []health.Check
+}
+
+// NewSystemHandler probes the Go services' /readyz. payment-service only has /health and
+// node-service only its root, so for those a non 5xx answer is all there is to know.
+func NewSystemHandler(cfg *config.Config, readiness []health.Check) *SystemHandler {
+	return &SystemHandler{
+		probes: map[string]string{
+			"auth":         cfg.AuthServiceURL + "/readyz",
+			"user":         cfg.UserServiceURL + "/readyz",
+			"admin":        cfg.AdminServiceURL + "/readyz",
+			"google":       cfg.GoogleServiceURL + "/readyz",
+			"subscription": cfg.SubscriptionURL + "/readyz",
+			"payment":      cfg.PaymentServiceURL + "/health",
+			"node":         cfg.NodeServiceURL + "/",
+		},
+		readiness: readiness,
+	}
+}
+
+// HandleSystemStatus gathers every service's readiness, the gateway's own and the state
+// of its circuit breakers into one view. The overall status is ok only if all of it is.
+func (h *SystemHandler) HandleSystemStatus() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		services := make(map[string]ServiceStatus, len(h.probes))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, url := range h.probes {
+			wg.Add(1)
+			go func(name, url string) {
+				defer wg.Done()
+				status := probeService(url)
+				mu.Lock()
+				services[name] = status
+				mu.Unlock()
+			}(name, url)
+		}
+		gateway := health.Run(c.UserContext(), "gateway", h.readiness)
+		wg.Wait()
+
+		overall := health.StatusOK
+		if gateway.Status != health.StatusOK {
+			overall = "degraded"
+		}
+		for _, service := range services {
+			if service.Status != health.StatusOK {
+				overall = "degraded"
+			}
+		}
+		breakers := upstream.Status()
+		for _, breaker := range breakers {
+			if breaker.State != upstream.StateClosed {
+				overall = "degraded"
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":     overall,
+			"checked_at": time.Now(),
+			"gateway":    gateway,
+			"services":   services,
+			"breakers":   breakers,
+		})
+	}
+}
+
+func probeService(url string) ServiceStatus {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(url)
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	start := time.Now()
+	err := fasthttp.DoTimeout(req, resp, health.CheckTimeout+time.Second)
+	status := ServiceStatus{LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = health.StatusDown
+		status.Error = err.Error()
+		return status
+	}
+
+	var report health.Report
+	if json.Unmarshal(resp.Body(), &report) == nil && report.Checks != nil {
+		status.Status = report.Status
+		status.Checks = report.Checks
+		return status
+	}
+	if resp.StatusCode() >= fiber.StatusInternalServerError {
+		status.Status = health.StatusDown
+		status.Error = fasthttp.StatusMessage(resp.StatusCode())
+		return status
+	}
+	status.Status = health.StatusOK
+	return status
+}
@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Loader holds the live route table. Reloads swap the whole table at once, so a request
+// sees either the old routes or the new ones, never a mix.
+type Loader struct {
+	path    string
+	opts    Options
+	table   atomic.Pointer[Table]
+	modTime time.Time
+	stop    chan struct{}
+}
+
+// NewLoader reads the manifest at path. A manifest that cannot be loaded at startup is
+// an error; a bad edit later only keeps the previous table.
+func NewLoader(path string, opts Options) (*Loader, error) {
+	l := &Loader{path: path, opts: opts, stop: make(chan struct{})}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Table returns the routes currently in effect
+func (l *Loader) Table() *Table {
+	return l.table.Load()
+}
+
+func (l *Loader) load() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("cannot read route manifest: %v", err)
+	}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("cannot read route manifest: %v", err)
+	}
+	table, err := Parse(data, l.opts)
+	if err != nil {
+		return err
+	}
+
+	l.table.Store(table)
+	l.modTime = info.ModTime()
+	return nil
+}
+
+// Watch polls the manifest every interval and reloads it when the file changes, until Stop.
+// Polling rather than inotify keeps it working on bind mounts and ConfigMaps.
+func (l *Loader) Watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(l.path)
+			if err != nil {
+				slog.Error("Route manifest stat failed", "path", l.path, "error", err)
+				continue
+			}
+			if info.ModTime().Equal(l.modTime) {
+				continue
+			}
+			if err := l.load(); err != nil {
+				// Remember the broken version so it is reported once, not every tick
+				l.modTime = info.ModTime()
+				slog.Error("Route manifest reload failed, keeping previous routes", "path", l.path, "error", err)
+				continue
+			}
+			slog.Info("Route manifest reloaded", "path", l.path, "routes", l.Table().Len())
+		}
+	}()
+}
+
+// Stop ends the Watch loop. It must be called at most once.
+func (l *Loader) Stop() {
+	close(l.stop)
+}
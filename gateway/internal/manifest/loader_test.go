@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	oneRoute  = `{"routes": [{"method": "GET", "path": "/a", "upstream": "node"}]}`
+	twoRoutes = `{"routes": [{"method": "GET", "path": "/a", "upstream": "node"}, {"method": "GET", "path": "/b", "upstream": "node"}]}`
+)
+
+// writeManifest replaces the file and moves its mtime forward, so the watcher sees a
+// change even on filesystems with a coarse clock
+func writeManifest(t *testing.T, path, data string, version int) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Duration(version) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually polls cond until it holds or a second has passed
+func eventually(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func TestNewLoaderFailsOnABadManifest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewLoader(filepath.Join(dir, "missing.json"), testOptions); err == nil {
+		t.Error("NewLoader with no file, want an error")
+	}
+
+	path := filepath.Join(dir, "routes.json")
+	writeManifest(t, path, `{"routes": [{"method": "GET", "path": "/a", "upstream": "billing"}]}`, 0)
+	if _, err := NewLoader(path, testOptions); err == nil {
+		t.Error("NewLoader with an unknown upstream, want an error")
+	}
+}
+
+func TestLoaderReloadsAndKeepsTheLastGoodManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	writeManifest(t, path, oneRoute, 0)
+
+	loader, err := NewLoader(path, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Watch(5 * time.Millisecond)
+	defer loader.Stop()
+
+	if loader.Table().Len() != 1 {
+		t.Fatalf("loaded %d routes, want 1", loader.Table().Len())
+	}
+
+	writeManifest(t, path, twoRoutes, 1)
+	if !eventually(func() bool { return loader.Table().Len() == 2 }) {
+		t.Fatalf("table still has %d routes after the edit, want 2", loader.Table().Len())
+	}
+	good := loader.Table()
+
+	writeManifest(t, path, `{"routes": [`, 2)
+	time.Sleep(50 * time.Millisecond)
+	if loader.Table() != good {
+		t.Fatal("a broken edit replaced the table, want the last good one kept")
+	}
+	if route, _, _ := loader.Table().Match("GET", "/b"); route == nil {
+		t.Error("route from the last good manifest no longer matches")
+	}
+
+	writeManifest(t, path, oneRoute, 3)
+	if !eventually(func() bool { return loader.Table().Len() == 1 }) {
+		t.Errorf("table has %d routes after the fix, want the fixed manifest loaded", loader.Table().Len())
+	}
+}
+
+func TestWatchWithoutIntervalDoesNotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	writeManifest(t, path, oneRoute, 0)
+
+	loader, err := NewLoader(path, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Watch(0)
+
+	writeManifest(t, path, twoRoutes, 1)
+	time.Sleep(20 * time.Millisecond)
+	if loader.Table().Len() != 1 {
+		t.Errorf("table has %d routes, want reloading off", loader.Table().Len())
+	}
+}
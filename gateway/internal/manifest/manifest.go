@@ -0,0 +1,261 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"platform/contracts"
+	"regexp"
+	"strings"
+)
+
+// Response transformers a route can name. The envelope wraps JSON as {"data", "message"}
+// like the hand written handlers do; raw hands the upstream response back untouched.
+const (
+	TransformEnvelope = "envelope"
+	TransformRaw      = "raw"
+)
+
+// Manifest is the file format: a list of routes, matched in the order they are declared
+type Manifest struct {
+	Routes []Route `json:"routes"`
+}
+
+// Route maps one gateway endpoint onto an upstream service.
+//
+// Path uses the gateway's :param syntax. UpstreamPath and the values in Query.Set may
+// reference path params as {param} and the caller's token as {claims.username},
+// {claims.userId}, {claims.email} or {claims.role}.
+type Route struct {
+	Method       string           `json:"method"`
+	Path         string           `json:"path"`
+	Upstream     string           `json:"upstream"`
+	UpstreamPath string           `json:"upstream_path"`
+	Auth         bool             `json:"auth"`
+	Roles        []contracts.Role `json:"roles"`
+	Query        QueryRules       `json:"query"`
+	Transform    string           `json:"transform"`
+	RateLimit    string           `json:"rate_limit"`
+
+	baseURL  string
+	segments []string
+}
+
+// QueryRules decide what reaches the upstream query string. Forward lists the client
+// params to pass on, "*" passes all of them. Set is applied last, so a client cannot
+// override a value the gateway fills in.
+type QueryRules struct {
+	Forward []string          `json:"forward"`
+	Set     map[string]string `json:"set"`
+}
+
+// Options are the names a manifest is allowed to refer to
+type Options struct {
+	// Upstreams maps an upstream name to the base URL of the service
+	Upstreams map[string]string
+	// RateLimits are the rate limit policy names the gateway knows
+	RateLimits map[string]bool
+}
+
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+var claimFields = map[string]bool{
+	"claims.username": true,
+	"claims.userId":   true,
+	"claims.email":    true,
+	"claims.role":     true,
+}
+
+// Table is a parsed and validated manifest, ready to match requests against
+type Table struct {
+	routes []*Route
+}
+
+// Parse reads a manifest and checks every route against opts, so a typo fails the load
+// instead of the first request that hits it
+func Parse(data []byte, opts Options) (*Table, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid route manifest: %v", err)
+	}
+
+	table := &Table{}
+	seen := make(map[string]bool)
+	for i := range m.Routes {
+		route := m.Routes[i]
+		if err := route.compile(opts); err != nil {
+			return nil, fmt.Errorf("route %d (%s %s): %v", i, route.Method, route.Path, err)
+		}
+		key := route.Method + " " + route.shape()
+		if seen[key] {
+			return nil, fmt.Errorf("route %d (%s %s): declared twice", i, route.Method, route.Path)
+		}
+		seen[key] = true
+		table.routes = append(table.routes, &route)
+	}
+	return table, nil
+}
+
+func (r *Route) compile(opts Options) error {
+	r.Method = strings.ToUpper(strings.TrimSpace(r.Method))
+	if r.Method == "" {
+		return fmt.Errorf("method is required")
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+
+	baseURL, ok := opts.Upstreams[r.Upstream]
+	if !ok {
+		return fmt.Errorf("unknown upstream %q", r.Upstream)
+	}
+	if baseURL == "" {
+		return fmt.Errorf("upstream %q has no URL configured", r.Upstream)
+	}
+	r.baseURL = strings.TrimRight(baseURL, "/")
+
+	switch r.Transform {
+	case "":
+		r.Transform = TransformEnvelope
+	case TransformEnvelope, TransformRaw:
+	default:
+		return fmt.Errorf("unknown transform %q", r.Transform)
+	}
+
+	if r.RateLimit != "" && !opts.RateLimits[r.RateLimit] {
+		return fmt.Errorf("unknown rate limit policy %q", r.RateLimit)
+	}
+	// Roles can only be checked against a token
+	if len(r.Roles) > 0 {
+		r.Auth = true
+	}
+
+	params := make(map[string]bool)
+	r.segments = splitPath(r.Path)
+	for _, segment := range r.segments {
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = true
+		}
+	}
+
+	templates := []string{r.UpstreamPath}
+	for _, value := range r.Query.Set {
+		templates = append(templates, value)
+	}
+	for _, template := range templates {
+		for _, match := range placeholder.FindAllStringSubmatch(template, -1) {
+			name := match[1]
+			switch {
+			case params[name]:
+			case claimFields[name]:
+				if !r.Auth {
+					return fmt.Errorf("%s needs a signed in caller, set auth", match[0])
+				}
+			default:
+				return fmt.Errorf("unknown placeholder %s", match[0])
+			}
+		}
+	}
+	return nil
+}
+
+// shape is the path with param names blanked, two routes with the same shape can never both match
+func (r *Route) shape() string {
+	parts := make([]string, len(r.segments))
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, ":") {
+			segment = ":"
+		}
+		parts[i] = strings.ToLower(segment)
+	}
+	return strings.Join(parts, "/")
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Match finds the first route for method and path. When the path is known but only under
+// other methods, it reports pathMatched so the caller can answer 405 instead of 404.
+func (t *Table) Match(method, path string) (route *Route, params map[string]string, pathMatched bool) {
+	requested := splitPath(path)
+	for _, r := range t.routes {
+		p, ok := r.match(requested)
+		if !ok {
+			continue
+		}
+		if r.Method == method || (method == "HEAD" && r.Method == "GET") {
+			return r, p, true
+		}
+		pathMatched = true
+	}
+	return nil, nil, pathMatched
+}
+
+func (r *Route) match(requested []string) (map[string]string, bool) {
+	if len(requested) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, ":") {
+			value, err := url.PathUnescape(requested[i])
+			// PathEscape leaves dot segments alone, so upstream they would climb out of the route's path
+			if err != nil || value == "" || value == "." || value == ".." {
+				return nil, false
+			}
+			params[segment[1:]] = value
+			continue
+		}
+		// Fiber routes are case insensitive by default, keep manifest routes the same
+		if !strings.EqualFold(segment, requested[i]) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Len is the number of routes in the table
+func (t *Table) Len() int {
+	return len(t.routes)
+}
+
+// UpstreamURL renders the upstream URL for a matched request. values holds the path
+// params plus whichever claims.* fields the caller's token provides.
+func (r *Route) UpstreamURL(values map[string]string, query url.Values) string {
+	upstreamPath := placeholder.ReplaceAllStringFunc(r.UpstreamPath, func(match string) string {
+		return url.PathEscape(values[match[1:len(match)-1]])
+	})
+
+	target := r.baseURL + upstreamPath
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return target
+}
+
+// UpstreamQuery applies the route's query rules to the client's query string
+func (r *Route) UpstreamQuery(client url.Values, values map[string]string) url.Values {
+	query := url.Values{}
+	for _, name := range r.Query.Forward {
+		if name == "*" {
+			for key, vals := range client {
+				query[key] = append([]string(nil), vals...)
+			}
+			continue
+		}
+		if vals, ok := client[name]; ok {
+			query[name] = append([]string(nil), vals...)
+		}
+	}
+	for key, template := range r.Query.Set {
+		query.Set(key, placeholder.ReplaceAllStringFunc(template, func(match string) string {
+			return values[match[1:len(match)-1]]
+		}))
+	}
+	return query
+}
@@ -0,0 +1,186 @@
+package manifest
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var testOptions = Options{
+	Upstreams: map[string]string{
+		"node":    "http://node:3000/",
+		"user":    "http://user:8082",
+		"missing": "",
+	},
+	RateLimits: map[string]bool{"public": true},
+}
+
+func parse(t *testing.T, manifest string) *Table {
+	t.Helper()
+	table, err := Parse([]byte(manifest), testOptions)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return table
+}
+
+func TestParseFillsDefaults(t *testing.T) {
+	table := parse(t, `{"routes": [
+		{"method": "get", "path": "/courses/:id", "upstream": "node", "upstream_path": "/courses/{id}"},
+		{"method": "DELETE", "path": "/admin/courses/:id", "upstream": "node", "upstream_path": "/courses/{id}", "roles": ["Admin"], "transform": "raw"}
+	]}`)
+
+	if table.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", table.Len())
+	}
+	route, _, _ := table.Match("GET", "/courses/1")
+	if route == nil || route.Method != "GET" || route.Transform != TransformEnvelope || route.Auth {
+		t.Errorf("route %+v, want method upper-cased, the envelope transform and no auth", route)
+	}
+	route, _, _ = table.Match("DELETE", "/admin/courses/1")
+	if route == nil || !route.Auth || route.Transform != TransformRaw {
+		t.Errorf("route %+v, want roles to imply auth and the raw transform kept", route)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		route string
+		error string
+	}{
+		{"no method", `{"path": "/a", "upstream": "node"}`, "method is required"},
+		{"relative path", `{"method": "GET", "path": "a", "upstream": "node"}`, "must start with /"},
+		{"unknown upstream", `{"method": "GET", "path": "/a", "upstream": "billing"}`, `unknown upstream "billing"`},
+		{"upstream without a URL", `{"method": "GET", "path": "/a", "upstream": "missing"}`, "no URL configured"},
+		{"unknown transform", `{"method": "GET", "path": "/a", "upstream": "node", "transform": "xml"}`, `unknown transform "xml"`},
+		{"unknown rate limit", `{"method": "GET", "path": "/a", "upstream": "node", "rate_limit": "bulk"}`, `unknown rate limit policy "bulk"`},
+		{"unknown path param", `{"method": "GET", "path": "/a/:id", "upstream": "node", "upstream_path": "/a/{slug}"}`, "unknown placeholder {slug}"},
+		{"unknown query placeholder", `{"method": "GET", "path": "/a", "upstream": "node", "query": {"set": {"x": "{claims.phone}"}}}`, "unknown placeholder {claims.phone}"},
+		{"claims without auth", `{"method": "GET", "path": "/me", "upstream": "user", "upstream_path": "/users/{claims.username}"}`, "needs a signed in caller"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(`{"routes": [`+tc.route+`]}`), testOptions)
+			if err == nil || !strings.Contains(err.Error(), tc.error) {
+				t.Errorf("Parse error %v, want one about %q", err, tc.error)
+			}
+		})
+	}
+}
+
+func TestParseRejectsBadJSON(t *testing.T) {
+	if _, err := Parse([]byte(`{"routes": [`), testOptions); err == nil || !strings.Contains(err.Error(), "invalid route manifest") {
+		t.Errorf("Parse error %v, want invalid route manifest", err)
+	}
+}
+
+func TestParseRejectsRoutesThatOverlap(t *testing.T) {
+	_, err := Parse([]byte(`{"routes": [
+		{"method": "GET", "path": "/courses/:id", "upstream": "node"},
+		{"method": "GET", "path": "/Courses/:slug", "upstream": "node"}
+	]}`), testOptions)
+	if err == nil || !strings.Contains(err.Error(), "declared twice") {
+		t.Errorf("Parse error %v, want the second route reported as declared twice", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	table := parse(t, `{"routes": [
+		{"method": "GET", "path": "/courses/all", "upstream": "node"},
+		{"method": "GET", "path": "/courses/:id", "upstream": "node"},
+		{"method": "PUT", "path": "/courses/:id", "upstream": "node", "auth": true}
+	]}`)
+
+	for _, tc := range []struct {
+		method, path string
+		wantPath     string
+		wantParams   map[string]string
+		pathMatched  bool
+	}{
+		{"GET", "/courses/all", "/courses/all", map[string]string{}, true},
+		{"GET", "/COURSES/7/", "/courses/:id", map[string]string{"id": "7"}, true},
+		{"HEAD", "/courses/7", "/courses/:id", map[string]string{"id": "7"}, true},
+		{"GET", "/courses/a%20b", "/courses/:id", map[string]string{"id": "a b"}, true},
+		{"GET", "/courses/..", "", nil, false},
+		{"GET", "/courses/%2e%2E", "", nil, false},
+		{"GET", "/courses/%2E", "", nil, false},
+		{"GET", "/courses/...", "/courses/:id", map[string]string{"id": "..."}, true},
+		{"PUT", "/courses/7", "/courses/:id", map[string]string{"id": "7"}, true},
+		{"DELETE", "/courses/7", "", nil, true},
+		{"GET", "/courses/7/lessons", "", nil, false},
+		{"GET", "/courses", "", nil, false},
+	} {
+		route, params, pathMatched := table.Match(tc.method, tc.path)
+		gotPath := ""
+		if route != nil {
+			gotPath = route.Path
+		}
+		if gotPath != tc.wantPath || pathMatched != tc.pathMatched || !equalParams(params, tc.wantParams) {
+			t.Errorf("Match(%s %s) = %q %v %v, want %q %v %v",
+				tc.method, tc.path, gotPath, params, pathMatched, tc.wantPath, tc.wantParams, tc.pathMatched)
+		}
+	}
+}
+
+func equalParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUpstreamURLSubstitutesTemplates(t *testing.T) {
+	table := parse(t, `{"routes": [
+		{"method": "GET", "path": "/tutors/:tutorId/courses/:id", "upstream": "node", "auth": true,
+		 "upstream_path": "/users/{claims.userId}/tutors/{tutorId}/courses/{id}"}
+	]}`)
+	route, params, _ := table.Match("GET", "/tutors/t%2F1/courses/c 2")
+	if route == nil {
+		t.Fatal("route not matched")
+	}
+	params["claims.userId"] = "42"
+
+	got := route.UpstreamURL(params, url.Values{"page": {"2"}})
+	want := "http://node:3000/users/42/tutors/t%2F1/courses/c%202?page=2"
+	if got != want {
+		t.Errorf("UpstreamURL = %q, want %q", got, want)
+	}
+	if got := route.UpstreamURL(params, nil); strings.Contains(got, "?") {
+		t.Errorf("UpstreamURL without a query = %q, want no ?", got)
+	}
+}
+
+func TestUpstreamQuery(t *testing.T) {
+	client := url.Values{"page": {"2"}, "tag": {"go", "fiber"}, "owner": {"mallory"}}
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  url.Values
+	}{
+		{"nothing forwarded by default", `{}`, url.Values{}},
+		{"listed params only", `{"forward": ["tag", "sort"]}`, url.Values{"tag": {"go", "fiber"}}},
+		{"everything with *", `{"forward": ["*"]}`, client},
+		{"set overrides the client", `{"forward": ["*"], "set": {"owner": "{claims.username}", "course": "{id}"}}`,
+			url.Values{"page": {"2"}, "tag": {"go", "fiber"}, "owner": {"alice"}, "course": {"7"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			table := parse(t, `{"routes": [{"method": "GET", "path": "/courses/:id", "upstream": "node", "auth": true, "query": `+tc.query+`}]}`)
+			route, params, _ := table.Match("GET", "/courses/7")
+			params["claims.username"] = "alice"
+
+			got := route.UpstreamQuery(client, params)
+			if got.Encode() != tc.want.Encode() {
+				t.Errorf("UpstreamQuery = %q, want %q", got.Encode(), tc.want.Encode())
+			}
+		})
+	}
+	if len(client["tag"]) != 2 || client.Get("owner") != "mallory" {
+		t.Errorf("client query changed to %v, it must be copied", client)
+	}
+}
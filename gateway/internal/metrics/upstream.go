@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gateway_upstream_request_duration_seconds",
+	Help:    "Time spent on each call to an upstream service, retries counted separately.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"upstream", "method", "status"})
+
+// ObserveUpstream records one attempt against an upstream. Attempts that got no answer at all
+// are labelled with status "error".
+func ObserveUpstream(upstream, method string, status int, duration time.Duration) {
+	label := "error"
+	if status > 0 {
+		label = strconv.Itoa(status)
+	}
+	upstreamDuration.WithLabelValues(upstream, method, label).Observe(duration.Seconds())
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/valyala/fasthttp"
+)
+
+// minRefetchInterval stops a flood of tokens with a made-up kid from hammering the auth service
+const minRefetchInterval = 30 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// JWKSCache keeps the auth service's public keys. Keys are refetched after ttl,
+// or early when a token names a kid we have not seen, which is how rotations are picked up.
+type JWKSCache struct {
+	url string
+	ttl time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]verificationKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewJWKSCache(url string, ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:  url,
+		ttl:  ttl,
+		keys: make(map[string]verificationKey),
+	}
+}
+
+// Keyfunc resolves the verification key for a token by its kid header
+func (j *JWKSCache) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	key, ok, stale := j.lookup(kid)
+	if !ok || stale {
+		if err := j.refresh(!ok); err != nil {
+			slog.Error("JWKS refresh failed", "error", err)
+		}
+		key, ok, _ = j.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// Ready reports whether there are keys to verify tokens with. Only an empty cache calls
+// the auth service, so a gateway that has keys stays ready while auth-service restarts.
+func (j *JWKSCache) Ready(context.Context) error {
+	j.mu.RLock()
+	loaded := len(j.keys) > 0
+	j.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+	return j.refresh(true)
+}
+
+func (j *JWKSCache) lookup(kid string) (verificationKey, bool, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.keys[kid]
+	return key, ok, time.Since(j.fetchedAt) > j.ttl
+}
+
+func (j *JWKSCache) refresh(unknownKid bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Another request may have refreshed while we waited for the lock
+	if !unknownKid && time.Since(j.fetchedAt) <= j.ttl {
+		return nil
+	}
+	if time.Since(j.lastAttempt) < minRefetchInterval && len(j.keys) > 0 {
+		return nil
+	}
+	j.lastAttempt = time.Now()
+
+	keys, err := fetchJWKS(j.url)
+	if err != nil {
+		return err
+	}
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	return nil
+}
+
+func fetchJWKS(url string) (map[string]verificationKey, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	if err := fasthttp.DoTimeout(req, resp, 5*time.Second); err != nil {
+		return nil, fmt.Errorf("auth service unavailable: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, fmt.Errorf("JWKS fetch failed with status %d", resp.StatusCode())
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(resp.Body(), &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping JWKS key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (verificationKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{
+			alg: jwt.SigningMethodRS256.Alg(),
+			key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+		}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return verificationKey{}, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return verificationKey{}, errors.New("invalid Ed25519 key size")
+		}
+		return verificationKey{
+			alg: jwt.SigningMethodEdDSA.Alg(),
+			key: ed25519.PublicKey(x),
+		}, nil
+	}
+	return verificationKey{}, fmt.Errorf("unsupported key type %s", k.Kty)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves one Ed25519 key while up is set and a 503 otherwise
+func jwksServer(t *testing.T, up *atomic.Bool) (*httptest.Server, *atomic.Int32) {
+	public, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := fmt.Sprintf(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","alg":"EdDSA","x":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(public))
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(set))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestJWKSCacheReadyNeedsKeys(t *testing.T) {
+	var up atomic.Bool
+	server, _ := jwksServer(t, &up)
+	cache := NewJWKSCache(server.URL, time.Minute)
+
+	if err := cache.Ready(context.Background()); err == nil {
+		t.Fatal("Ready with no keys and auth-service down, want an error")
+	}
+
+	up.Store(true)
+	if err := cache.Ready(context.Background()); err != nil {
+		t.Fatalf("Ready once the keys can be fetched: %v", err)
+	}
+}
+
+func TestJWKSCacheReadyDoesNotCallAuthServiceOnceLoaded(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	server, calls := jwksServer(t, &up)
+	cache := NewJWKSCache(server.URL, time.Minute)
+
+	if err := cache.Ready(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	up.Store(false)
+	for range 3 {
+		if err := cache.Ready(context.Background()); err != nil {
+			t.Errorf("Ready with cached keys while auth-service is down: %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("auth-service called %d times, want 1", n)
+	}
+}
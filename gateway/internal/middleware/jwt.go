@@ -1,51 +1,24 @@
 package middleware
 
 import (
-	"fmt"
+	"log/slog"
+	"platform/contracts"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// Custom claims struct
-type Claims struct {
-	jwt.StandardClaims
-	UserID   uint   `json:"userId"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-}
+// Claims are what auth-service signs into access tokens
+type Claims = contracts.Claims
 
-// JWTMiddleware validates the JWT token
-func JWTMiddleware(jwtSecret string) fiber.Handler {
+// JWTMiddleware validates the JWT token against the auth service's published keys
+// and rejects tokens the checker reports as revoked
+func JWTMiddleware(jwks *JWKSCache, checker RevocationChecker) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		token := c.Get("Authorization")
-		if len(token) > 7 && token[:7] == "Bearer " {
-			token = token[7:]
-		}
-		if token == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing authorization token",
-			})
-		}
-
-		// Parse and validate token with custom claims
-		claims := &Claims{}
-		parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
-			})
-		}
-
-		if !parsedToken.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Token is not valid",
+		claims, e := Authenticate(c, jwks, checker)
+		if e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
 			})
 		}
 
@@ -55,8 +28,41 @@ func JWTMiddleware(jwtSecret string) fiber.Handler {
 	}
 }
 
+// Authenticate checks the request's bearer token without writing a response
+func Authenticate(c *fiber.Ctx, jwks *JWKSCache, checker RevocationChecker) (*Claims, *fiber.Error) {
+	token := c.Get("Authorization")
+	if len(token) > 7 && token[:7] == "Bearer " {
+		token = token[7:]
+	}
+	if token == "" {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Missing authorization token")
+	}
+
+	// Parse and validate token with custom claims
+	claims := &Claims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, jwks.Keyfunc)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid token")
+	}
+
+	if !parsedToken.Valid || claims.TokenUse != contracts.TokenUseAccess {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Token is not valid")
+	}
+
+	revoked, err := checker.IsRevoked(c.UserContext(), claims)
+	if err != nil {
+		// Fail closed: a token we cannot vouch for is not let through
+		slog.ErrorContext(c.UserContext(), "Revocation check failed", "error", err)
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Unable to verify token status")
+	}
+	if revoked {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Token has been revoked")
+	}
+	return claims, nil
+}
+
 // RequireRole middleware for role-based access control
-func RequireRole(roles ...string) fiber.Handler {
+func RequireRole(roles ...contracts.Role) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		claims, ok := c.Locals("user").(*Claims)
 		if !ok {
@@ -65,11 +71,8 @@ func RequireRole(roles ...string) fiber.Handler {
 			})
 		}
 
-		// Check if user's role matches any of the required roles
-		for _, role := range roles {
-			if claims.Role == role {
-				return c.Next()
-			}
+		if HasRole(claims, roles...) {
+			return c.Next()
 		}
 
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -77,3 +80,13 @@ func RequireRole(roles ...string) fiber.Handler {
 		})
 	}
 }
+
+// HasRole reports whether the user's role matches any of the given roles
+func HasRole(claims *Claims, roles ...contracts.Role) bool {
+	for _, role := range roles {
+		if claims.Role == role {
+			return true
+		}
+	}
+	return false
+}
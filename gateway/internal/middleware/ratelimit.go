@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"gateway/internal/config"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult is the outcome of taking one token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds token buckets. The memory store is per replica; the redis store
+// lets every gateway replica draw from the same buckets.
+type RateLimitStore interface {
+	Take(key string, policy config.RateLimitPolicy) (RateLimitResult, error)
+}
+
+// NewRateLimitStore picks the backend named in the config
+func NewRateLimitStore(cfg config.RateLimitConfig) (RateLimitStore, error) {
+	switch cfg.Backend {
+	case "memory", "":
+		return NewMemoryRateLimitStore(), nil
+	case "redis":
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+		}
+		return NewRedisRateLimitStore(redis.NewClient(opts)), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+}
+
+// RateLimit throttles requests with the policy's token bucket
+func RateLimit(store RateLimitStore, policy config.RateLimitPolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if e := CheckRateLimit(c, store, policy); e != nil {
+			return c.Status(e.Code).JSON(fiber.Map{
+				"error": e.Message,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// CheckRateLimit takes a token for the request and sets the rate limit headers. A failing
+// store lets requests through: losing throttling is better than losing the gateway.
+func CheckRateLimit(c *fiber.Ctx, store RateLimitStore, policy config.RateLimitPolicy) *fiber.Error {
+	key := "ratelimit:" + policy.Name + ":" + rateLimitIdentity(c, policy.Key)
+
+	result, err := store.Take(key, policy)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Rate limit store failed", "policy", policy.Name, "error", err)
+		return nil
+	}
+
+	c.Set("X-RateLimit-Limit", strconv.Itoa(policy.Burst))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many requests, please slow down")
+	}
+	return nil
+}
+
+// rateLimitIdentity names the bucket owner. Per user limits fall back to the client IP
+// when the route runs before authentication.
+func rateLimitIdentity(c *fiber.Ctx, key string) string {
+	switch key {
+	case config.RateLimitByUser:
+		if claims, ok := c.Locals("user").(*Claims); ok {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID), 10)
+		}
+	case config.RateLimitByRoute:
+		path := c.Route().Path
+		// Manifest routes all run through one catch-all, the dispatcher names the real route
+		if manifestPath, ok := c.Locals("route").(string); ok {
+			path = manifestPath
+		}
+		return "route:" + c.Method() + " " + path
+	}
+	return "ip:" + c.IP()
+}
+
+// refillRate is tokens per second
+func refillRate(policy config.RateLimitPolicy) float64 {
+	return float64(policy.Requests) / policy.Period.Seconds()
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(key string, policy config.RateLimitPolicy) (RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.rate = refillRate(policy)
+	b.burst = float64(policy.Burst)
+	b.tokens = b.refill(now)
+	b.last = now
+
+	// Full buckets carry no state worth keeping, drop them so the map does not grow forever
+	if len(s.buckets) > 10000 {
+		for k, other := range s.buckets {
+			if other != b && other.refill(now) >= other.burst {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		return RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: wait}, nil
+	}
+	b.tokens--
+	return RateLimitResult{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// tokenBucketScript refills and takes from a bucket in one step so concurrent
+// replicas cannot both spend the last token. Redis' own clock keeps replicas consistent.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate))
+return {allowed, math.floor(tokens), wait}
+`)
+
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+func NewRedisRateLimitStore(client *redis.Client) RateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+// Close releases the Redis connections on shutdown
+func (s *redisRateLimitStore) Close() error {
+	return s.client.Close()
+}
+
+// Ping lets readiness checks see whether Redis is reachable
+func (s *redisRateLimitStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *redisRateLimitStore) Take(key string, policy config.RateLimitPolicy) (RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The script works in milliseconds
+	rate := refillRate(policy) / 1000
+	values, err := tokenBucketScript.Run(ctx, s.client, []string{key}, rate, policy.Burst).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
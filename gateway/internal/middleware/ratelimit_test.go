@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"gateway/internal/config"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// A token every 10ms, at most 3 saved up
+var testPolicy = config.RateLimitPolicy{Name: "test", Requests: 100, Period: time.Second, Burst: 3, Key: config.RateLimitByIP}
+
+func TestMemoryStoreSpendsBurstThenRefills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+
+	for i := range testPolicy.Burst {
+		result, err := store.Take("k", testPolicy)
+		if err != nil || !result.Allowed {
+			t.Fatalf("take %d = %+v, %v, want allowed within the burst", i+1, result, err)
+		}
+		if want := testPolicy.Burst - i - 1; result.Remaining != want {
+			t.Errorf("take %d left %d tokens, want %d", i+1, result.Remaining, want)
+		}
+	}
+
+	result, _ := store.Take("k", testPolicy)
+	if result.Allowed {
+		t.Fatal("take past the burst allowed")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 10*time.Millisecond {
+		t.Errorf("retry after %v, want up to one token's refill time of 10ms", result.RetryAfter)
+	}
+
+	time.Sleep(result.RetryAfter + 2*time.Millisecond)
+	if result, _ := store.Take("k", testPolicy); !result.Allowed {
+		t.Error("take after the refill time refused")
+	}
+}
+
+func TestMemoryStoreKeepsBucketsApart(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	for range testPolicy.Burst {
+		store.Take("a", testPolicy)
+	}
+	if result, _ := store.Take("b", testPolicy); !result.Allowed || result.Remaining != testPolicy.Burst-1 {
+		t.Errorf("first take from another key = %+v, want a full bucket", result)
+	}
+}
+
+func TestRateLimitAnswers429WithRetryAfter(t *testing.T) {
+	policy := config.RateLimitPolicy{Name: "slow", Requests: 1, Period: 90 * time.Second, Burst: 1, Key: config.RateLimitByIP}
+	app := fiber.New()
+	app.Get("/", RateLimit(NewMemoryRateLimitStore(), policy), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("X-RateLimit-Limit") != "1" || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("first request %d with limit %q remaining %q, want 200, 1 and 0",
+			resp.StatusCode, resp.Header.Get("X-RateLimit-Limit"), resp.Header.Get("X-RateLimit-Remaining"))
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("second request %d, want 429", resp.StatusCode)
+	}
+	retryAfter, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	if err != nil || retryAfter < 89 || retryAfter > 90 {
+		t.Errorf("Retry-After %q, want the 90 seconds until the next token", resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+}
+
+// keyStore records the bucket keys it is asked for and allows everything
+type keyStore struct {
+	keys []string
+}
+
+func (s *keyStore) Take(key string, policy config.RateLimitPolicy) (RateLimitResult, error) {
+	s.keys = append(s.keys, key)
+	return RateLimitResult{Allowed: true, Remaining: policy.Burst}, nil
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		key   string
+		setup fiber.Handler
+		want  string
+	}{
+		{"by ip", config.RateLimitByIP, nil, "ratelimit:test:ip:0.0.0.0"},
+		{"by user", config.RateLimitByUser, func(c *fiber.Ctx) error {
+			c.Locals("user", &Claims{UserID: 42})
+			return c.Next()
+		}, "ratelimit:test:user:42"},
+		{"by user before authentication", config.RateLimitByUser, nil, "ratelimit:test:ip:0.0.0.0"},
+		{"by route", config.RateLimitByRoute, nil, "ratelimit:test:route:GET /items/:id"},
+		{"by manifest route", config.RateLimitByRoute, func(c *fiber.Ctx) error {
+			c.Locals("route", "/catalog/items/:id")
+			return c.Next()
+		}, "ratelimit:test:route:GET /catalog/items/:id"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &keyStore{}
+			policy := testPolicy
+			policy.Key = tc.key
+
+			handlers := []fiber.Handler{RateLimit(store, policy), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusNoContent)
+			}}
+			if tc.setup != nil {
+				handlers = append([]fiber.Handler{tc.setup}, handlers...)
+			}
+			app := fiber.New()
+			app.Get("/items/:id", handlers...)
+
+			if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/items/7", nil)); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.keys) != 1 || store.keys[0] != tc.want {
+				t.Errorf("keys %q, want [%q]", store.keys, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"platform/client"
+	"sync"
+	"time"
+)
+
+// RevocationChecker tells whether an otherwise valid token has been revoked
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+}
+
+type revocationEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+type authRevocationChecker struct {
+	auth *client.AuthClient
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]revocationEntry
+}
+
+// NewRevocationChecker asks the auth service about each token and caches the answer for ttl,
+// so a block takes at most ttl to reach the gateway
+func NewRevocationChecker(auth *client.AuthClient, ttl time.Duration) RevocationChecker {
+	return &authRevocationChecker{
+		// Every authenticated request waits on this call, so it gives up sooner than most
+		auth:  &client.AuthClient{Client: auth.WithTimeout(2 * time.Second)},
+		ttl:   ttl,
+		cache: make(map[string]revocationEntry),
+	}
+}
+
+func (r *authRevocationChecker) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	key := claims.Username + "|" + claims.ID + "|" + claims.SessionID
+	now := time.Now()
+
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < r.ttl {
+		return entry.revoked, nil
+	}
+
+	revoked, err := r.auth.IsRevoked(ctx, claims)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.cache[key] = revocationEntry{revoked: revoked, checkedAt: now}
+	// Entries are keyed per token, drop stale ones so the map does not grow forever
+	if len(r.cache) > 10000 {
+		for k, e := range r.cache {
+			if now.Sub(e.checkedAt) >= r.ttl {
+				delete(r.cache, k)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	return revoked, nil
+}
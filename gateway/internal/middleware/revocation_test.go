@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"platform/client"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// revokedServer answers /revoked like auth-service, counting the calls it gets
+func revokedServer(t *testing.T, revoked bool) (*client.AuthClient, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/revoked" || r.URL.Query().Get("username") != "alice" {
+			http.Error(w, `{"error":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		if revoked {
+			w.Write([]byte(`{"revoked":true}`))
+			return
+		}
+		w.Write([]byte(`{"revoked":false}`))
+	}))
+	t.Cleanup(server.Close)
+	return client.NewAuthClient(server.URL, "key"), &calls
+}
+
+func TestRevocationCheckerCachesAnswers(t *testing.T) {
+	auth, calls := revokedServer(t, true)
+	checker := NewRevocationChecker(auth, time.Minute)
+	claims := &Claims{Username: "alice", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}}
+
+	for range 3 {
+		revoked, err := checker.IsRevoked(context.Background(), claims)
+		if err != nil || !revoked {
+			t.Fatalf("revoked %v err %v, want revoked", revoked, err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("auth-service asked %d times, want 1 within the ttl", got)
+	}
+
+	// Another token of the same user is a separate answer
+	other := &Claims{Username: "alice", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-2"}}
+	checker.IsRevoked(context.Background(), other)
+	if got := calls.Load(); got != 2 {
+		t.Errorf("auth-service asked %d times, want 2 for two tokens", got)
+	}
+}
+
+func TestRevocationCheckerAsksAgainAfterTTL(t *testing.T) {
+	auth, calls := revokedServer(t, false)
+	checker := NewRevocationChecker(auth, time.Millisecond)
+	claims := &Claims{Username: "alice", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}}
+
+	checker.IsRevoked(context.Background(), claims)
+	time.Sleep(5 * time.Millisecond)
+	if revoked, err := checker.IsRevoked(context.Background(), claims); err != nil || revoked {
+		t.Fatalf("revoked %v err %v, want not revoked", revoked, err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("auth-service asked %d times, want 2 once the ttl passed", got)
+	}
+}
+
+func TestRevocationCheckerReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"down"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	checker := NewRevocationChecker(client.NewAuthClient(server.URL, "key"), time.Minute)
+
+	claims := &Claims{Username: "alice", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}}
+	if _, err := checker.IsRevoked(context.Background(), claims); err == nil {
+		t.Error("no error when auth-service fails, the middleware could not fail closed")
+	}
+}
@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas reflects Go types into component schemas. Named structs become components and
+// are referenced, everything else is written inline.
+type schemas struct {
+	components map[string]*Schema
+	enums      map[reflect.Type][]any
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		enums:      make(map[reflect.Type][]any),
+	}
+}
+
+func (s *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s *schemas) forType(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if values, ok := s.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.forType(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := componentName(t)
+		if _, ok := s.components[name]; !ok {
+			// Claimed before the fields are walked so a type that refers to itself terminates
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, schema)
+	return schema
+}
+
+// fields adds t's JSON fields to schema, flattening embedded structs the way encoding/json does
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, schema)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.forType(field.Type)
+		if constrain(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// constrain carries the validate tag over to the schema and reports whether it makes the
+// field required. A $ref cannot carry constraints next to it, so those are left alone.
+func constrain(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(rule, "=")
+		if rule == "required" {
+			required = true
+		}
+		if schema.Ref != "" {
+			continue
+		}
+		numeric := schema.Type == "integer" || schema.Type == "number"
+		switch rule {
+		case "oneof":
+			schema.Enum = nil
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "email":
+			schema.Format = "email"
+		case "gt", "gte", "min":
+			if numeric {
+				schema.Minimum = number(param)
+				schema.ExclusiveMinimum = rule == "gt"
+			} else if n, err := strconv.Atoi(param); err == nil {
+				schema.MinLength = &n
+			}
+		case "lt", "lte", "max":
+			if numeric {
+				schema.Maximum = number(param)
+				schema.ExclusiveMaximum = rule == "lt"
+			} else if n, err := strconv.Atoi(param); err == nil {
+				schema.MaxLength = &n
+			}
+		}
+	}
+	return required
+}
+
+func number(param string) *float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// componentName strips package paths from generic type names, so Page[platform/contracts.User]
+// becomes PageOfUser and Envelope[[]platform/contracts.Plan] EnvelopeOfPlanList
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	open := strings.Index(name, "[")
+	if open < 0 {
+		return name
+	}
+	var args []string
+	for _, arg := range strings.Split(name[open+1:len(name)-1], ",") {
+		list := strings.HasPrefix(arg, "[]")
+		if strings.HasPrefix(strings.TrimPrefix(arg, "[]"), "map[") {
+			arg = "Map"
+		} else {
+			arg = arg[strings.LastIndex(arg, ".")+1:]
+		}
+		if list {
+			arg += "List"
+		}
+		args = append(args, arg)
+	}
+	return name[:open] + "Of" + strings.Join(args, "And")
+}
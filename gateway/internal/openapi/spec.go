@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"platform/contracts"
+	"platform/validation"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const bearerAuth = "bearerAuth"
+
+// Route documents one route. Path uses Fiber's syntax, :name for a path parameter.
+type Route struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Query   []Param
+	// Body and Response are values of the types sent and returned, nil when there is none
+	Body     any
+	Response any
+	// Status is the success status, 200 when zero
+	Status int
+}
+
+// Param is a query string parameter
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Group is a set of routes under one prefix sharing the middleware in front of them. Auth
+// stands for JWTMiddleware and Roles for RequireRole.
+type Group struct {
+	Prefix string
+	Auth   bool
+	Roles  []contracts.Role
+	Routes []Route
+}
+
+// Envelope is the {"data": ..., "message": ...} wrapper most services answer with
+type Envelope[T any] struct {
+	Data    T      `json:"data"`
+	Message string `json:"message,omitempty"`
+}
+
+// Message is an answer that only says what happened
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Error is what the gateway and the services send back when a request fails
+type Error struct {
+	Error string `json:"error"`
+}
+
+// ValidationError is what the services send back for a body that breaks its validate tags
+type ValidationError validation.Error
+
+// Spec collects route groups into a Document
+type Spec struct {
+	doc     Document
+	schemas *schemas
+	tags    map[string]bool
+
+	once sync.Once
+	body []byte
+	err  error
+}
+
+func New(info Info) *Spec {
+	s := &Spec{
+		doc: Document{
+			OpenAPI: "3.0.3",
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+		},
+		schemas: newSchemas(),
+		tags:    make(map[string]bool),
+	}
+	s.doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+	}
+	return s
+}
+
+// Enum lists the values a named string type can take, since reflection cannot see constants.
+// It has to be called before the groups that use the type are added.
+func (s *Spec) Enum(v any, values ...string) {
+	enum := make([]any, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	s.schemas.enums[reflect.TypeOf(v)] = enum
+}
+
+func (s *Spec) Add(groups ...Group) {
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			s.add(group, route)
+		}
+	}
+}
+
+func (s *Spec) add(group Group, route Route) {
+	path := group.Prefix + route.Path
+	op := &Operation{
+		Summary:     route.Summary,
+		OperationID: operationID(route.Method, path),
+		Responses:   make(map[string]Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+		if !s.tags[route.Tag] {
+			s.tags[route.Tag] = true
+			s.doc.Tags = append(s.doc.Tags, Tag{Name: route.Tag})
+		}
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+			segment = "{" + name + "}"
+		}
+		segments = append(segments, segment)
+	}
+	for _, param := range route.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Required:    param.Required,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(s.schemas.of(route.Body))}
+		s.fail(op, http.StatusBadRequest, "Malformed body")
+		op.Responses[strconv.Itoa(http.StatusUnprocessableEntity)] = Response{
+			Description: "Body breaks a field rule",
+			Content:     jsonContent(s.schemas.of(ValidationError{})),
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		response.Content = jsonContent(s.schemas.of(route.Response))
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	if group.Auth {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+		s.fail(op, http.StatusUnauthorized, "Missing, invalid or revoked token")
+	}
+	if len(group.Roles) > 0 {
+		for _, role := range group.Roles {
+			op.Roles = append(op.Roles, string(role))
+		}
+		op.Description = "Requires role " + strings.Join(op.Roles, " or ")
+		s.fail(op, http.StatusForbidden, "Signed in with a role that is not allowed")
+	}
+
+	key := strings.Join(segments, "/")
+	if s.doc.Paths[key] == nil {
+		s.doc.Paths[key] = make(PathItem)
+	}
+	s.doc.Paths[key][strings.ToLower(route.Method)] = op
+}
+
+func (s *Spec) fail(op *Operation, status int, description string) {
+	op.Responses[strconv.Itoa(status)] = Response{
+		Description: description,
+		Content:     jsonContent(s.schemas.of(Error{})),
+	}
+}
+
+// Operation looks a route up by its Fiber method and path, nil when it is not documented
+func (s *Spec) Operation(method, path string) *Operation {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segment = "{" + name + "}"
+		}
+		segments = append(segments, segment)
+	}
+	return s.doc.Paths[strings.Join(segments, "/")][strings.ToLower(method)]
+}
+
+// Document returns the finished description
+func (s *Spec) Document() Document {
+	doc := s.doc
+	doc.Components.Schemas = s.schemas.components
+	return doc
+}
+
+// Handler serves the document as JSON, encoded on first use
+func (s *Spec) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		s.once.Do(func() {
+			s.body, s.err = json.Marshal(s.Document())
+		})
+		if s.err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(Error{Error: "Failed to encode the API description"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(s.body)
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{fiber.MIMEApplicationJSON: {Schema: schema}}
+}
+
+// operationID turns GET /api/refunds/:id into getApiRefundsById
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == '.' }) {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			id.WriteString("By")
+			segment = name
+		}
+		id.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return id.String()
+}
@@ -0,0 +1,36 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// swaggerUIVersion pins the swagger-ui-dist release the docs page loads from the CDN
+const swaggerUIVersion = "5.17.14"
+
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>VNVoDich API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: %[2]q, dom_id: "#swagger-ui", persistAuthorization: true });
+  </script>
+</body>
+</html>
+`
+
+// UI serves a Swagger UI page reading the document at specURL
+func UI(specURL string) fiber.Handler {
+	page := fmt.Sprintf(uiPage, swaggerUIVersion, specURL)
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(page)
+	}
+}
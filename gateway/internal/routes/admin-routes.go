@@ -33,6 +33,10 @@ func AdminAssignRole(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ct
 	return ForwardRequest(req, resp, c, url, "POST", body)
 }
 
+func AdminUnlockUser(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
+	return ForwardRequest(req, resp, c, url, "PATCH", c.Body())
+}
+
 func AdminBlockJWT(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
 	return ForwardRequest(req, resp, c, url, "POST", c.Body())
 }
@@ -12,3 +12,19 @@ func LoginRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, ur
 func RegisterRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
 	return ForwardRequest(req, resp, c, url, "POST", c.Body())
 }
+
+func RefreshRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
+	return ForwardRequest(req, resp, c, url, "POST", c.Body())
+}
+
+func LogoutRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
+	return ForwardRequest(req, resp, c, url, "POST", c.Body())
+}
+
+func TwoFactorRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
+	return ForwardRequest(req, resp, c, url, "POST", c.Body())
+}
+
+func PasswordRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
+	return ForwardRequest(req, resp, c, url, "POST", c.Body())
+}
@@ -2,9 +2,10 @@ package routes
 
 import (
 	"encoding/json"
-	"fmt"
+	"gateway/internal/upstream"
 	"gateway/utils"
-	"log"
+	"log/slog"
+	"platform/client"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,17 +15,13 @@ import (
 func ForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url, method string, body []byte) error {
 	cookie := c.Request().Header.Peek("Cookie")
 	if len(cookie) > 0 {
-		fmt.Println("Detect Cookies\nOriginal Cookies:", string(cookie), "\nCopying")
 		req.Header.SetBytesK([]byte("Cookie"), string(cookie))
 	}
-	utils.BuildRequest(req, method, body, utils.API_KEY, url)
+	client.Prepare(c.UserContext(), req, method, body, utils.API_KEY, url)
 
 	// Forward request
-	if err := fasthttp.Do(req, resp); err != nil {
-		fmt.Printf("Error forwarding request: %v\n", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Service unavailable",
-		})
+	if err := upstream.Do(req, resp); err != nil {
+		return upstreamError(c, err)
 	}
 
 	// Handle redirects
@@ -43,7 +40,7 @@ func ForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx
 	// Parse JSON response
 	var responseData interface{}
 	if err := json.Unmarshal(resp.Body(), &responseData); err != nil {
-		log.Printf("Error parsing JSON response: %v\n", err)
+		slog.ErrorContext(c.UserContext(), "Upstream returned invalid JSON", "url", url, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Invalid response format",
 			"details": err.Error(),
@@ -74,21 +71,25 @@ func ForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx
 	})
 }
 
+func upstreamError(c *fiber.Ctx, err error) error {
+	slog.ErrorContext(c.UserContext(), "Forwarding request failed", "path", c.Path(), "error", err)
+	status, message := upstream.ErrorStatus(err)
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
 func CustomForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string, method string, body []byte, dataTransformer func(originalData interface{}) (interface{}, error),
 ) error {
 	cookie := c.Request().Header.Peek("Cookie")
 	if len(cookie) > 0 {
-		fmt.Println("Detect Cookies\nOriginal Cookies:", string(cookie), "\nCopying")
 		req.Header.SetBytesK([]byte("Cookie"), string(cookie))
 	}
-	utils.BuildRequest(req, method, body, utils.API_KEY, url)
+	client.Prepare(c.UserContext(), req, method, body, utils.API_KEY, url)
 
 	// Forward request
-	if err := fasthttp.Do(req, resp); err != nil {
-		fmt.Printf("Error forwarding request: %v\n", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Service unavailable",
-		})
+	if err := upstream.Do(req, resp); err != nil {
+		return upstreamError(c, err)
 	}
 
 	// Handle redirects
@@ -107,7 +108,7 @@ func CustomForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fib
 	// Parse JSON response
 	var originalData interface{}
 	if err := json.Unmarshal(resp.Body(), &originalData); err != nil {
-		fmt.Printf("Error parsing JSON response: %v\n", err)
+		slog.ErrorContext(c.UserContext(), "Upstream returned invalid JSON", "url", url, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Invalid response format",
 			"details": err.Error(),
@@ -118,7 +119,7 @@ func CustomForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fib
 	// The transformer will return exactly what should be sent to the client
 	responseData, err := dataTransformer(originalData)
 	if err != nil {
-		fmt.Printf("Error transforming data: %v\n", err)
+		slog.ErrorContext(c.UserContext(), "Transforming upstream response failed", "url", url, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Error processing response",
 			"details": err.Error(),
@@ -128,3 +129,26 @@ func CustomForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fib
 	// Return only what the transformer provided
 	return c.Status(resp.StatusCode()).JSON(responseData)
 }
+
+// RawForwardRequest relays the upstream status, content type and body as they are
+func RawForwardRequest(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url, method string, body []byte) error {
+	cookie := c.Request().Header.Peek("Cookie")
+	if len(cookie) > 0 {
+		req.Header.SetBytesK([]byte("Cookie"), string(cookie))
+	}
+	client.Prepare(c.UserContext(), req, method, body, utils.API_KEY, url)
+
+	if err := upstream.Do(req, resp); err != nil {
+		return upstreamError(c, err)
+	}
+
+	if resp.StatusCode() >= 300 && resp.StatusCode() < 400 {
+		redirectURL := string(resp.Header.Peek("Location"))
+		return c.Redirect(redirectURL, resp.StatusCode())
+	}
+
+	if contentType := resp.Header.Peek("Content-Type"); len(contentType) > 0 {
+		c.Set("Content-Type", string(contentType))
+	}
+	return c.Status(resp.StatusCode()).Send(resp.Body())
+}
@@ -9,12 +9,12 @@ func GoogleLoginRoute(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.C
 	return ForwardRequest(req, resp, c, url, "GET", c.Body())
 }
 
-func SendVerificationEmail(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
-	return ForwardRequest(req, resp, c, url, "POST", c.Body())
+func SendVerificationEmail(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string, body []byte) error {
+	return ForwardRequest(req, resp, c, url, "POST", body)
 }
 
-func VerifyEmail(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
-	return ForwardRequest(req, resp, c, url, "PUT", c.Body())
+func VerifyEmail(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string, body []byte) error {
+	return ForwardRequest(req, resp, c, url, "POST", body)
 }
 
 func CreateMeetLink(req *fasthttp.Request, resp *fasthttp.Response, c *fiber.Ctx, url string) error {
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"gateway/internal/openapi"
+	"net/http"
+	"platform/contracts"
+)
+
+// apiSpec describes every route setupRoutes registers, grouped the way the Fiber groups are.
+// A route added there without an entry here fails TestSpecCoversRoutes. Manifest routes are
+// documented from manifestDocs.
+func apiSpec() *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:       "VNVoDich API",
+		Description: "Public API of the gateway. Routes only served from the route manifest are listed when documented in the gateway.",
+		Version:     "1.0.0",
+	})
+	spec.Enum(contracts.Role(""), string(contracts.RoleParent), string(contracts.RoleChildren), string(contracts.RoleTutor), string(contracts.RoleAdmin))
+	spec.Enum(contracts.Status(""), string(contracts.StatusActive), string(contracts.StatusSuspended), string(contracts.StatusBanned), string(contracts.StatusDeleted))
+	spec.Enum(contracts.BillingCycle(""), string(contracts.BillingMonthly), string(contracts.BillingAnnually))
+	spec.Enum(contracts.SubscriptionStatus(""),
+		string(contracts.SubscriptionActive), string(contracts.SubscriptionCanceled), string(contracts.SubscriptionPastDue),
+		string(contracts.SubscriptionTrialing), string(contracts.SubscriptionIncomplete))
+	spec.Enum(contracts.RefundStatus(""), string(contracts.RefundStatusPending), string(contracts.RefundStatusApproved), string(contracts.RefundStatusRejected))
+
+	page := []openapi.Param{{Name: "page", Description: "1 based page number"}, {Name: "limit", Description: "Page size"}}
+
+	spec.Add(
+		openapi.Group{Routes: []openapi.Route{
+			{Method: http.MethodGet, Path: "/health", Tag: "System", Summary: "Gateway status and upstream circuit breakers"},
+			{Method: http.MethodGet, Path: "/livez", Tag: "System", Summary: "Liveness probe"},
+			{Method: http.MethodGet, Path: "/readyz", Tag: "System", Summary: "Readiness probe"},
+			{Method: http.MethodGet, Path: "/openapi.json", Tag: "System", Summary: "This document"},
+			{Method: http.MethodGet, Path: "/docs", Tag: "System", Summary: "Swagger UI for this document"},
+
+			{Method: http.MethodPost, Path: "/auth/login", Tag: "Auth", Summary: "Sign in with a password", Body: contracts.LoginRequest{}, Response: contracts.Session{}},
+			{Method: http.MethodPost, Path: "/auth/register", Tag: "Auth", Summary: "Create an account", Body: contracts.RegisterRequest{}, Response: openapi.Message{}, Status: http.StatusCreated},
+			{Method: http.MethodPost, Path: "/auth/refresh", Tag: "Auth", Summary: "Trade a refresh token for a new session", Body: contracts.RefreshRequest{}, Response: contracts.Session{}},
+			{Method: http.MethodPost, Path: "/auth/password/forgot", Tag: "Auth", Summary: "Email a password reset link", Body: contracts.ForgotPasswordRequest{}, Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/auth/password/reset", Tag: "Auth", Summary: "Set a new password with a reset token", Body: contracts.ResetPasswordRequest{}, Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/auth/login/2fa", Tag: "Auth", Summary: "Finish a login with a second factor", Body: contracts.TwoFactorLoginRequest{}, Response: contracts.Session{}},
+			{Method: http.MethodPost, Path: "/auth/2fa/enroll", Tag: "Auth", Summary: "Start the enrollment a login was stopped for", Body: contracts.TwoFactorEnrollRequest{}},
+			{Method: http.MethodPost, Path: "/auth/2fa/activate", Tag: "Auth", Summary: "Finish the enrollment a login was stopped for", Body: contracts.TwoFactorLoginRequest{}, Response: contracts.Session{}},
+			{Method: http.MethodGet, Path: "/google/auth/login", Tag: "Auth", Summary: "Redirect to Google sign in", Status: http.StatusTemporaryRedirect},
+			{Method: http.MethodGet, Path: "/google/auth/login/callback", Tag: "Auth", Summary: "Google sign in callback", Status: http.StatusTemporaryRedirect,
+				Query: []openapi.Param{{Name: "state", Required: true}, {Name: "code", Required: true}}},
+
+			{Method: http.MethodGet, Path: "/public/user/:username", Tag: "Users", Summary: "Public profile of a user", Response: contracts.User{}},
+			{Method: http.MethodGet, Path: "/payment/success", Tag: "Payments", Summary: "PayPal return URL", Status: http.StatusTemporaryRedirect,
+				Query: []openapi.Param{{Name: "paymentId", Required: true}, {Name: "PayerID", Required: true}, {Name: "orderId", Required: true}}},
+			{Method: http.MethodGet, Path: "/payment/cancel", Tag: "Payments", Summary: "PayPal cancel URL", Status: http.StatusTemporaryRedirect,
+				Query: []openapi.Param{{Name: "orderId", Required: true}}},
+
+			{Method: http.MethodGet, Path: "/subscription/plans", Tag: "Plans", Summary: "List plans", Response: openapi.Envelope[[]contracts.Plan]{},
+				Query: []openapi.Param{{Name: "active_only", Description: "false to include retired plans"}}},
+			{Method: http.MethodGet, Path: "/subscription/plans/:id", Tag: "Plans", Summary: "Get a plan", Response: openapi.Envelope[contracts.Plan]{}},
+		}},
+
+		openapi.Group{Prefix: "/api", Auth: true, Routes: []openapi.Route{
+			{Method: http.MethodPost, Path: "/auth/logout", Tag: "Auth", Summary: "Revoke the current token", Body: contracts.LogoutRequest{}, Response: openapi.Message{}},
+			{Method: http.MethodGet, Path: "/get/me", Tag: "Users", Summary: "The signed in user", Response: contracts.User{}},
+			{Method: http.MethodPut, Path: "/update/me", Tag: "Users", Summary: "Update the signed in user", Body: contracts.UserUpdate{}, Response: openapi.Message{}},
+			{Method: http.MethodPatch, Path: "/update/me/password", Tag: "Users", Summary: "Change the signed in user's password", Response: openapi.Message{},
+				Query: []openapi.Param{{Name: "cur_password", Required: true}, {Name: "new_password", Required: true}}},
+			{Method: http.MethodDelete, Path: "/delete/me", Tag: "Users", Summary: "Schedule the signed in user's account for deletion", Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/delete/me/cancel", Tag: "Users", Summary: "Cancel a scheduled deletion", Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/verify-email/send", Tag: "Users", Summary: "Email a verification code", Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/verify-email/verify", Tag: "Users", Summary: "Verify the email address with a code", Response: openapi.Message{},
+				Query: []openapi.Param{{Name: "code", Description: "May be sent as {\"code\": ...} in the body instead"}}},
+			{Method: http.MethodPost, Path: "/payment/create", Tag: "Payments", Summary: "Create a PayPal payment",
+				Query: []openapi.Param{{Name: "amount", Required: true}, {Name: "description"}, {Name: "orderId", Required: true}}},
+
+			{Method: http.MethodGet, Path: "/subscription/tutor/:tutorId", Tag: "Subscriptions", Summary: "A tutor's current subscription", Response: openapi.Envelope[contracts.Subscription]{}},
+			{Method: http.MethodPost, Path: "/subscription", Tag: "Subscriptions", Summary: "Start a subscription, pending payment", Body: contracts.SubscriptionRequest{}, Response: openapi.Envelope[contracts.Subscription]{}, Status: http.StatusCreated},
+			{Method: http.MethodPost, Path: "/subscription/confirm", Tag: "Subscriptions", Summary: "Confirm the payment for a subscription", Body: contracts.PaymentConfirmationRequest{}, Response: openapi.Envelope[contracts.Subscription]{}},
+			{Method: http.MethodPut, Path: "/subscription/:id/cancel", Tag: "Subscriptions", Summary: "Cancel a subscription", Response: openapi.Message{}},
+			{Method: http.MethodPut, Path: "/subscription/:id/change-plan", Tag: "Subscriptions", Summary: "Move a subscription to another plan at a prorated price, pending payment when something is due",
+				Body: contracts.ChangePlanRequest{}, Response: openapi.Envelope[contracts.Subscription]{}},
+			{Method: http.MethodPost, Path: "/subscription/:id/change-plan/preview", Tag: "Subscriptions", Summary: "Quote a plan change without starting it", Body: contracts.ChangePlanRequest{}, Response: openapi.Envelope[contracts.PlanChangeQuote]{}},
+
+			{Method: http.MethodPost, Path: "/refunds", Tag: "Refunds", Summary: "Ask for a refund", Body: contracts.RefundRequestInput{}, Response: openapi.Envelope[contracts.Refund]{}, Status: http.StatusCreated},
+			{Method: http.MethodGet, Path: "/refunds/:id", Tag: "Refunds", Summary: "Get a refund request", Response: contracts.Refund{}},
+			{Method: http.MethodGet, Path: "/p-refunds", Tag: "Refunds", Summary: "List refund requests", Response: contracts.Page[contracts.Refund]{},
+				Query: append(page, openapi.Param{Name: "status"})},
+		}},
+
+		openapi.Group{Prefix: "/api/2fa", Auth: true, Roles: []contracts.Role{contracts.RoleAdmin, contracts.RoleTutor}, Routes: []openapi.Route{
+			{Method: http.MethodPost, Path: "/enroll", Tag: "Auth", Summary: "Start two factor enrollment"},
+			{Method: http.MethodPost, Path: "/activate", Tag: "Auth", Summary: "Turn two factor authentication on", Body: contracts.TwoFactorLoginRequest{}, Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/disable", Tag: "Auth", Summary: "Turn two factor authentication off", Body: contracts.TwoFactorLoginRequest{}, Response: openapi.Message{}},
+		}},
+
+		openapi.Group{Prefix: "/api/tutor", Auth: true, Roles: []contracts.Role{contracts.RoleTutor}, Routes: []openapi.Route{
+			{Method: http.MethodGet, Path: "/meet", Tag: "Tutors", Summary: "Create a Google Meet link", Query: []openapi.Param{{Name: "title"}}},
+		}},
+
+		openapi.Group{Prefix: "/api/admin", Auth: true, Roles: []contracts.Role{contracts.RoleAdmin}, Routes: []openapi.Route{
+			{Method: http.MethodGet, Path: "/users", Tag: "Admin", Summary: "List users", Response: contracts.Page[contracts.User]{}, Query: page},
+			{Method: http.MethodPut, Path: "/user/update", Tag: "Admin", Summary: "Update a user", Body: contracts.UserUpdate{}, Response: openapi.Message{},
+				Query: []openapi.Param{{Name: "id"}, {Name: "username", Required: true}}},
+			{Method: http.MethodGet, Path: "/user", Tag: "Admin", Summary: "Get a user", Response: contracts.User{}, Query: []openapi.Param{{Name: "username", Required: true}}},
+			{Method: http.MethodPatch, Path: "/users/:username/status", Tag: "Admin", Summary: "Change a user's status", Response: contracts.User{}, Query: []openapi.Param{{Name: "status", Required: true}}},
+			{Method: http.MethodPatch, Path: "/users/:username/unlock", Tag: "Admin", Summary: "Unlock a locked account", Response: contracts.User{}},
+			{Method: http.MethodDelete, Path: "/users/:id", Tag: "Admin", Summary: "Delete a user", Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/users/:username/roles", Tag: "Admin", Summary: "Assign a role", Response: openapi.Message{}, Query: []openapi.Param{{Name: "role", Required: true}}},
+
+			{Method: http.MethodGet, Path: "/subscriptions", Tag: "Admin", Summary: "List subscriptions", Response: contracts.Page[contracts.Subscription]{},
+				Query: append(page, openapi.Param{Name: "status"}, openapi.Param{Name: "tutor_id"}, openapi.Param{Name: "plan_id"})},
+			{Method: http.MethodPut, Path: "/subscription/:id/status", Tag: "Admin", Summary: "Override a subscription's status", Body: contracts.SubscriptionStatusUpdateRequest{}, Response: openapi.Message{}},
+			{Method: http.MethodGet, Path: "/subscription/:id/events", Tag: "Admin", Summary: "A subscription's history, including every dunning step", Response: openapi.Envelope[[]contracts.SubscriptionEvent]{}},
+			{Method: http.MethodPost, Path: "/subscription/plans", Tag: "Admin", Summary: "Create a plan", Body: contracts.PlanRequest{}, Response: openapi.Envelope[contracts.Plan]{}, Status: http.StatusCreated},
+			{Method: http.MethodPut, Path: "/subscription/plans/:id", Tag: "Admin", Summary: "Replace a plan", Body: contracts.PlanRequest{}, Response: openapi.Envelope[contracts.Plan]{}},
+			{Method: http.MethodDelete, Path: "/subscription/plans/:id", Tag: "Admin", Summary: "Delete a plan", Response: openapi.Message{}},
+			{Method: http.MethodPost, Path: "/jwt/block", Tag: "Admin", Summary: "Revoke a user's tokens", Response: openapi.Message{},
+				Query: []openapi.Param{{Name: "username", Required: true}, {Name: "time", Description: "How long the block lasts"}}},
+			{Method: http.MethodPost, Path: "/jwt/unblock", Tag: "Admin", Summary: "Lift a token block", Response: openapi.Message{}, Query: []openapi.Param{{Name: "username", Required: true}}},
+			{Method: http.MethodGet, Path: "/system/status", Tag: "Admin", Summary: "Health of every service"},
+
+			{Method: http.MethodGet, Path: "/refunds", Tag: "Admin", Summary: "List refund requests", Response: contracts.Page[contracts.Refund]{},
+				Query: append(page, openapi.Param{Name: "status"})},
+			{Method: http.MethodGet, Path: "/refunds/statistics", Tag: "Admin", Summary: "Refund counts and totals", Response: openapi.Envelope[map[string]any]{}},
+			{Method: http.MethodPut, Path: "/refunds/:id/process", Tag: "Admin", Summary: "Approve or reject a refund request", Body: contracts.RefundProcessInput{}, Response: openapi.Message{}},
+		}},
+	)
+	spec.Add(manifestDocs...)
+	return spec
+}
+
+// manifestDocs describes routes served from routes.json. The manifest says where a route goes
+// but not what it takes or returns, so those live here; TestSpecCoversManifestRoutes fails
+// when an entry no longer matches a manifest route with the same roles.
+var manifestDocs = []openapi.Group{
+	{Prefix: "/api/admin", Auth: true, Roles: []contracts.Role{contracts.RoleAdmin}, Routes: []openapi.Route{
+		{Method: http.MethodGet, Path: "/subscription/coupons", Tag: "Admin", Summary: "List coupons", Response: openapi.Envelope[[]contracts.Coupon]{},
+			Query: []openapi.Param{{Name: "active_only", Description: "false to include deactivated coupons"}}},
+		{Method: http.MethodGet, Path: "/subscription/coupons/:id", Tag: "Admin", Summary: "Get a coupon", Response: openapi.Envelope[contracts.Coupon]{}},
+		{Method: http.MethodPost, Path: "/subscription/coupons", Tag: "Admin", Summary: "Create a coupon", Body: contracts.CouponRequest{}, Response: openapi.Envelope[contracts.Coupon]{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/subscription/coupons/:id", Tag: "Admin", Summary: "Replace a coupon", Body: contracts.CouponRequest{}, Response: openapi.Envelope[contracts.Coupon]{}},
+		{Method: http.MethodDelete, Path: "/subscription/coupons/:id", Tag: "Admin", Summary: "Deactivate a coupon", Response: openapi.Message{}},
+	}},
+}
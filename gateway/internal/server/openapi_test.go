@@ -0,0 +1,118 @@
+package server
+
+import (
+	"gateway/internal/config"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestGateway(t *testing.T) *Gateway {
+	t.Helper()
+	t.Setenv("ROUTE_MANIFEST_PATH", "../../routes.json")
+	t.Setenv("ROUTE_MANIFEST_RELOAD", "0")
+	t.Setenv("RATE_LIMIT_BACKEND", "memory")
+	// The manifest refuses upstreams without a URL, nothing is dialled while routes are listed
+	for _, name := range []string{"AUTH", "NODE", "GOOGLE", "USER", "ADMIN", "PAYMENT", "SUBSCRIPTION"} {
+		t.Setenv(name+"_SERVICE_URL", "http://"+strings.ToLower(name)+".test")
+	}
+
+	g := NewGateway(config.New())
+	t.Cleanup(g.routes.Stop)
+	return g
+}
+
+// registered lists the routes setupRoutes adds, leaving out the HEAD twin Fiber adds to
+// every GET and the manifest catch-all
+func registered(g *Gateway) []fiber.Route {
+	var routes []fiber.Route
+	for _, route := range g.app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Path == "/*" {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	g := newTestGateway(t)
+
+	documented := 0
+	for _, route := range registered(g) {
+		op := g.spec.Operation(route.Method, route.Path)
+		if op == nil {
+			t.Errorf("%s %s is registered but missing from the OpenAPI spec", route.Method, route.Path)
+			continue
+		}
+		documented++
+
+		protected := strings.HasPrefix(route.Path, "/api/")
+		if protected != (len(op.Security) > 0) {
+			t.Errorf("%s %s: spec says auth required is %v, route sits behind JWTMiddleware: %v",
+				route.Method, route.Path, len(op.Security) > 0, protected)
+		}
+		if strings.HasPrefix(route.Path, "/api/admin/") && (len(op.Roles) != 1 || op.Roles[0] != "Admin") {
+			t.Errorf("%s %s: spec roles %v, want [Admin]", route.Method, route.Path, op.Roles)
+		}
+	}
+
+	for _, group := range manifestDocs {
+		documented += len(group.Routes)
+	}
+	total := 0
+	for _, item := range g.spec.Document().Paths {
+		total += len(item)
+	}
+	if total != documented {
+		t.Errorf("spec documents %d operations but only %d are registered or in manifestDocs", total, documented)
+	}
+}
+
+func TestSpecCoversManifestRoutes(t *testing.T) {
+	g := newTestGateway(t)
+
+	for _, group := range manifestDocs {
+		for _, doc := range group.Routes {
+			path := group.Prefix + doc.Path
+			if g.spec.Operation(doc.Method, path) == nil {
+				t.Errorf("%s %s is in manifestDocs but missing from the OpenAPI spec", doc.Method, path)
+			}
+
+			// Any value stands in for a path param, the table only needs a segment there
+			var segments []string
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, ":") {
+					segment = "1"
+				}
+				segments = append(segments, segment)
+			}
+			route, _, _ := g.routes.Table().Match(doc.Method, strings.Join(segments, "/"))
+			if route == nil {
+				t.Errorf("%s %s is documented but not in the route manifest", doc.Method, path)
+				continue
+			}
+			if route.Auth != group.Auth || !slices.Equal(route.Roles, group.Roles) {
+				t.Errorf("%s %s: manifest auth %v roles %v, documented as %v %v",
+					doc.Method, path, route.Auth, route.Roles, group.Auth, group.Roles)
+			}
+		}
+	}
+}
+
+func TestSpecIsServed(t *testing.T) {
+	g := newTestGateway(t)
+
+	for _, path := range []string{"/openapi.json", "/docs"} {
+		resp, err := g.app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("GET %s answered %d", path, resp.StatusCode)
+		}
+	}
+}
@@ -1,26 +1,47 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"gateway/internal/config"
 	"gateway/internal/handlers"
+	"gateway/internal/manifest"
 	"gateway/internal/middleware"
+	"gateway/internal/openapi"
+	"gateway/internal/upstream"
+	"gateway/utils"
+	"io"
+	"log/slog"
+	"platform/client"
+	"platform/contracts"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/shutdown"
+	"platform/tracing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 type Gateway struct {
 	config       *config.Config
 	app          *fiber.App
+	metrics      *fiber.App
 	auth         *handlers.AuthHandler
 	google       *handlers.GoogleHandler
 	user         *handlers.UserServiceHandler
-	node         *handlers.NodeServiceHandler
 	admin        *handlers.AdminServiceHandler
 	payment      *handlers.PaymentHandler
 	subscription *handlers.SubscriptionHandler
 	refund       *handlers.RefundHandler
+	manifest     *handlers.ManifestHandler
+	routes       *manifest.Loader
+	system       *handlers.SystemHandler
+	revocation   middleware.RevocationChecker
+	jwks         *middleware.JWKSCache
+	rateLimits   middleware.RateLimitStore
+	spec         *openapi.Spec
 }
 
 func NewGateway(config *config.Config) *Gateway {
@@ -30,49 +51,122 @@ func NewGateway(config *config.Config) *Gateway {
 	})
 
 	// Middleware
+	app.Use(tracing.Middleware("gateway"), logging.Middleware(), metrics.Middleware())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET, POST, PUT, DELETE, PATCH",
 		AllowHeaders: "*",
+		// Lets the frontend quote the request id when reporting a problem
+		ExposeHeaders: tracing.RequestIDHeader,
 	}))
 
-	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path}\n",
-	}))
+	rateLimits, err := middleware.NewRateLimitStore(config.RateLimit)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up rate limiting: %v", err))
+	}
+
+	upstream.Configure(config.Upstream, config.Upstreams())
+
+	routeManifest, err := manifest.NewLoader(config.RouteManifest.Path, manifestOptions(config))
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load route manifest: %v", err))
+	}
+	routeManifest.Watch(config.RouteManifest.ReloadInterval)
+
+	revocation := middleware.NewRevocationChecker(client.NewAuthClient(config.AuthServiceURL, utils.API_KEY), config.RevocationCacheTTL)
+	jwks := middleware.NewJWKSCache(config.JWKSURL, config.JWKSCacheTTL)
 
 	gateway := &Gateway{
 		config:       config,
 		app:          app,
+		metrics:      metricsApp(),
 		auth:         handlers.NewAuthHandler(config.AuthServiceURL),
 		google:       handlers.NewGoogleHandler(config),
 		user:         handlers.NewUserService(config.UserServiceURL),
-		node:         handlers.NewNodeServiceHandler(config.NodeServiceURL),
 		admin:        handlers.NewAdminService(config),
 		payment:      handlers.NewPaymentHandler(config.PaymentServiceURL),
 		subscription: handlers.NewSubscriptionHandler(config.SubscriptionURL),
 		refund:       handlers.NewRefundHandler(config.AdminServiceURL),
+		manifest:     handlers.NewManifestHandler(routeManifest, jwks, revocation, rateLimits, config.RateLimit.Policies),
+		routes:       routeManifest,
+		revocation:   revocation,
+		jwks:         jwks,
+		rateLimits:   rateLimits,
+		spec:         apiSpec(),
 	}
+	gateway.system = handlers.NewSystemHandler(config, gateway.readinessChecks())
 
 	gateway.setupRoutes()
 	return gateway
 }
 
+// metricsApp serves /metrics on its own port so the public listener does not expose it
+func metricsApp() *fiber.App {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/metrics", metrics.Handler())
+	return app
+}
+
+// manifestOptions names the upstreams and rate limit policies a manifest route may use
+func manifestOptions(config *config.Config) manifest.Options {
+	policies := make(map[string]bool, len(config.RateLimit.Policies))
+	for name := range config.RateLimit.Policies {
+		policies[name] = true
+	}
+	return manifest.Options{
+		Upstreams:  config.Upstreams(),
+		RateLimits: policies,
+	}
+}
+
+// limit applies the named rate limit policy from the config
+func (g *Gateway) limit(policy string) fiber.Handler {
+	return middleware.RateLimit(g.rateLimits, g.config.RateLimit.Policies[policy])
+}
+
+// readinessChecks covers what every authenticated request needs: cached signing keys
+// from auth-service and, when rate limits are kept there, Redis
+func (g *Gateway) readinessChecks() []health.Check {
+	checks := []health.Check{{Name: "jwks", Run: g.jwks.Ready}}
+	if pinger, ok := g.rateLimits.(interface{ Ping(context.Context) error }); ok {
+		checks = append(checks, health.Check{Name: "redis", Run: pinger.Ping})
+	}
+	return checks
+}
+
 func (g *Gateway) setupRoutes() {
 	// Public routes
 	g.app.Get("/health", func(c *fiber.Ctx) error {
+		upstreams := upstream.Status()
+		status := "ok"
+		for _, breaker := range upstreams {
+			if breaker.State != upstream.StateClosed {
+				status = "degraded"
+			}
+		}
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"status":  "ok",
-			"service": "gateway",
+			"status":    status,
+			"service":   "gateway",
+			"upstreams": upstreams,
 		})
 	})
-	g.app.Post("/auth/login", g.auth.HandleLogin())
-	g.app.Post("/auth/register", g.auth.HandleRegister())
+	g.app.Get("/livez", health.Livez("gateway"))
+	g.app.Get("/readyz", health.Readyz("gateway", g.readinessChecks()...))
+	g.app.Get("/openapi.json", g.spec.Handler())
+	g.app.Get("/docs", openapi.UI("/openapi.json"))
+	g.app.Post("/auth/login", g.limit("auth"), g.auth.HandleLogin())
+	g.app.Post("/auth/register", g.limit("auth"), g.auth.HandleRegister())
+	g.app.Post("/auth/refresh", g.limit("auth"), g.auth.HandleRefresh())
+	g.app.Post("/auth/password/forgot", g.limit("email"), g.auth.HandleForgotPassword())
+	g.app.Post("/auth/password/reset", g.limit("auth"), g.auth.HandleResetPassword())
+	g.app.Post("/auth/login/2fa", g.limit("auth"), g.auth.HandleLoginTwoFactor())
+	g.app.Post("/auth/2fa/enroll", g.limit("auth"), g.auth.HandleTwoFactorChallenge("enroll"))
+	g.app.Post("/auth/2fa/activate", g.limit("auth"), g.auth.HandleTwoFactorChallenge("activate"))
 	g.app.Get("/google/auth/login", g.google.HandleLogin())
 	g.app.Get("/google/auth/login/callback", g.google.HandleCallback())
 
-	g.app.Get("/public/user/:username", g.user.HandleGetPublicUserProfile())
-	g.app.Get("/public/course/all", g.node.HandleGetAllCourse())
-	g.app.Get("/public/course/:id", g.node.HandleGetACourse())
+	public := g.app.Group("/public", g.limit("public"))
+	public.Get("/user/:username", g.user.HandleGetPublicUserProfile())
 	g.app.Get("/payment/success", g.payment.HandleCompletePayPalPayment())
 	g.app.Get("/payment/cancel", g.payment.HandleCancelPayPalPayment())
 
@@ -81,13 +175,14 @@ func (g *Gateway) setupRoutes() {
 
 	// Protected routes
 	api := g.app.Group("/api")
-	api.Use(middleware.JWTMiddleware(g.config.JWTSecret))
+	api.Use(middleware.JWTMiddleware(g.jwks, g.revocation), g.limit("api"))
+	api.Post("/auth/logout", g.auth.HandleLogout())
 	api.Get("/get/me", g.user.HandleGetMe())
 	api.Put("/update/me", g.user.HandleUpdateMe())
 	api.Patch("/update/me/password", g.user.HandleUpdateMePassword())
 	api.Delete("/delete/me", g.user.HandleDeleteMe())
 	api.Post("/delete/me/cancel", g.user.HandleCancelDeleteMe())
-	api.Post("verify-email/send", g.google.HandleSendVerificationEmail())
+	api.Post("verify-email/send", g.limit("email"), g.google.HandleSendVerificationEmail())
 	api.Post("verify-email/verify", g.google.HandleVerifyEmail())
 	api.Post("/payment/create", g.payment.HandleCreatePayment())
 
@@ -96,31 +191,40 @@ func (g *Gateway) setupRoutes() {
 	api.Post("/subscription/confirm", g.subscription.HandleConfirmSubscription())
 	api.Put("/subscription/:id/cancel", g.subscription.HandleCancelSubscription())
 	api.Put("/subscription/:id/change-plan", g.subscription.HandleChangePlan())
+	api.Post("/subscription/:id/change-plan/preview", g.subscription.HandlePreviewPlanChange())
 
 	api.Post("/refunds", g.refund.HandleCreateRefundRequest())
 	api.Get("/refunds/:id", g.refund.HandleGetRefundRequest())
 	api.Get("/p-refunds", g.refund.HandleGetAllRefundRequests())
 
+	two_factor_api := api.Group("/2fa").Use(middleware.RequireRole(contracts.RoleAdmin, contracts.RoleTutor))
+	two_factor_api.Post("/enroll", g.auth.HandleTwoFactor("enroll"))
+	two_factor_api.Post("/activate", g.auth.HandleTwoFactor("activate"))
+	two_factor_api.Post("/disable", g.auth.HandleTwoFactor("disable"))
+
 	// Tutor routes
-	tutor_api := api.Group("/tutor").Use(middleware.RequireRole("Tutor"))
+	tutor_api := api.Group("/tutor").Use(middleware.RequireRole(contracts.RoleTutor))
 	tutor_api.Get("/meet", g.google.HandleCreateMeetLink())
 	//// Admin routes
 	admin_api := api.Group("/admin")
-	admin_api.Use(middleware.RequireRole("Admin"))
+	admin_api.Use(middleware.RequireRole(contracts.RoleAdmin))
 	admin_api.Get("/users", g.user.HandleAllGetUser())
 	admin_api.Put("/user/update", g.admin.HandleAdminUpdateUser())
 	admin_api.Get("/user", g.admin.HandleAdminGetUSerDetail())
 	admin_api.Patch("/users/:username/status", g.admin.HandleUpdateUserStatus())
+	admin_api.Patch("/users/:username/unlock", g.admin.HandleUnlockUser())
 	admin_api.Delete("/users/:id", g.admin.HandleDeleteUser())
 	admin_api.Post("/users/:username/roles", g.admin.HandleAssignRole())
 
 	admin_api.Get("/subscriptions", g.subscription.HandleGetAllSubscriptions())
 	admin_api.Put("/subscription/:id/status", g.subscription.HandleUpdateSubscriptionStatus())
+	admin_api.Get("/subscription/:id/events", g.subscription.HandleGetSubscriptionEvents())
 	admin_api.Post("/subscription/plans", g.subscription.HandleAdminCreatePlan())
 	admin_api.Put("/subscription/plans/:id", g.subscription.HandleAdminUpdatePlan())
 	admin_api.Delete("/subscription/plans/:id", g.subscription.HandleAdminDeletePlan())
 	admin_api.Post("/jwt/block", g.admin.HandleAdminBlockJWT())
 	admin_api.Post("/jwt/unblock", g.admin.HandleAdminUnBlockJWT())
+	admin_api.Get("/system/status", g.system.HandleSystemStatus())
 
 	// Admin refund routes
 	admin_api.Get("/refunds", g.refund.HandleGetAllRefundRequests())
@@ -129,8 +233,32 @@ func (g *Gateway) setupRoutes() {
 
 	//// Specific role-based routes
 	//api.Get("/sensitive-data", middleware.RequireRole("admin", "data_analyst"), g.auth.HandleSensitiveData())
+
+	// Everything else is looked up in the route manifest, this has to stay last
+	g.app.All("/*", g.manifest.HandleManifestRoute())
 }
 
-func (g *Gateway) Start(addr string) error {
-	return g.app.Listen(addr)
+// Start serves until the process is told to stop, then drains requests and runs the
+// gateway's own cleanup followed by steps. Metrics are served on metricsAddr until the
+// public listener has drained.
+func (g *Gateway) Start(addr, metricsAddr string, steps ...shutdown.Step) error {
+	go func() {
+		if err := g.metrics.Listen(metricsAddr); err != nil {
+			slog.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
+	cleanup := []shutdown.Step{
+		{Name: "metrics server", Run: g.metrics.ShutdownWithContext},
+		shutdown.Func("route manifest watcher", g.routes.Stop),
+	}
+	if closer, ok := g.rateLimits.(io.Closer); ok {
+		cleanup = append(cleanup, shutdown.Step{
+			Name: "redis",
+			Run: func(context.Context) error {
+				return closer.Close()
+			},
+		})
+	}
+	return shutdown.Serve(g.app, addr, append(cleanup, steps...)...)
 }
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"platform/contracts"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestManifestServesCouponAdminRoutes(t *testing.T) {
+	g := newTestGateway(t)
+
+	for _, tc := range []struct {
+		method, path string
+		upstream     string
+	}{
+		{fiber.MethodGet, "/api/admin/subscription/coupons", "http://subscription.test/api/admin/coupons"},
+		{fiber.MethodPost, "/api/admin/subscription/coupons", "http://subscription.test/api/admin/coupons"},
+		{fiber.MethodGet, "/api/admin/subscription/coupons/3", "http://subscription.test/api/admin/coupons/3"},
+		{fiber.MethodPut, "/api/admin/subscription/coupons/3", "http://subscription.test/api/admin/coupons/3"},
+		{fiber.MethodDelete, "/api/admin/subscription/coupons/3", "http://subscription.test/api/admin/coupons/3"},
+	} {
+		route, params, _ := g.routes.Table().Match(tc.method, tc.path)
+		if route == nil {
+			t.Errorf("%s %s is not in the route manifest", tc.method, tc.path)
+			continue
+		}
+		if !route.Auth || !slices.Equal(route.Roles, []contracts.Role{contracts.RoleAdmin}) {
+			t.Errorf("%s %s: auth %v roles %v, want admins only", tc.method, tc.path, route.Auth, route.Roles)
+		}
+		if got := route.UpstreamURL(params, nil); got != tc.upstream {
+			t.Errorf("%s %s goes to %s, want %s", tc.method, tc.path, got, tc.upstream)
+		}
+	}
+}
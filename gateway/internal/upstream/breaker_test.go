@@ -0,0 +1,95 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+)
+
+const cooldown = 20 * time.Millisecond
+
+// openBreaker returns a breaker that has just been opened by threshold failures
+func openBreaker(t *testing.T, threshold int) *Breaker {
+	t.Helper()
+	b := NewBreaker(threshold, cooldown)
+	for range threshold {
+		b.Failure()
+	}
+	if state := b.Status().State; state != StateOpen {
+		t.Fatalf("state after %d failures is %s, want %s", threshold, state, StateOpen)
+	}
+	return b
+}
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	b := NewBreaker(3, time.Minute)
+	for i := range 2 {
+		b.Failure()
+		if !b.Allow() {
+			t.Fatalf("call refused after %d failures, threshold is 3", i+1)
+		}
+	}
+	b.Failure()
+	if b.Allow() {
+		t.Error("call allowed after 3 failures, want the breaker open")
+	}
+	if status := b.Status(); status.State != StateOpen || status.Failures != 3 || status.OpenedAt == nil {
+		t.Errorf("status %+v, want open with 3 failures and an opened_at", status)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewBreaker(3, time.Minute)
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	if !b.Allow() {
+		t.Error("call refused, failures before a success should not count")
+	}
+}
+
+func TestBreakerLetsOneProbeThroughAfterCooldown(t *testing.T) {
+	b := openBreaker(t, 2)
+	if b.Allow() {
+		t.Fatal("call allowed during the cooldown")
+	}
+
+	time.Sleep(cooldown)
+	if !b.Allow() {
+		t.Fatal("probe refused after the cooldown")
+	}
+	if state := b.Status().State; state != StateHalfOpen {
+		t.Errorf("state while probing is %s, want %s", state, StateHalfOpen)
+	}
+	for range 3 {
+		if b.Allow() {
+			t.Fatal("a second call was let through while the probe is in flight")
+		}
+	}
+
+	b.Success()
+	if state := b.Status().State; state != StateClosed || !b.Allow() {
+		t.Errorf("state after a good probe is %s, want %s and calls allowed", state, StateClosed)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b := openBreaker(t, 2)
+	time.Sleep(cooldown)
+	if !b.Allow() {
+		t.Fatal("probe refused after the cooldown")
+	}
+
+	b.Failure()
+	if state := b.Status().State; state != StateOpen {
+		t.Fatalf("state after a failed probe is %s, want %s", state, StateOpen)
+	}
+	if b.Allow() {
+		t.Error("call allowed right after a failed probe, want a new cooldown")
+	}
+	time.Sleep(cooldown)
+	if !b.Allow() {
+		t.Error("probe refused after the second cooldown")
+	}
+}
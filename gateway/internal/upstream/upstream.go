@@ -0,0 +1,189 @@
+package upstream
+
+import (
+	"errors"
+	"gateway/internal/config"
+	"gateway/internal/metrics"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// ErrCircuitOpen is returned without calling an upstream whose breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type upstream struct {
+	name    string
+	client  *fasthttp.Client
+	breaker *Breaker
+}
+
+// Pool keeps one client and one breaker per upstream, found by the host of the request URL
+type Pool struct {
+	mu      sync.Mutex
+	cfg     config.UpstreamConfig
+	byHost  map[string]*upstream
+	ordered []*upstream
+}
+
+func NewPool(cfg config.UpstreamConfig, upstreams map[string]string) *Pool {
+	p := &Pool{
+		cfg:    cfg,
+		byHost: make(map[string]*upstream),
+	}
+	for name, baseURL := range upstreams {
+		parsed, err := url.Parse(baseURL)
+		if err != nil || parsed.Host == "" || p.byHost[parsed.Host] != nil {
+			continue
+		}
+		clientCfg, ok := cfg.Clients[name]
+		if !ok {
+			clientCfg = cfg.Default
+		}
+		p.add(parsed.Host, name, clientCfg)
+	}
+	return p
+}
+
+func (p *Pool) add(host, name string, cfg config.UpstreamClientConfig) *upstream {
+	u := &upstream{
+		name: name,
+		client: &fasthttp.Client{
+			Dial: func(addr string) (net.Conn, error) {
+				return fasthttp.DialTimeout(addr, cfg.DialTimeout)
+			},
+			ReadTimeout:         cfg.ReadTimeout,
+			WriteTimeout:        cfg.WriteTimeout,
+			MaxConnsPerHost:     cfg.MaxConns,
+			MaxConnWaitTimeout:  cfg.DialTimeout,
+			MaxIdleConnDuration: 30 * time.Second,
+		},
+		breaker: NewBreaker(p.cfg.BreakerFailures, p.cfg.BreakerCooldown),
+	}
+	p.byHost[host] = u
+	p.ordered = append(p.ordered, u)
+	return u
+}
+
+// lookup finds the upstream for a host. Hosts that are not a configured service still get
+// their own client and breaker, named after the host.
+func (p *Pool) lookup(host string) *upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if u, ok := p.byHost[host]; ok {
+		return u
+	}
+	return p.add(host, host, p.cfg.Default)
+}
+
+// Do sends req through the upstream's breaker. GET and HEAD are retried on transport
+// errors and 502/503/504 with jittered exponential backoff; other methods go out once,
+// since the upstream may already have acted on them.
+func (p *Pool) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	u := p.lookup(string(req.URI().Host()))
+
+	attempts := 1
+	if req.Header.IsGet() || req.Header.IsHead() {
+		attempts += p.cfg.Retries
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(p.cfg.RetryBackoff, attempt))
+		}
+		if !u.breaker.Allow() {
+			if attempt == 0 {
+				return ErrCircuitOpen
+			}
+			// Our own failures opened it, report what actually went wrong
+			break
+		}
+
+		resp.Reset()
+		start := time.Now()
+		err = u.client.Do(req, resp)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode()
+		}
+		metrics.ObserveUpstream(u.name, string(req.Header.Method()), status, time.Since(start))
+		if err == nil && !retryableStatus(resp.StatusCode()) {
+			u.breaker.Success()
+			return nil
+		}
+		u.breaker.Failure()
+	}
+	// The last upstream answer is still worth relaying, a 503 from the service says more than ours
+	return err
+}
+
+func retryableStatus(status int) bool {
+	return status == fiber.StatusBadGateway || status == fiber.StatusServiceUnavailable || status == fiber.StatusGatewayTimeout
+}
+
+// backoff waits a random time up to base * 2^(attempt-1), so retries from many requests spread out
+func backoff(base time.Duration, attempt int) time.Duration {
+	ceiling := base << (attempt - 1)
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// Status reports every upstream's breaker, keyed by upstream name
+func (p *Pool) Status() map[string]BreakerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make(map[string]BreakerStatus, len(p.ordered))
+	for _, u := range p.ordered {
+		status[u.name] = u.breaker.Status()
+	}
+	return status
+}
+
+// ErrorStatus maps a failed call to what the client is told: 503 while the breaker is open,
+// 504 when the upstream was too slow, 502 for anything else on the wire
+func ErrorStatus(err error) (int, string) {
+	if errors.Is(err, ErrCircuitOpen) {
+		return fiber.StatusServiceUnavailable, "Service temporarily unavailable"
+	}
+	var netErr net.Error
+	if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fiber.StatusGatewayTimeout, "Service timed out"
+	}
+	return fiber.StatusBadGateway, "Service unavailable"
+}
+
+var defaultPool = NewPool(config.UpstreamConfig{
+	Default: config.UpstreamClientConfig{
+		DialTimeout:  time.Second,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		MaxConns:     512,
+	},
+	Retries:         2,
+	RetryBackoff:    50 * time.Millisecond,
+	BreakerFailures: 5,
+	BreakerCooldown: 30 * time.Second,
+}, nil)
+
+// Configure replaces the pool the package level Do uses. Call it once at startup.
+func Configure(cfg config.UpstreamConfig, upstreams map[string]string) {
+	defaultPool = NewPool(cfg, upstreams)
+}
+
+func Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return defaultPool.Do(req, resp)
+}
+
+func Status() map[string]BreakerStatus {
+	return defaultPool.Status()
+}
@@ -0,0 +1,136 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"gateway/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// failingServer answers every request with status and counts them
+func failingServer(t *testing.T, status int) (string, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &calls
+}
+
+func testPool(retries, breakerFailures int) *Pool {
+	return NewPool(config.UpstreamConfig{
+		Default: config.UpstreamClientConfig{
+			DialTimeout:  time.Second,
+			ReadTimeout:  time.Second,
+			WriteTimeout: time.Second,
+			MaxConns:     4,
+		},
+		Retries:         retries,
+		RetryBackoff:    time.Millisecond,
+		BreakerFailures: breakerFailures,
+		BreakerCooldown: time.Minute,
+	}, nil)
+}
+
+func call(p *Pool, method, url string) (int, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.Header.SetMethod(method)
+	req.SetRequestURI(url)
+	err := p.Do(req, resp)
+	return resp.StatusCode(), err
+}
+
+func TestDoRetriesGetUpToRetries(t *testing.T) {
+	url, calls := failingServer(t, fiber.StatusServiceUnavailable)
+	pool := testPool(2, 10)
+
+	status, err := call(pool, fiber.MethodGet, url)
+	if err != nil || status != fiber.StatusServiceUnavailable {
+		t.Fatalf("Do = %d, %v, want the upstream's 503 relayed", status, err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("upstream called %d times, want 1 + 2 retries", n)
+	}
+}
+
+func TestDoNeverRetriesPost(t *testing.T) {
+	url, calls := failingServer(t, fiber.StatusServiceUnavailable)
+	pool := testPool(2, 10)
+
+	if _, err := call(pool, fiber.MethodPost, url); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times for a POST, want 1", n)
+	}
+}
+
+func TestDoDoesNotRetrySuccess(t *testing.T) {
+	url, calls := failingServer(t, fiber.StatusNotFound)
+	pool := testPool(2, 10)
+
+	if status, err := call(pool, fiber.MethodGet, url); err != nil || status != fiber.StatusNotFound {
+		t.Fatalf("Do = %d, %v, want the 404 passed through", status, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+}
+
+func TestDoStopsAtOpenBreaker(t *testing.T) {
+	url, calls := failingServer(t, fiber.StatusBadGateway)
+	pool := testPool(5, 2)
+
+	if _, err := call(pool, fiber.MethodGet, url); err != nil {
+		t.Fatalf("Do = %v, want the upstream's answer rather than our own breaker", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("upstream called %d times, want the retries cut off once the breaker opened at 2", n)
+	}
+
+	if _, err := call(pool, fiber.MethodGet, url); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("next call = %v, want %v", err, ErrCircuitOpen)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("upstream called %d times, want no call while the breaker is open", n)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"open breaker", ErrCircuitOpen, fiber.StatusServiceUnavailable},
+		{"read timeout", fasthttp.ErrTimeout, fiber.StatusGatewayTimeout},
+		{"dial timeout", fasthttp.ErrDialTimeout, fiber.StatusGatewayTimeout},
+		{"network timeout", fmt.Errorf("dial: %w", timeoutError{}), fiber.StatusGatewayTimeout},
+		{"connection refused", errors.New("dial tcp: connection refused"), fiber.StatusBadGateway},
+		{"connection closed", fasthttp.ErrConnectionClosed, fiber.StatusBadGateway},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if status, _ := ErrorStatus(tc.err); status != tc.want {
+				t.Errorf("ErrorStatus(%v) = %d, want %d", tc.err, status, tc.want)
+			}
+		})
+	}
+}
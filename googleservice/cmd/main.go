@@ -1,23 +1,56 @@
 package main
 
 import (
+	"fmt"
 	"google-service/internal/config"
 	"google-service/internal/handlers"
 	"google-service/internal/middleware"
 	"google-service/utils"
-	"log"
+	"log/slog"
 	"os"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/shutdown"
+	"platform/tracing"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 func init() {
 	utils.SetupTimeZone()
 }
 
+// readinessChecks fails while the SMTP or OAuth credentials are missing, since sign in and
+// every email depend on them, or while the services the OAuth callback calls are down
+func readinessChecks(cfg *config.Config) []health.Check {
+	smtp := cfg.Email.SMTPConfig
+	return []health.Check{
+		health.Configured("smtp", map[string]string{
+			"SMTP_HOST":     smtp.Host,
+			"SMTP_USERNAME": smtp.Username,
+			"SMTP_PASSWORD": smtp.Password,
+			"SMTP_FROM":     smtp.From,
+		}),
+		health.Configured("google-oauth", map[string]string{
+			"GOOGLE_CLIENT_ID":     cfg.GoogleAuth.ClientID,
+			"GOOGLE_CLIENT_SECRET": cfg.GoogleAuth.ClientSecret,
+			"GOOGLE_REDIRECT_URL":  cfg.GoogleAuth.RedirectURL,
+		}),
+		health.HTTP("user-service", cfg.USER_SERVICE_URL+"/livez"),
+		health.HTTP("auth-service", cfg.AUTH_SERVICE_URL+"/livez"),
+	}
+}
+
 func main() {
+	logging.Init("google-service")
+
+	shutdownTracing, err := tracing.Init("google-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
+	}
+
 	cfg := config.Google_config
 
 	// Initialize app
@@ -26,11 +59,11 @@ func main() {
 		WriteTimeout: cfg.Server.WriteTimeout,
 	})
 
+	app.Use(tracing.Middleware("google-service"), logging.Middleware(), metrics.Middleware())
 	app.Use(cors.New())
-	app.Use(logger.New())
 
 	// Initialize handlers
-	googleHandler := handlers.NewGoogleHandler(cfg.GoogleAuth)
+	googleHandler := handlers.NewGoogleHandler(cfg)
 	emailHandler := handlers.NewEmailHandler(cfg.Email)
 	meetHandler := handlers.NewMeetHandler(cfg.GoogleAuth)
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -39,6 +72,9 @@ func main() {
 			"service": "google-service",
 		})
 	})
+	app.Get("/livez", health.Livez("google-service"))
+	app.Get("/readyz", health.Readyz("google-service", readinessChecks(cfg)...))
+	app.Get("/metrics", metrics.Handler())
 	// Routes
 	api := app.Group("/api", middleware.Middleware(os.Getenv("API_KEY")))
 
@@ -61,5 +97,10 @@ func main() {
 	if port == "" {
 		port = "8084"
 	}
-	log.Fatal(app.Listen(":" + port))
+	if err := shutdown.Serve(app, ":"+port,
+		shutdown.Step{Name: "tracing", Run: shutdownTracing},
+	); err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
+	}
 }
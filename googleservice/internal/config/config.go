@@ -1,7 +1,7 @@
 package config
 
 import (
-	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
@@ -17,10 +17,11 @@ type Config struct {
 	Server           ServerConfig
 	GoogleAuth       *GoogleOAuthConfig
 	Email            *EmailConfig
-	JWT              JWTConfig
 	ServiceAccount   *ServiceAccountConfig
 	API_KEY          string
 	USER_SERVICE_URL string
+	AUTH_SERVICE_URL string
+	TOKEN_ISSUE_KEY  string
 }
 
 type ServerConfig struct {
@@ -37,12 +38,6 @@ type SMTPConfig struct {
 	From     string
 }
 
-type JWTConfig struct {
-	Secret        string
-	ExpiresIn     time.Duration
-	RefreshSecret string
-}
-
 type ServiceAccountConfig struct {
 	CredentialsJSON []byte
 }
@@ -53,10 +48,11 @@ func New() *Config {
 		Server:           loadServerConfig(),
 		GoogleAuth:       NewGoogleOAuthConfig(),
 		Email:            NewEmailConfig(),
-		JWT:              loadJWTConfig(),
 		ServiceAccount:   NewServiceAccountConfig(),
 		API_KEY:          os.Getenv("API_KEY"),
 		USER_SERVICE_URL: os.Getenv("USER_SERVICE_URL"),
+		AUTH_SERVICE_URL: os.Getenv("AUTH_SERVICE_URL"),
+		TOKEN_ISSUE_KEY:  os.Getenv("TOKEN_ISSUE_KEY"),
 	}
 }
 
@@ -98,28 +94,6 @@ func loadSMTPConfig() SMTPConfig {
 	}
 }
 
-func loadJWTConfig() JWTConfig {
-	expiresIn, err := strconv.Atoi(os.Getenv("JWT_EXPIRES_IN"))
-	if err != nil {
-		expiresIn = 24 // default 24 hours
-	}
-
-	return JWTConfig{
-		Secret:        getRequiredEnv("JWT_SECRET"),
-		ExpiresIn:     time.Duration(expiresIn) * time.Hour,
-		RefreshSecret: getRequiredEnv("JWT_REFRESH_SECRET"),
-	}
-}
-
-// getRequiredEnv gets an environment variable or panics if it's not set
-func getRequiredEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		panic("Required environment variable not set: " + key)
-	}
-	return value
-}
-
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	// Add validation logic here if needed
@@ -148,7 +122,7 @@ func loadMeetCredentials() ([]byte, error) {
 func NewServiceAccountConfig() *ServiceAccountConfig {
 	credentials, err := loadMeetCredentials()
 	if err != nil {
-		fmt.Println("Create New Service Account failed:", err)
+		slog.Error("Create new service account failed", "error", err)
 		return nil
 	}
 	return &ServiceAccountConfig{
@@ -1,27 +1,28 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
 	"google-service/internal/config"
-	"google-service/internal/middleware"
 	"google-service/internal/services"
-	"google-service/utils"
-	"os"
-	"time"
+	"log/slog"
+	"platform/client"
+	"platform/contracts"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
-	"github.com/valyala/fasthttp"
 )
 
 type GoogleHandler struct {
 	oauthService *services.GoogleOAuthService
+	users        *client.UserClient
+	// google-service holds no signing keys of its own, auth-service mints the session.
+	// It carries the token issue key rather than the shared API key.
+	auth *client.AuthClient
 }
 
-func NewGoogleHandler(config *config.GoogleOAuthConfig) *GoogleHandler {
+func NewGoogleHandler(cfg *config.Config) *GoogleHandler {
 	return &GoogleHandler{
-		oauthService: services.NewGoogleOAuthService(config),
+		oauthService: services.NewGoogleOAuthService(cfg.GoogleAuth),
+		users:        client.NewUserClient(cfg.USER_SERVICE_URL, cfg.API_KEY),
+		auth:         client.NewAuthClient(cfg.AUTH_SERVICE_URL, cfg.TOKEN_ISSUE_KEY),
 	}
 }
 
@@ -39,7 +40,6 @@ func (h *GoogleHandler) HandleGoogleLogin(c *fiber.Ctx) error {
 
 func (h *GoogleHandler) HandleGoogleCallback(c *fiber.Ctx) error {
 	state := c.Cookies("oauth_state")
-	fmt.Println("COOKIE:", state, "\nQUERY:", c.Query("state"))
 	if state != c.Query("state") {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid state",
@@ -55,8 +55,7 @@ func (h *GoogleHandler) HandleGoogleCallback(c *fiber.Ctx) error {
 
 	token, err := h.oauthService.Exchange(c.Context(), code)
 	if err != nil {
-
-		fmt.Printf("Token exchange error: %v\n", err)
+		slog.ErrorContext(c.UserContext(), "Token exchange failed", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to exchange token",
 			"details": err.Error(),
@@ -70,98 +69,57 @@ func (h *GoogleHandler) HandleGoogleCallback(c *fiber.Ctx) error {
 		})
 	}
 
-	err = AddUser(userInfo.Name, userInfo.Email, userInfo.Picture, token.AccessToken)
-	if err != nil {
-		fmt.Println("GOOGLE SERVICE: Save User to Database failed:", err)
-	}
-
-	id, err := GetUserID(userInfo.Email)
-	if err != nil {
-		fmt.Println("GOOGLE SERVICE: Get user id failed:", err)
-	}
-
-	claims := middleware.Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "google-service",
-		},
-		UserID:   uint(id),
+	err = h.users.AddGoogle(c.UserContext(), contracts.NewUser{
 		Username: userInfo.Email,
 		Email:    userInfo.Email,
-		Role:     "Parent",
+		Role:     contracts.RoleParent,
+		Picture:  userInfo.Picture,
+	}, token.AccessToken)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Save user to database failed", "error", err)
 	}
 
-	type User struct {
-		Userid   uint   `json:"ID"`
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Role     string `json:"role"`
-		Status   string `json:"status"`
-		Picture  string `json:"picture"`
+	// The account may predate this Google sign-in, so role and status come from user-service,
+	// which answers 403 for banned, deleted and locked accounts
+	user, err := h.users.ByEmail(c.UserContext(), userInfo.Email)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Get user failed", "error", err)
+		if client.StatusOf(err) == fiber.StatusForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Account is not allowed to sign in",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get user information",
+		})
 	}
 
-	jwt_token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwt_tokenString, err := jwt_token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	session, err := h.auth.IssueToken(c.UserContext(), user.Username)
 	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Issue token failed", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "User is Signed in but could not generate jwt token",
 		})
 	}
 	h.oauthService.StoreUserToken(userInfo.Email, token)
 
-	return c.JSON(fiber.Map{
-		"token": jwt_tokenString,
-		"user": User{
-			Userid:   uint(id),
-			Username: userInfo.Email,
-			Email:    userInfo.Email,
-			Role:     "Parent",
-			Status:   "Active",
-			Picture:  userInfo.Picture,
-		},
-	})
-}
-
-func AddUser(name, email, picture, access_token string) error {
-	req := &fasthttp.Request{}
-	resp := &fasthttp.Response{}
-
-	body := fmt.Sprintf(`{"username":"%s","password":"%s", "email":"%s", "role":"%s", "picture":"%s"}`, email, "", email, "Parent", picture)
-	query := fmt.Sprintf("?google_token=%s", access_token)
-	fmt.Println(config.Google_config.USER_SERVICE_URL + "/user/add" + query)
-	utils.BuildRequest(req, "POST", []byte(body), config.Google_config.API_KEY, config.Google_config.USER_SERVICE_URL+"/user/add"+query)
-
-	if err := fasthttp.Do(req, resp); err != nil {
-		return fmt.Errorf("user service unavailable: %v", err)
+	if user.Picture == "" {
+		user.Picture = userInfo.Picture
 	}
 
-	if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
-		return nil
-	}
-	return fmt.Errorf("add user failed: %s", string(resp.Body()))
-}
-
-func GetUserID(email string) (int, error) {
-	req := &fasthttp.Request{}
-	resp := &fasthttp.Response{}
-
-	query := fmt.Sprintf("?email=%s", email)
-	utils.BuildRequest(req, "GET", nil, config.Google_config.API_KEY, config.Google_config.USER_SERVICE_URL+"/user/get/id"+query)
-
-	if err := fasthttp.Do(req, resp); err != nil {
-		return 0, fmt.Errorf("user service unavailable: %v", err)
+	if session.TwoFactorRequired {
+		return c.JSON(fiber.Map{
+			"two_factor_required": true,
+			"enrollment_required": session.EnrollmentRequired,
+			"challenge_token":     session.ChallengeToken,
+			"expires_in":          session.ExpiresIn,
+		})
 	}
 
-	if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
-		var originalData map[string]interface{}
-		if err := json.Unmarshal(resp.Body(), &originalData); err != nil {
-			fmt.Printf("Error parsing JSON response: %v\n", err)
-			return 0, fmt.Errorf("error formatting response data: %s", err)
-		}
-		id := int(originalData["user_id"].(float64))
-
-		return id, nil
-	}
-	return 0, fmt.Errorf("get user id failed: %s", string(resp.Body()))
+	return c.JSON(fiber.Map{
+		"token":         session.Token,
+		"refresh_token": session.RefreshToken,
+		"expires_in":    session.ExpiresIn,
+		"user":          user,
+	})
 }
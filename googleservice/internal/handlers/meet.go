@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"fmt"
 	"google-service/internal/config"
 	"google-service/internal/services"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -17,7 +17,7 @@ func NewMeetHandler(config *config.GoogleOAuthConfig) *MeetHandler {
 	oauthService := services.NewGoogleOAuthService(config)
 	meetService, err := services.NewMeetService(config)
 	if err != nil {
-		fmt.Println("Meet Service init failed:", err)
+		slog.Error("Meet service init failed", "error", err)
 		return nil
 	}
 
@@ -40,7 +40,7 @@ func (m *MeetHandler) CreateMeetWithEmail(c *fiber.Ctx) error {
 
 	token, err := m.OAuthService.GetUserToken(email)
 	if err != nil {
-		fmt.Println("Retriving token from email failed:", err)
+		slog.ErrorContext(c.UserContext(), "Retrieving token from email failed", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Retriving token from email failed",
 		})
@@ -48,7 +48,7 @@ func (m *MeetHandler) CreateMeetWithEmail(c *fiber.Ctx) error {
 
 	meetLink, err := m.MeetService.CreateMeetLinkWithOAUTH(token, title)
 	if err != nil {
-		fmt.Println("Failed to create meet link:", err)
+		slog.ErrorContext(c.UserContext(), "Failed to create meet link", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to create meeting",
 			"details": err.Error(),
@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var emailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "emails_sent_total",
+	Help: "Emails handed to the SMTP server, by result: sent or failed.",
+}, []string{"result"})
+
+func RecordEmail(result string) {
+	emailsSent.WithLabelValues(result).Inc()
+}
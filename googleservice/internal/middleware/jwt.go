@@ -1,84 +0,0 @@
-package middleware
-
-import (
-	"fmt"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
-)
-
-// Custom claims struct
-type Claims struct {
-	jwt.RegisteredClaims
-	UserID   uint   `json:"userId"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-}
-
-// JWTMiddleware validates the JWT token
-func JWTMiddleware(jwtSecret string) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		token := c.Get("Authorization")
-		if len(token) > 7 && token[:7] == "Bearer " {
-			token = token[7:]
-		}
-		if token == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing authorization token",
-			})
-		}
-
-		// Parse and validate token with custom claims
-		claims := &Claims{}
-		parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
-			})
-		}
-
-		if !parsedToken.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Token is not valid",
-			})
-		}
-
-		// Store claims in context for later use
-		c.Locals("user", claims)
-		return c.Next()
-	}
-}
-
-// RequireRole middleware for role-based access control
-func RequireRole(roles ...string) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		claims, ok := c.Locals("user").(*Claims)
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token claims",
-			})
-		}
-
-		// Check if user's role matches any of the required roles
-		for _, role := range roles {
-			if claims.Role == role {
-				return c.Next()
-			}
-		}
-
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Access denied: Insufficient privileges",
-		})
-	}
-}
-
-// RequireAdmin middleware specifically for admin access
-func RequireAdmin() fiber.Handler {
-	return RequireRole("admin")
-}
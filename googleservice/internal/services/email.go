@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"google-service/internal/config"
+	"google-service/internal/metrics"
+	"log/slog"
 	"net/smtp"
 	"strings"
 )
@@ -29,8 +31,10 @@ func (e *EmailService) SendEmail(title, body string, receipient []string) error
 
 	err := smtp.SendMail(addr, auth, e.config.SMTPConfig.From, to, message)
 	if err != nil {
-		fmt.Printf("Error sending email: %s", err)
+		metrics.RecordEmail("failed")
+		slog.Error("Sending email failed", "error", err)
 		return err
 	}
+	metrics.RecordEmail("sent")
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"google-service/internal/config"
 	"google-service/internal/models"
 	"io"
+	"log/slog"
 	"net/http"
 
 	"golang.org/x/oauth2"
@@ -75,7 +76,7 @@ func (s *GoogleOAuthService) GetUserInfo(token *oauth2.Token) (*models.GoogleUse
 }
 
 func (s *GoogleOAuthService) StoreUserToken(email string, token *oauth2.Token) {
-	fmt.Printf("Storing token for user %s\n", email)
+	slog.Info("Storing Google token", "email", email)
 	TokenStorage[email] = token
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"google-service/internal/config"
+	"log/slog"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -68,7 +69,7 @@ func (s *MeetService) CreateMeetLink(accessToken string, meetingTitle string) (s
 		ConferenceDataVersion(1).
 		Do()
 	if err != nil {
-		fmt.Printf("Error creating meet link: %v", err)
+		slog.ErrorContext(ctx, "Creating meet link failed", "error", err)
 		return "", fmt.Errorf("failed to create event: %v", err)
 	}
 
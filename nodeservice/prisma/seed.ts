@@ -21,6 +21,11 @@ async function deleteAllData(orderedFileNames: string[]) {
 }
 
 async function main() {
+  if (!process.argv.includes("--force") && (await prisma.tutor.count()) > 0) {
+    console.log("Database already seeded, run with --force to reseed");
+    return;
+  }
+
   const dataDirectory = path.join(__dirname, "seedData");
 
   const orderedFileNames = [
@@ -1,6 +1,42 @@
-import { log } from "console";
+import { createPublicKey, KeyObject } from "crypto";
 import { NextFunction, Request, Response } from "express";
-import jwt, { JwtPayload } from "jsonwebtoken";
+import jwt, { JwtHeader, JwtPayload } from "jsonwebtoken";
+
+// Tokens are signed by auth-service; its public keys are cached and
+// refetched when they go stale or an unknown kid shows up.
+const JWKS_URL = `${process.env.AUTH_SERVICE_URL}/.well-known/jwks.json`;
+const JWKS_TTL_MS = 5 * 60 * 1000;
+
+let jwksKeys = new Map<string, KeyObject>();
+let jwksFetchedAt = 0;
+
+const fetchJwks = async (): Promise<void> => {
+  const response = await fetch(JWKS_URL);
+  if (!response.ok) {
+    throw new Error(`JWKS fetch failed with status ${response.status}`);
+  }
+  const body = (await response.json()) as { keys: any[] };
+  const keys = new Map<string, KeyObject>();
+  for (const jwk of body.keys) {
+    keys.set(jwk.kid, createPublicKey({ key: jwk, format: "jwk" }));
+  }
+  jwksKeys = keys;
+  jwksFetchedAt = Date.now();
+};
+
+const getSigningKey = async (kid?: string): Promise<KeyObject> => {
+  if (!kid) {
+    throw new Error("Token has no kid");
+  }
+  if (!jwksKeys.has(kid) || Date.now() - jwksFetchedAt > JWKS_TTL_MS) {
+    await fetchJwks();
+  }
+  const key = jwksKeys.get(kid);
+  if (!key) {
+    throw new Error("Unknown signing key");
+  }
+  return key;
+};
 
 const tutorAuth = async (
   req: Request,
@@ -18,7 +54,12 @@ const tutorAuth = async (
   const token = authHeader.split(" ")[1]; // Lấy token từ "Bearer <token>"
 
   try {
-    const tokenDecode = jwt.verify(token, String(process.env.JWT_SECRET));
+    const header = jwt.decode(token, { complete: true })?.header as
+      | JwtHeader
+      | undefined;
+    const key = await getSigningKey(header?.kid);
+    // jsonwebtoken cannot verify EdDSA, so node-service needs auth-service to sign with RSA keys
+    const tokenDecode = jwt.verify(token, key, { algorithms: ["RS256"] });
 
     if (typeof tokenDecode === "object" && "userId" in tokenDecode) {
       req.body.userId = (tokenDecode as JwtPayload).userId;
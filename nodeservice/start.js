@@ -12,9 +12,11 @@ async function waitForDatabase() {
       console.log("Generating Prisma Client...");
       execSync("npx prisma generate", { stdio: "inherit" });
 
-      // Force reset and push the new schema
-      console.log("Pushing new schema to database (with reset)...");
-      execSync("npx prisma db push --force-reset --accept-data-loss", {
+      // Tables the Go services own are created by their migrations and only mirrored in
+      // schema.prisma. Without --accept-data-loss the push stops on any drift instead of
+      // dropping their data.
+      console.log("Pushing schema to database...");
+      execSync("npx prisma db push --skip-generate", {
         stdio: "inherit",
       });
 
@@ -0,0 +1,133 @@
+// Package client is how one service calls another. Client carries the API key, trace context
+// and request id on every call and turns non-2xx answers into *Error; the typed clients on top
+// of it decode into platform/contracts so a change to a wire type fails the build of its callers.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"platform/tracing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultTimeout bounds a call unless the client was given another with WithTimeout
+const DefaultTimeout = 10 * time.Second
+
+// Prepare sets up a call to another service, carrying ctx's trace and request id along.
+// It is for callers that need the raw request, such as the gateway proxying a body it never decodes.
+func Prepare(ctx context.Context, req *fasthttp.Request, method string, body []byte, apiKey string, url string) {
+	tracing.Inject(ctx, req)
+	req.SetBody(body)
+	req.Header.SetMethod(method)
+	req.Header.Set("API_KEY", apiKey)
+	req.Header.SetContentType("application/json")
+	req.SetRequestURI(url)
+}
+
+// Error is a call that reached the other service and got a non-2xx answer back
+type Error struct {
+	Service string
+	Status  int
+	// Message is the "error" or "message" field of a JSON body, or the body itself
+	Message string
+	Body    []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Service, e.Status, e.Message)
+}
+
+func newError(service string, status int, body []byte) *Error {
+	e := &Error{Service: service, Status: status, Body: body, Message: strings.TrimSpace(string(body))}
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		if payload.Error != "" {
+			e.Message = payload.Error
+		} else if payload.Message != "" {
+			e.Message = payload.Message
+		}
+	}
+	return e
+}
+
+// Client calls one service's API
+type Client struct {
+	service string
+	baseURL string
+	apiKey  string
+	timeout time.Duration
+}
+
+// New returns a client for service, the name only shows up in errors
+func New(service, baseURL, apiKey string) *Client {
+	return &Client{service: service, baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, timeout: DefaultTimeout}
+}
+
+// WithTimeout returns a copy of the client that gives up on a call after timeout
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	clone := *c
+	clone.timeout = timeout
+	return &clone
+}
+
+// Do sends in as the JSON body, nil for none, and decodes a 2xx answer into out, nil to
+// discard it. A json.RawMessage out receives the body untouched.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encoding request to %s: %w", c.service, err)
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	Prepare(ctx, req, method, body, c.apiKey, target)
+
+	if err := fasthttp.DoTimeout(req, resp, c.timeout); err != nil {
+		return fmt.Errorf("%s unavailable: %w", c.service, err)
+	}
+
+	status := resp.StatusCode()
+	if status < 200 || status > 299 {
+		return newError(c.service, status, append([]byte(nil), resp.Body()...))
+	}
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*json.RawMessage); ok {
+		*raw = append((*raw)[:0], resp.Body()...)
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body(), out); err != nil {
+		return fmt.Errorf("decoding response from %s: %w", c.service, err)
+	}
+	return nil
+}
+
+// StatusOf is the status the other service answered err with, 0 when it never answered
+func StatusOf(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status
+	}
+	return 0
+}
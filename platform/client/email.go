@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EmailClient sends mail through google-service, which holds the SMTP credentials
+type EmailClient struct {
+	*Client
+}
+
+func NewEmailClient(baseURL, apiKey string) *EmailClient {
+	return &EmailClient{New("google-service", baseURL, apiKey).WithTimeout(15 * time.Second)}
+}
+
+// Send delivers a plain text email
+func (c *EmailClient) Send(ctx context.Context, to, title, body string) error {
+	query := url.Values{}
+	query.Set("to", to)
+	query.Set("title", title)
+	query.Set("body", body)
+	return c.Do(ctx, http.MethodPost, "/api/email/send", query, nil, nil)
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"platform/contracts"
+)
+
+// PaymentClient calls payment-service
+type PaymentClient struct {
+	*Client
+}
+
+func NewPaymentClient(baseURL, apiKey string) *PaymentClient {
+	return &PaymentClient{New("payment-service", baseURL, apiKey)}
+}
+
+// CreatePayPal opens a PayPal order and returns the URL to send the payer to
+func (c *PaymentClient) CreatePayPal(ctx context.Context, orderID string, amount float64, description string) (string, error) {
+	query := url.Values{}
+	query.Set("amount", strconv.FormatFloat(amount, 'f', 2, 64))
+	query.Set("description", description)
+	query.Set("orderId", orderID)
+
+	var response struct {
+		RedirectURL string `json:"redirectUrl"`
+		PaymentID   string `json:"paymentId"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/api/payment/paypal/create", query, nil, &response); err != nil {
+		return "", err
+	}
+	return response.RedirectURL, nil
+}
+
+// Order fetches an order by the id the caller gave it
+func (c *PaymentClient) Order(ctx context.Context, orderID string) (*contracts.Payment, error) {
+	var payment contracts.Payment
+	if err := c.Do(ctx, http.MethodGet, "/api/payment/order/"+url.PathEscape(orderID), nil, nil, &payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
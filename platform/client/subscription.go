@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"platform/contracts"
+)
+
+// SubscriptionClient calls subscription-service
+type SubscriptionClient struct {
+	*Client
+}
+
+func NewSubscriptionClient(baseURL, apiKey string) *SubscriptionClient {
+	return &SubscriptionClient{New("subscription-service", baseURL, apiKey)}
+}
+
+// ByTutor returns a tutor's current subscription, a 404 *Error when there is none
+func (c *SubscriptionClient) ByTutor(ctx context.Context, tutorID uint) (*contracts.Subscription, error) {
+	var response struct {
+		Data contracts.Subscription `json:"data"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/subscriptions/tutor/"+strconv.FormatUint(uint64(tutorID), 10), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// SubscriptionFilter narrows List, zero fields are left out
+type SubscriptionFilter struct {
+	Page    int
+	Limit   int
+	Status  contracts.SubscriptionStatus
+	TutorID uint
+	PlanID  uint
+	// FromDate and ToDate are YYYY-MM-DD
+	FromDate string
+	ToDate   string
+}
+
+// List pages through every tutor's subscriptions
+func (c *SubscriptionClient) List(ctx context.Context, filter SubscriptionFilter) (*contracts.Page[contracts.Subscription], error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(filter.Page))
+	query.Set("limit", strconv.Itoa(filter.Limit))
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+	if filter.TutorID != 0 {
+		query.Set("tutor_id", strconv.FormatUint(uint64(filter.TutorID), 10))
+	}
+	if filter.PlanID != 0 {
+		query.Set("plan_id", strconv.FormatUint(uint64(filter.PlanID), 10))
+	}
+	if filter.FromDate != "" {
+		query.Set("from_date", filter.FromDate)
+	}
+	if filter.ToDate != "" {
+		query.Set("to_date", filter.ToDate)
+	}
+
+	var page contracts.Page[contracts.Subscription]
+	if err := c.Do(ctx, http.MethodGet, "/api/admin/subscriptions", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
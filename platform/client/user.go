@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"platform/contracts"
+)
+
+// ErrAccountLocked is user-service turning a login away because of too many failed attempts
+var ErrAccountLocked = errors.New("account is temporarily locked")
+
+// UserClient calls user-service
+type UserClient struct {
+	*Client
+}
+
+func NewUserClient(baseURL, apiKey string) *UserClient {
+	return &UserClient{New("user-service", baseURL, apiKey)}
+}
+
+// Login checks a username and password and returns the account they belong to
+func (c *UserClient) Login(ctx context.Context, username, password string) (*contracts.User, error) {
+	var user contracts.User
+	err := c.Do(ctx, http.MethodPost, "/user/get", nil, map[string]string{"username": username, "password": password}, &user)
+	if StatusOf(err) == http.StatusLocked {
+		return nil, ErrAccountLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Add creates an account
+func (c *UserClient) Add(ctx context.Context, user contracts.NewUser) error {
+	return c.Do(ctx, http.MethodPost, "/user/add", nil, user, nil)
+}
+
+// AddGoogle creates an account for a Google sign-in, keeping the Google access token with it
+func (c *UserClient) AddGoogle(ctx context.Context, user contracts.NewUser, googleToken string) error {
+	return c.Do(ctx, http.MethodPost, "/user/add", url.Values{"google_token": {googleToken}}, user, nil)
+}
+
+// ByUsername looks an account up whatever its status
+func (c *UserClient) ByUsername(ctx context.Context, username string) (*contracts.User, error) {
+	var user contracts.User
+	if err := c.Do(ctx, http.MethodGet, "/user", url.Values{"username": {username}}, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ByID looks an account up whatever its status
+func (c *UserClient) ByID(ctx context.Context, id uint) (*contracts.User, error) {
+	var user contracts.User
+	if err := c.Do(ctx, http.MethodGet, "/user/get/by-id", url.Values{"id": {strconv.FormatUint(uint64(id), 10)}}, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ByEmail looks up an account that may sign in. Banned, deleted and locked accounts come back
+// as a 403 *Error, unknown addresses as a 404.
+func (c *UserClient) ByEmail(ctx context.Context, email string) (*contracts.User, error) {
+	var user contracts.User
+	if err := c.Do(ctx, http.MethodGet, "/user/get/email", url.Values{"email": {email}}, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UserFilter narrows List, zero fields are left out
+type UserFilter struct {
+	Page        int
+	Limit       int
+	Role        contracts.Role
+	Status      contracts.Status
+	Search      string
+	IsVerified  *bool
+	CreatedFrom string
+	CreatedTo   string
+	Sort        string
+	SortDir     string
+}
+
+// List pages through accounts
+func (c *UserClient) List(ctx context.Context, filter UserFilter) (*contracts.Page[contracts.User], error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(filter.Page))
+	query.Set("limit", strconv.Itoa(filter.Limit))
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("role", string(filter.Role))
+	set("status", string(filter.Status))
+	set("search", filter.Search)
+	set("created_from", filter.CreatedFrom)
+	set("created_to", filter.CreatedTo)
+	set("sort", filter.Sort)
+	set("sort_dir", filter.SortDir)
+	if filter.IsVerified != nil {
+		query.Set("is_verified", strconv.FormatBool(*filter.IsVerified))
+	}
+
+	var page contracts.Page[contracts.User]
+	if err := c.Do(ctx, http.MethodGet, "/user/get-all-user", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// TwoFactor runs one of the 2FA actions (enroll, activate, verify, disable) for a user and
+// returns user-service's answer untouched, enroll's carries the secret to show the user
+func (c *UserClient) TwoFactor(ctx context.Context, action, username, code string) (json.RawMessage, error) {
+	var body json.RawMessage
+	err := c.Do(ctx, http.MethodPost, "/user/2fa/"+url.PathEscape(action), url.Values{"username": {username}}, map[string]string{"code": code}, &body)
+	return body, err
+}
+
+// ForgotPassword has user-service email a reset link
+func (c *UserClient) ForgotPassword(ctx context.Context, email string) (json.RawMessage, error) {
+	var body json.RawMessage
+	err := c.Do(ctx, http.MethodPost, "/user/password/forgot", nil, map[string]string{"email": email}, &body)
+	return body, err
+}
+
+// ResetPassword spends a reset token and returns the account with its new PasswordChangedAt
+func (c *UserClient) ResetPassword(ctx context.Context, token, newPassword string) (*contracts.User, error) {
+	var user contracts.User
+	if err := c.Do(ctx, http.MethodPost, "/user/password/reset", nil, map[string]string{"token": token, "new_password": newPassword}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,50 @@
+package contracts
+
+// LoginRequest signs a user in with a password, DeviceID ties the refresh token to a device
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	DeviceID string `json:"device_id"`
+}
+
+// RefreshRequest trades a refresh token for a new session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest optionally names the refresh token to revoke along with the access token
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TwoFactorLoginRequest finishes a login that stopped for a second factor
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorEnrollRequest starts enrollment, with the challenge token when a login forced it
+type TwoFactorEnrollRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// ForgotPasswordRequest asks for a reset link to be emailed
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with the token from the reset email
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// RegisterRequest signs a new user up
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Password string `json:"password" validate:"required,min=8"`
+	Email    string `json:"email" validate:"required,email"`
+	Role     string `json:"role"`
+	Fullname string `json:"full_name"`
+	Phone    string `json:"phone"`
+}
@@ -0,0 +1,19 @@
+package contracts
+
+import "github.com/golang-jwt/jwt/v4"
+
+// TokenUseAccess marks tokens that may call the API. 2FA challenges are signed with the same
+// keys and carry another value.
+const TokenUseAccess = "access"
+
+// Claims is what auth-service signs into access tokens and the gateway verifies
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     Role   `json:"role"`
+	// SessionID is the refresh token family the token was minted from
+	SessionID string `json:"sid,omitempty"`
+	TokenUse  string `json:"token_use"`
+}
@@ -0,0 +1,54 @@
+package contracts
+
+import "time"
+
+// CouponDiscountType is whether a coupon takes a percentage or a fixed amount off
+type CouponDiscountType string
+
+const (
+	CouponPercent CouponDiscountType = "percent"
+	CouponFixed   CouponDiscountType = "fixed"
+)
+
+// CouponDuration is how many billed periods a redeemed coupon keeps discounting
+type CouponDuration string
+
+const (
+	CouponOnce      CouponDuration = "once"
+	CouponRepeating CouponDuration = "repeating"
+	CouponForever   CouponDuration = "forever"
+)
+
+// Coupon is a promotion code tutors can redeem when subscribing or changing plan
+type Coupon struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	Code           string             `json:"code"`
+	Description    string             `json:"description"`
+	DiscountType   CouponDiscountType `json:"discount_type"`
+	Amount         float64            `json:"amount"` // percentage off for percent coupons, amount off for fixed ones
+	Duration       CouponDuration     `json:"duration"`
+	DurationCycles int                `json:"duration_cycles"` // billed periods a repeating coupon lasts
+	PlanIDs        []uint             `json:"plan_ids"`        // plans the coupon is limited to, empty for every plan
+	MaxRedemptions int                `json:"max_redemptions"` // across all tutors, 0 for no cap
+	TimesRedeemed  int                `json:"times_redeemed"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
+	IsActive       bool               `json:"is_active"`
+}
+
+// CouponRequest creates or replaces a coupon. A percent coupon's amount is at most 100, which
+// validation checks alongside the tags.
+type CouponRequest struct {
+	Code           string             `json:"code" validate:"required,max=50"`
+	Description    string             `json:"description"`
+	DiscountType   CouponDiscountType `json:"discount_type" validate:"required,oneof=percent fixed"`
+	Amount         float64            `json:"amount" validate:"required,gt=0"`
+	Duration       CouponDuration     `json:"duration" validate:"required,oneof=once repeating forever"`
+	DurationCycles int                `json:"duration_cycles" validate:"required_if=Duration repeating,gte=0"`
+	PlanIDs        []uint             `json:"plan_ids"`
+	MaxRedemptions int                `json:"max_redemptions" validate:"gte=0"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
+	IsActive       bool               `json:"is_active"`
+}
@@ -0,0 +1,6 @@
+// Package contracts holds the types that cross service boundaries: JWT claims, the user
+// record user-service hands out, pagination, the session auth-service mints and the bodies
+// the public API accepts and returns for plans, subscriptions and refunds. Services
+// decode into these instead of keeping their own copies, so a change here breaks the build
+// of every service it affects.
+package contracts
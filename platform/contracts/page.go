@@ -0,0 +1,24 @@
+package contracts
+
+// Pagination describes where a page sits in the full result
+type Pagination struct {
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// NewPagination works out the page count for total results split into pages of pageSize
+func NewPagination(total int64, page, pageSize int) Pagination {
+	totalPages := 0
+	if pageSize > 0 {
+		totalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
+	}
+	return Pagination{Total: total, Page: page, PageSize: pageSize, TotalPages: totalPages}
+}
+
+// Page is one page of a list endpoint
+type Page[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
@@ -0,0 +1,16 @@
+package contracts
+
+import "time"
+
+// Payment is an order as payment-service reports it
+type Payment struct {
+	OrderID       string    `json:"order_id"`
+	Amount        float64   `json:"amount"`
+	Status        string    `json:"status"`
+	PaymentMethod string    `json:"payment_method"`
+	TransactionID string    `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PaymentCompleted is the status of an order that has been paid
+const PaymentCompleted = "COMPLETED"
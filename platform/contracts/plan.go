@@ -0,0 +1,33 @@
+package contracts
+
+import "time"
+
+// Plan is a subscription plan tutors can pick from
+type Plan struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	PriceMonthly   float64  `json:"price_monthly"`
+	PriceAnnually  float64  `json:"price_annually"`
+	MaxCourses     int      `json:"max_courses"`
+	CommissionRate float64  `json:"commission_rate"`
+	TrialDays      int      `json:"trial_days"`
+	Features       []string `json:"features"`
+	IsActive       bool     `json:"is_active"`
+}
+
+// PlanRequest creates or replaces a plan
+type PlanRequest struct {
+	Name           string   `json:"name" validate:"required"`
+	Description    string   `json:"description"`
+	PriceMonthly   float64  `json:"price_monthly" validate:"required,gt=0"`
+	PriceAnnually  float64  `json:"price_annually" validate:"required,gt=0"`
+	MaxCourses     int      `json:"max_courses" validate:"required,gt=0"`
+	CommissionRate float64  `json:"commission_rate" validate:"required,gte=0,lte=100"`
+	TrialDays      int      `json:"trial_days" validate:"gte=0,lte=365"`
+	Features       []string `json:"features"`
+	IsActive       bool     `json:"is_active"`
+}
@@ -0,0 +1,46 @@
+package contracts
+
+import "time"
+
+// RefundStatus is where a refund request is in review
+type RefundStatus string
+
+const (
+	RefundStatusPending  RefundStatus = "PENDING"
+	RefundStatusApproved RefundStatus = "APPROVED"
+	RefundStatusRejected RefundStatus = "REJECTED"
+)
+
+// Refund is a user's refund request as admin-service returns it
+type Refund struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	UserID           uint         `json:"user_id"`
+	Username         string       `json:"username"`
+	Email            string       `json:"email"`
+	OrderID          string       `json:"order_id"`
+	Amount           float64      `json:"amount"`
+	CardNumber       string       `json:"card_number"`
+	Reason           string       `json:"reason"`
+	Status           RefundStatus `json:"status"`
+	ProcessedBy      *uint        `json:"processed_by"`
+	ProcessedAt      *time.Time   `json:"processed_at"`
+	AdminNote        string       `json:"admin_note"`
+	NotificationSent bool         `json:"notification_sent"`
+}
+
+// RefundRequestInput is a user asking for an order to be refunded
+type RefundRequestInput struct {
+	OrderID    string  `json:"order_id" validate:"required"`
+	Amount     float64 `json:"amount" validate:"required,gt=0"`
+	CardNumber string  `json:"card_number" validate:"required"`
+	Reason     string  `json:"reason"`
+}
+
+// RefundProcessInput is an admin's decision on a refund request
+type RefundProcessInput struct {
+	Status    RefundStatus `json:"status" validate:"required,oneof=APPROVED REJECTED"`
+	AdminNote string       `json:"admin_note"`
+}
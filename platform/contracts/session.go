@@ -0,0 +1,15 @@
+package contracts
+
+// Session is what auth-service returns when it mints tokens for a user another service has
+// already authenticated
+type Session struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	User         User   `json:"user"`
+
+	// Set instead of the tokens when the account needs a second factor first
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+}
@@ -0,0 +1,130 @@
+package contracts
+
+import "time"
+
+// BillingCycle is how often a subscription is charged
+type BillingCycle string
+
+const (
+	BillingMonthly  BillingCycle = "monthly"
+	BillingAnnually BillingCycle = "annually"
+)
+
+// SubscriptionStatus is where a tutor's subscription is in its lifecycle
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive     SubscriptionStatus = "active"
+	SubscriptionCanceled   SubscriptionStatus = "canceled"
+	SubscriptionPastDue    SubscriptionStatus = "past_due"
+	SubscriptionTrialing   SubscriptionStatus = "trialing"
+	SubscriptionIncomplete SubscriptionStatus = "incomplete"
+)
+
+// Subscription is a tutor's subscription with the plan terms it was sold on
+type Subscription struct {
+	ID                 uint               `json:"id"`
+	TutorID            uint               `json:"tutor_id"`
+	PlanName           string             `json:"plan_name"`
+	Status             SubscriptionStatus `json:"status"`
+	CurrentPeriodStart time.Time          `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time          `json:"current_period_end"`
+	CancelAtPeriodEnd  bool               `json:"cancel_at_period_end"`
+	BillingCycle       BillingCycle       `json:"billing_cycle"`
+	Price              float64            `json:"price"`
+	Features           []string           `json:"features"`
+	MaxCourses         int                `json:"max_courses"`
+	CommissionRate     float64            `json:"commission_rate"`
+	PaymentOrderID     string             `json:"payment_order_id,omitempty"`
+	// FeaturesEnabled is false once a subscription has ended or been past_due beyond its grace period
+	FeaturesEnabled bool       `json:"features_enabled"`
+	PastDueSince    *time.Time `json:"past_due_since,omitempty"`
+	DunningAttempts int        `json:"dunning_attempts,omitempty"`
+	NextDunningAt   *time.Time `json:"next_dunning_at,omitempty"`
+	TrialEndsAt     *time.Time `json:"trial_ends_at,omitempty"`
+	// Discount is what CouponCode takes off Price, at checkout or for the next renewal
+	CouponCode string  `json:"coupon_code,omitempty"`
+	Discount   float64 `json:"discount,omitempty"`
+	// AmountDue is set at checkout: what the payment for PaymentOrderID has to be, Price less
+	// Discount, or nothing when the subscription starts with a free trial
+	AmountDue *float64 `json:"amount_due,omitempty"`
+	// PlanChange is set in the response to a plan change
+	PlanChange *PlanChange `json:"plan_change,omitempty"`
+}
+
+// PlanChangeQuote prices moving a subscription to another plan or billing cycle. Keeping the
+// billing cycle keeps the current period, changing it starts a new one now. The unused part of
+// the current period is credited against the charge less any coupon discount, and credit left
+// over extends the new period.
+type PlanChangeQuote struct {
+	SubscriptionID   uint         `json:"subscription_id"`
+	FromPlanID       uint         `json:"from_plan_id"`
+	ToPlanID         uint         `json:"to_plan_id"`
+	ToPlanName       string       `json:"to_plan_name"`
+	FromBillingCycle BillingCycle `json:"from_billing_cycle"`
+	ToBillingCycle   BillingCycle `json:"to_billing_cycle"`
+	CouponCode       string       `json:"coupon_code,omitempty"`
+	Credit           float64      `json:"credit"`
+	Charge           float64      `json:"charge"`
+	Discount         float64      `json:"discount"`
+	AmountDue        float64      `json:"amount_due"`
+	PeriodStart      time.Time    `json:"period_start"`
+	PeriodEnd        time.Time    `json:"period_end"`
+}
+
+// PlanChangeStatus is where a started plan change is
+type PlanChangeStatus string
+
+const (
+	PlanChangePending    PlanChangeStatus = "pending"
+	PlanChangeApplied    PlanChangeStatus = "applied"
+	PlanChangeSuperseded PlanChangeStatus = "superseded"
+)
+
+// PlanChange is a started plan change. It applies once its payment is confirmed, or straight
+// away when nothing is due.
+type PlanChange struct {
+	PlanChangeQuote
+	Status         PlanChangeStatus `json:"status"`
+	PaymentOrderID string           `json:"payment_order_id,omitempty"`
+	PaymentURL     string           `json:"payment_url,omitempty"`
+}
+
+// SubscriptionEvent is one step in a subscription's history, such as a renewal or a dunning retry
+type SubscriptionEvent struct {
+	ID             uint               `json:"id"`
+	SubscriptionID uint               `json:"subscription_id"`
+	EventType      string             `json:"event_type"`
+	PreviousStatus SubscriptionStatus `json:"previous_status"`
+	CurrentStatus  SubscriptionStatus `json:"current_status"`
+	Notes          string             `json:"notes"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+// SubscriptionRequest starts a subscription, which stays incomplete until its payment is confirmed
+type SubscriptionRequest struct {
+	TutorID      uint         `json:"tutor_id" validate:"required"`
+	PlanID       uint         `json:"plan_id" validate:"required"`
+	BillingCycle BillingCycle `json:"billing_cycle" validate:"required,oneof=monthly annually"`
+	CouponCode   string       `json:"coupon_code"`
+}
+
+// ChangePlanRequest moves a subscription to another plan or billing cycle. Without a coupon
+// code the subscription's own coupon carries over when it covers the new plan.
+type ChangePlanRequest struct {
+	NewPlanID    uint         `json:"new_plan_id" validate:"required"`
+	BillingCycle BillingCycle `json:"billing_cycle" validate:"required,oneof=monthly annually"`
+	CouponCode   string       `json:"coupon_code"`
+}
+
+// PaymentConfirmationRequest reports that the payment for a subscription went through
+type PaymentConfirmationRequest struct {
+	OrderID   string `json:"order_id" validate:"required"`
+	PaymentID string `json:"payment_id"`
+	PayerID   string `json:"payer_id"`
+}
+
+// SubscriptionStatusUpdateRequest is an admin overriding a subscription's status
+type SubscriptionStatusUpdateRequest struct {
+	Status SubscriptionStatus `json:"status" validate:"required,oneof=active canceled past_due trialing incomplete"`
+}
@@ -0,0 +1,89 @@
+package contracts
+
+import "time"
+
+// Role is a user's role, stored as the User.role enum
+type Role string
+
+const (
+	RoleParent   Role = "Parent"
+	RoleChildren Role = "Children"
+	RoleTutor    Role = "Tutor"
+	RoleAdmin    Role = "Admin"
+)
+
+// Status is an account's state, stored as the User.status enum
+type Status string
+
+const (
+	StatusActive    Status = "Active"
+	StatusSuspended Status = "Suspended"
+	StatusBanned    Status = "Banned"
+	StatusDeleted   Status = "Deleted"
+)
+
+// User is an account as user-service returns it to other services. ID and the timestamps keep
+// the capitalised names gorm.Model has always put on the wire.
+type User struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	Username   string  `json:"username"`
+	Email      string  `json:"email"`
+	Role       Role    `json:"role"`
+	Phone      *string `json:"phone"`
+	FullName   *string `json:"full_name"`
+	Picture    string  `json:"picture"`
+	IsVerified bool    `json:"is_verified"`
+	Status     Status  `json:"status"`
+
+	LastLoginAt         *int64 `json:"last_login_at,omitempty"`
+	AccountLocked       bool   `json:"account_locked"`
+	FailedLoginAttempts int    `json:"failed_login_attempts"`
+	LockedUntil         *int64 `json:"locked_until,omitempty"`
+	PasswordChangedAt   *int64 `json:"password_changed_at"`
+	TwoFactorEnabled    bool   `json:"two_factor_enabled"`
+}
+
+// NewUser is what user-service needs to create an account
+type NewUser struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+	Role     Role   `json:"role"`
+	FullName string `json:"fullname"`
+	Phone    string `json:"phone"`
+	Picture  string `json:"picture"`
+}
+
+// UserUpdate changes an account's profile, empty fields are left as they are
+type UserUpdate struct {
+	Email    string `json:"email" validate:"omitempty,email"`
+	FullName string `json:"full_name" validate:"omitempty,min=2,max=100"`
+	Phone    string `json:"phone"`
+	Picture  string `json:"picture"`
+	Status   string `json:"status"`
+}
+
+// Tutor is the tutor profile attached to a Tutor account
+type Tutor struct {
+	ID             uint             `json:"id"`
+	Bio            string           `json:"bio"`
+	Qualifications string           `json:"qualifications"`
+	TeachingStyle  string           `json:"teaching_style"`
+	IsAvailable    bool             `json:"is_available"`
+	DemoVideoURL   *string          `json:"demo_video_url"`
+	Image          *string          `json:"image"`
+	Specialties    []TutorSpecialty `json:"specialties,omitempty"`
+}
+
+// TutorSpecialty is one subject and level a tutor teaches
+type TutorSpecialty struct {
+	ID              uint   `json:"id"`
+	Subject         string `json:"subject"`
+	Level           string `json:"level"`
+	Certification   string `json:"certification"`
+	YearsExperience int    `json:"years_experience"`
+	TutorID         uint   `json:"tutor_id"`
+}
@@ -0,0 +1,25 @@
+package health
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the connection pool behind db
+func Database(db *gorm.DB) Check {
+	return Check{
+		Name: "mysql",
+		Run: func(ctx context.Context) error {
+			if db == nil {
+				return errors.New("not connected")
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
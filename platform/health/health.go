@@ -0,0 +1,172 @@
+// Package health answers /livez and /readyz. Services pass in the checks that make them
+// ready, readiness fails for all of them once shutdown begins.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// CheckTimeout bounds every dependency check, so one hung dependency cannot hold up /readyz
+const CheckTimeout = 2 * time.Second
+
+const (
+	StatusOK          = "ok"
+	StatusDown        = "down"
+	StatusUnavailable = "unavailable"
+)
+
+// StatusDraining is what /readyz reports once shutdown has begun
+const StatusDraining = "draining"
+
+var draining atomic.Bool
+
+// Drain makes /readyz fail from now on, so load balancers and the gateway stop sending
+// traffic before the listener closes. /livez is unaffected.
+func Drain() {
+	draining.Store(true)
+}
+
+// Check is one dependency readiness depends on
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the /readyz body. Status is ok only when every check passed.
+type Report struct {
+	Status  string            `json:"status"`
+	Service string            `json:"service"`
+	Checks  map[string]Result `json:"checks"`
+}
+
+// Run executes the checks concurrently, each under CheckTimeout
+func Run(ctx context.Context, service string, checks []Check) Report {
+	report := Report{
+		Status:  StatusOK,
+		Service: service,
+		Checks:  make(map[string]Result, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := Result{Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// Livez only says the process is serving requests, it never looks at dependencies
+func Livez(service string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  StatusOK,
+			"service": service,
+		})
+	}
+}
+
+// Readyz answers 200 when every dependency is usable and 503 otherwise, with the per
+// check results in both cases
+func Readyz(service string, checks ...Check) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if draining.Load() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(Report{
+				Status:  StatusDraining,
+				Service: service,
+				Checks:  map[string]Result{},
+			})
+		}
+		report := Run(c.UserContext(), service, checks)
+		status := fiber.StatusOK
+		if report.Status != StatusOK {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(report)
+	}
+}
+
+// HTTP checks that a GET on url answers without a server error. Point it at another
+// service's /livez rather than /readyz, so one outage does not cascade through every caller.
+func HTTP(name, url string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+
+			req.SetRequestURI(url)
+			req.Header.SetMethod(fasthttp.MethodGet)
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				deadline = time.Now().Add(CheckTimeout)
+			}
+			if err := fasthttp.DoDeadline(req, resp, deadline); err != nil {
+				return err
+			}
+			if resp.StatusCode() >= fasthttp.StatusInternalServerError {
+				return fmt.Errorf("status %d", resp.StatusCode())
+			}
+			return nil
+		},
+	}
+}
+
+// Configured fails while any of the named settings is empty. Keys are the environment
+// variable names, so the error says what to set.
+func Configured(name string, settings map[string]string) Check {
+	return Check{
+		Name: name,
+		Run: func(context.Context) error {
+			var missing []string
+			for key, value := range settings {
+				if value == "" {
+					missing = append(missing, key)
+				}
+			}
+			if len(missing) > 0 {
+				sort.Strings(missing)
+				return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
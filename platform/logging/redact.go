@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Keys are compared with separators removed, so "new_password", "refreshToken" and "API_KEY"
+// all match. A bare "code" is only a secret on its own, "status_code" or "zip_code" are not.
+var sensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie",
+	"apikey", "otp", "cardnumber", "cvv", "verificationcode", "recoverycode",
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	normalized := strings.NewReplacer("_", "", "-", "", ".", "").Replace(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(normalized, s) {
+			return true
+		}
+	}
+	return key == "code" || key == "pwd"
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+[a-z0-9._~+/=-]+`)
+	jwtPattern    = regexp.MustCompile(`eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`)
+	// key=value and "key": "value" pairs inside free text, such as URLs and JSON bodies
+	keyValuePattern = regexp.MustCompile(`(?i)((?:\b(?:[a-z0-9]+[_-])*(?:password|passwd|pwd|secret|[a-z]*token|authorization|cookie|api_?key|card_?number|cvv|otp)|\bcode)["']?)(\s*[=:]\s*)("[^"]*"|'[^']*'|[^"'&\s,}]+)`)
+	cardPattern     = regexp.MustCompile(`\b(?:\d[ -]?){12,18}(\d{4})\b`)
+	emailPattern    = regexp.MustCompile(`([a-zA-Z0-9._%+-])[a-zA-Z0-9._%+-]*(@|%40)([a-zA-Z0-9.-]+\.[a-zA-Z]{2,})`)
+	// A 4 to 8 digit number right after "code" or "otp" in a sentence, URL encoded or not
+	codePattern = regexp.MustCompile(`(?i)\b(otp|code)((?:\s|\+|%20)+(?:is(?:\s|\+|%20)+)?)(\d{4,8})\b`)
+)
+
+// RedactString masks secrets inside free text: bearer tokens, JWTs, key=value pairs with sensitive
+// keys, card numbers (all but the last four digits) and the local part of email addresses
+func RedactString(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = keyValuePattern.ReplaceAllString(s, "${1}${2}"+redacted)
+	s = cardPattern.ReplaceAllString(s, "****${1}")
+	s = codePattern.ReplaceAllString(s, "${1}${2}"+redacted)
+	s = emailPattern.ReplaceAllString(s, "${1}***${2}${3}")
+	return s
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]any, len(group))
+		for i, member := range group {
+			attrs[i] = redactAttr(member)
+		}
+		return slog.Group(a.Key, attrs...)
+	case slog.KindAny:
+		return slog.Any(a.Key, redactAny(v.Any()))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactAny walks structs and maps through their JSON form, so fields like `json:"password"`
+// are masked the same way attribute keys are
+func redactAny(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return RedactString(v.Error())
+	case fmt.Stringer:
+		return RedactString(v.String())
+	case []byte:
+		return RedactString(string(v))
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return RedactString(fmt.Sprint(value))
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return RedactString(string(raw))
+	}
+	return redactJSON(decoded)
+}
+
+func redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if isSensitiveKey(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(member)
+		}
+		return v
+	case []any:
+		for i, member := range v {
+			v[i] = redactJSON(member)
+		}
+		return v
+	case string:
+		return RedactString(v)
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const usage = "usage: migrate up [n] | down [n] | status | force <version>"
+
+// Command runs the migrate subcommand, args being what follows "migrate" on the command line.
+// up applies everything pending unless given a count, down reverts one migration unless given a count.
+func Command(db *gorm.DB, service string, fsys fs.FS, args []string) error {
+	migrator, err := New(db, service, fsys)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		limit, err := count(args, 0)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(limit)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: applied %d migration(s)\n", service, applied)
+	case "down":
+		limit, err := count(args, 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(limit)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: reverted %d migration(s)\n", service, reverted)
+	case "force":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("version must be a number: %w", err)
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+		fmt.Printf("%s: forced to version %d\n", service, version)
+	case "status":
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		state := "clean"
+		if dirty {
+			state = "dirty"
+		}
+		fmt.Printf("%s: version %d of %d, %s\n", service, version, migrator.Latest(), state)
+		for _, migration := range migrator.Migrations() {
+			mark := "pending"
+			if migration.Version <= version {
+				mark = "applied"
+			}
+			fmt.Printf("  %04d  %-8s %s\n", migration.Version, mark, migration.Name)
+		}
+	default:
+		return errors.New(usage)
+	}
+	return nil
+}
+
+func count(args []string, fallback int) (int, error) {
+	if len(args) < 2 {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("count must be a positive number, got %q", args[1])
+	}
+	return n, nil
+}
+
+// OnStart applies pending migrations when MIGRATE_ON_START=true, then refuses to go on
+// unless the schema is exactly at the version this build expects
+func OnStart(db *gorm.DB, service string, fsys fs.FS) error {
+	migrator, err := New(db, service, fsys)
+	if err != nil {
+		return err
+	}
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		applied, err := migrator.Up(0)
+		if err != nil {
+			return err
+		}
+		if applied > 0 {
+			slog.Info("Schema migrated", "service", service, "applied", applied, "version", migrator.Latest())
+		}
+	}
+	return migrator.Check()
+}
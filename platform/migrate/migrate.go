@@ -0,0 +1,319 @@
+// Package migrate applies a service's numbered SQL migrations, from the embedded files the
+// service passes in, and backs its migrate subcommand.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LockTimeout bounds how long a replica waits while another one is migrating the same service
+const LockTimeout = 60 * time.Second
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change with the script that reverts it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies a service's migrations and records its version in schema_migrations,
+// one row per service since they all share the database
+type Migrator struct {
+	db         *gorm.DB
+	service    string
+	migrations []Migration
+}
+
+// New loads the NNNN_name.up.sql and NNNN_name.down.sql pairs at the root of fsys.
+// Versions must start at 1 and have no gaps.
+func New(db *gorm.DB, service string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, service: service, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s is not named NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must run 1..n without gaps, found %d at position %d", migration.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrations lists what this build knows about, oldest first
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest is the version this build expects the schema to be at
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version reports the recorded version and whether the last migration stopped halfway
+func (m *Migrator) Version() (version int, dirty bool, err error) {
+	ctx := context.Background()
+	conn, err := m.conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+	return m.version(ctx, conn)
+}
+
+// Check refuses a schema this build was not written against: a dirty one, one with
+// pending migrations, and one migrated by a newer build
+func (m *Migrator) Check() error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("schema of %s is dirty at version %d, repair it by hand and run migrate force", m.service, version)
+	case version < m.Latest():
+		return fmt.Errorf("schema of %s is at version %d but this build expects %d, run migrate up", m.service, version, m.Latest())
+	case version > m.Latest():
+		return fmt.Errorf("schema of %s is at version %d, newer than the %d this build knows about", m.service, version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies up to limit pending migrations, all of them when limit is 0
+func (m *Migrator) Up(limit int) (int, error) {
+	applied := 0
+	err := m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		version, dirty, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("schema of %s is dirty at version %d", m.service, version)
+		}
+		if version > m.Latest() {
+			return fmt.Errorf("schema of %s is at version %d, newer than the %d this build knows about", m.service, version, m.Latest())
+		}
+
+		for _, migration := range m.migrations[version:] {
+			if limit > 0 && applied == limit {
+				break
+			}
+			if err := m.setVersion(ctx, conn, migration.Version, true); err != nil {
+				return err
+			}
+			if err := run(ctx, conn, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s failed, schema left dirty: %w", migration.Version, migration.Name, err)
+			}
+			if err := m.setVersion(ctx, conn, migration.Version, false); err != nil {
+				return err
+			}
+			slog.Info("Applied migration", "service", m.service, "version", migration.Version, "name", migration.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last limit applied migrations
+func (m *Migrator) Down(limit int) (int, error) {
+	reverted := 0
+	err := m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		version, dirty, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("schema of %s is dirty at version %d", m.service, version)
+		}
+		if version > m.Latest() {
+			return fmt.Errorf("schema of %s is at version %d, this build cannot revert what it does not know", m.service, version)
+		}
+
+		for ; version > 0 && reverted < limit; version-- {
+			migration := m.migrations[version-1]
+			if err := m.setVersion(ctx, conn, version, true); err != nil {
+				return err
+			}
+			if err := run(ctx, conn, migration.Down); err != nil {
+				return fmt.Errorf("reverting %d_%s failed, schema left dirty: %w", migration.Version, migration.Name, err)
+			}
+			if err := m.setVersion(ctx, conn, version-1, false); err != nil {
+				return err
+			}
+			slog.Info("Reverted migration", "service", m.service, "version", migration.Version, "name", migration.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as cleanly applied without running anything, for after a failed
+// migration has been repaired by hand
+func (m *Migrator) Force(version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("version %d is outside 0..%d", version, m.Latest())
+	}
+	return m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		return m.setVersion(ctx, conn, version, false)
+	})
+}
+
+// conn pins one connection, since the advisory lock belongs to the session that took it
+func (m *Migrator) conn(ctx context.Context) (*sql.Conn, error) {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return conn, nil
+}
+
+func (m *Migrator) locked(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lock := "schema_migrations:" + m.service
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lock, int(LockTimeout.Seconds())).Scan(&acquired); err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("timed out waiting for another %s to finish migrating", m.service)
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lock)
+
+	return fn(ctx, conn)
+}
+
+const createTable = "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+	"`service` VARCHAR(64) NOT NULL, " +
+	"`version` INTEGER NOT NULL, " +
+	"`dirty` BOOLEAN NOT NULL DEFAULT false, " +
+	"`applied_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3), " +
+	"PRIMARY KEY (`service`)" +
+	") DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"
+
+func (m *Migrator) version(ctx context.Context, conn *sql.Conn) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := conn.QueryRowContext(ctx, "SELECT `version`, `dirty` FROM `schema_migrations` WHERE `service` = ?", m.service).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (m *Migrator) setVersion(ctx context.Context, conn *sql.Conn, version int, dirty bool) error {
+	_, err := conn.ExecContext(ctx,
+		"INSERT INTO `schema_migrations` (`service`, `version`, `dirty`) VALUES (?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `version` = VALUES(`version`), `dirty` = VALUES(`dirty`), `applied_at` = CURRENT_TIMESTAMP(3)",
+		m.service, version, dirty)
+	return err
+}
+
+// run executes a script one statement at a time. MySQL commits DDL implicitly, so a
+// transaction would not make a failed migration roll back, the dirty flag covers that instead.
+func run(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range statements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statements splits a script on semicolons that end a line and drops -- comments
+func statements(script string) []string {
+	var result []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
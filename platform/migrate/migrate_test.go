@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func files(names ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range names {
+		fsys[name] = &fstest.MapFile{Data: []byte("SELECT 1;\n")}
+	}
+	return fsys
+}
+
+func TestLoad(t *testing.T) {
+	fsys := files(
+		"0002_add_index.down.sql", "0002_add_index.up.sql",
+		"0001_create_users.up.sql", "0001_create_users.down.sql",
+	)
+	fsys["README.md"] = &fstest.MapFile{Data: []byte("not a migration")}
+	fsys["archive/0003_old.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+	migrations, err := load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[0].Name != "create_users" ||
+		migrations[1].Version != 2 || migrations[1].Name != "add_index" {
+		t.Fatalf("loaded %+v, want create_users then add_index", migrations)
+	}
+	if migrations[0].Up == "" || migrations[0].Down == "" {
+		t.Errorf("migration 1 up %q down %q, want both scripts", migrations[0].Up, migrations[0].Down)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		fsys  fstest.MapFS
+		error string
+	}{
+		{"a gap", files("0001_a.up.sql", "0001_a.down.sql", "0003_c.up.sql", "0003_c.down.sql"), "without gaps"},
+		{"not starting at 1", files("0002_b.up.sql", "0002_b.down.sql"), "without gaps"},
+		{"an up without a down", files("0001_a.up.sql"), "both an up and a down"},
+		{"a down without an up", files("0001_a.down.sql"), "both an up and a down"},
+		{"two names for a version", files("0001_a.up.sql", "0001_b.down.sql"), "two names"},
+		{"a badly named file", files("1-create.sql"), "is not named"},
+		{"an empty script", fstest.MapFS{
+			"0001_a.up.sql":   &fstest.MapFile{Data: []byte("SELECT 1;")},
+			"0001_a.down.sql": &fstest.MapFile{Data: []byte("  \n")},
+		}, "both an up and a down"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(tc.fsys)
+			if err == nil || !strings.Contains(err.Error(), tc.error) {
+				t.Errorf("error %v, want one about %q", err, tc.error)
+			}
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- Adds the table
+CREATE TABLE t (
+    id INTEGER NOT NULL, -- the key
+    note TEXT
+);
+
+-- and an index
+CREATE INDEX t_note ON t (note(10));
+INSERT INTO t VALUES (1, 'a;b')`
+
+	want := []string{
+		"CREATE TABLE t (\n    id INTEGER NOT NULL, -- the key\n    note TEXT\n)",
+		"CREATE INDEX t_note ON t (note(10))",
+		"INSERT INTO t VALUES (1, 'a;b')",
+	}
+	if got := statements(script); !slices.Equal(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestStatementsOfAnEmptyScript(t *testing.T) {
+	if got := statements("-- nothing to do\n\n"); len(got) != 0 {
+		t.Errorf("statements = %q, want none", got)
+	}
+}
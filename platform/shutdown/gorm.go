@@ -0,0 +1,24 @@
+package shutdown
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// CloseDB closes the connection pool behind db once requests have drained
+func CloseDB(db *gorm.DB) Step {
+	return Step{
+		Name: "mysql",
+		Run: func(context.Context) error {
+			if db == nil {
+				return nil
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	}
+}
@@ -0,0 +1,91 @@
+// Package shutdown runs a service's server until SIGTERM, then drains it and runs the
+// cleanup steps the service passes in.
+package shutdown
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"platform/health"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Step is one piece of cleanup run after the server has drained, such as stopping a
+// background worker or closing a connection pool
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Func wraps a cleanup that cannot fail and does not need the deadline
+func Func(name string, fn func()) Step {
+	return Step{
+		Name: name,
+		Run: func(context.Context) error {
+			fn()
+			return nil
+		},
+	}
+}
+
+// Serve runs app on addr until SIGINT or SIGTERM. It then fails readiness, waits
+// SHUTDOWN_DRAIN_DELAY seconds (default 3) for callers to notice, stops accepting
+// connections and gives in-flight requests up to SHUTDOWN_TIMEOUT seconds (default 20)
+// to finish. The steps run last, in order, within what is left of that deadline.
+// A second signal exits at once.
+func Serve(app *fiber.App, addr string, steps ...Step) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-listenErr:
+		return err
+	case sig := <-signals:
+		slog.Info("Shutting down", "signal", sig.String())
+	}
+	go func() {
+		<-signals
+		slog.Warn("Second signal received, exiting without draining")
+		os.Exit(1)
+	}()
+
+	health.Drain()
+	time.Sleep(seconds("SHUTDOWN_DRAIN_DELAY", 3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), seconds("SHUTDOWN_TIMEOUT", 20))
+	defer cancel()
+
+	start := time.Now()
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		slog.Error("In-flight requests did not finish before the deadline", "error", err)
+	} else {
+		slog.Info("In-flight requests drained", "duration_ms", time.Since(start).Milliseconds())
+	}
+
+	for _, step := range steps {
+		if err := step.Run(ctx); err != nil {
+			slog.Error("Shutdown step failed", "step", step.Name, "error", err)
+		}
+	}
+	slog.Info("Shutdown complete")
+	return nil
+}
+
+func seconds(key string, fallback int) time.Duration {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		value = fallback
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,20 @@
+package validation
+
+import (
+	"platform/contracts"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// registerRules adds the checks on shared contracts that span fields, which a tag cannot say
+func registerRules(v *validator.Validate) {
+	v.RegisterStructValidation(couponRequest, contracts.CouponRequest{})
+}
+
+// couponRequest caps a percent coupon at 100 percent off
+func couponRequest(sl validator.StructLevel) {
+	req := sl.Current().Interface().(contracts.CouponRequest)
+	if req.DiscountType == contracts.CouponPercent && req.Amount > 100 {
+		sl.ReportError(req.Amount, "amount", "Amount", "lte", "100")
+	}
+}
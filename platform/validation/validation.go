@@ -0,0 +1,138 @@
+// Package validation decodes request bodies and enforces their validate struct tags, so every
+// service rejects a bad body with the same 422 payload.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Errors name fields the way the client sent them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	registerRules(v)
+	return v
+}
+
+// FieldError is one field that broke one of its rules
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is the payload sent back for a body that is rejected
+type Error struct {
+	Status  int          `json:"-"`
+	Message string       `json:"error"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Send answers the request with the error
+func (e *Error) Send(c *fiber.Ctx) error {
+	return c.Status(e.Status).JSON(e)
+}
+
+// ParseBody decodes the request body into out and checks out's validate tags. A body that
+// does not decode is a 400, one that decodes but breaks a rule a 422 listing every field.
+func ParseBody(c *fiber.Ctx, out any) *Error {
+	if err := c.BodyParser(out); err != nil {
+		return &Error{Status: fiber.StatusBadRequest, Message: "Invalid request body"}
+	}
+	return Struct(out)
+}
+
+// Struct checks v's validate tags, for bodies decoded some other way
+func Struct(v any) *Error {
+	return check(validate.Struct(v))
+}
+
+// StructExcept checks v's validate tags, skipping the named Go fields
+func StructExcept(v any, fields ...string) *Error {
+	return check(validate.StructExcept(v, fields...))
+}
+
+// Missing is the 422 for a field a handler requires only in some cases, which a tag cannot say
+func Missing(field string) *Error {
+	return &Error{
+		Status:  fiber.StatusUnprocessableEntity,
+		Message: "Validation failed",
+		Fields:  []FieldError{{Field: field, Rule: "required", Message: field + " is required"}},
+	}
+}
+
+func check(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return &Error{Status: fiber.StatusInternalServerError, Message: err.Error()}
+	}
+
+	fields := make([]FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		// The namespace starts with the Go type name, which means nothing to the client
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: message(field, fe),
+		})
+	}
+	return &Error{Status: fiber.StatusUnprocessableEntity, Message: "Validation failed", Fields: fields}
+}
+
+func message(field string, fe validator.FieldError) string {
+	text := fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "min":
+		if text {
+			return fmt.Sprintf("%s must be at least %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		if text {
+			return fmt.Sprintf("%s must be at most %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "gte":
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", field, fe.Param())
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	}
+	return fmt.Sprintf("%s failed the %s rule", field, fe.Tag())
+}
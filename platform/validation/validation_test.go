@@ -0,0 +1,39 @@
+package validation
+
+import (
+	"platform/contracts"
+	"testing"
+)
+
+func TestCouponRequestAmount(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		kind    contracts.CouponDiscountType
+		amount  float64
+		wantErr bool
+	}{
+		{"percent up to 100", contracts.CouponPercent, 100, false},
+		{"percent over 100", contracts.CouponPercent, 150, true},
+		{"fixed over 100", contracts.CouponFixed, 150, false},
+		{"zero", contracts.CouponFixed, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := contracts.CouponRequest{Code: "SPRING", DiscountType: tc.kind, Amount: tc.amount, Duration: contracts.CouponOnce}
+			verr := Struct(&req)
+			if (verr != nil) != tc.wantErr {
+				t.Fatalf("Struct = %v, want an error %v", verr, tc.wantErr)
+			}
+			if verr != nil && (len(verr.Fields) != 1 || verr.Fields[0].Field != "amount") {
+				t.Errorf("fields %+v, want only amount", verr.Fields)
+			}
+		})
+	}
+}
+
+func TestPercentCouponMessage(t *testing.T) {
+	req := contracts.CouponRequest{Code: "SPRING", DiscountType: contracts.CouponPercent, Amount: 150, Duration: contracts.CouponOnce}
+	verr := Struct(&req)
+	if verr == nil || verr.Status != 422 || verr.Fields[0].Rule != "lte" || verr.Fields[0].Message != "amount must be at most 100" {
+		t.Errorf("Struct = %+v, want a 422 saying amount must be at most 100", verr)
+	}
+}
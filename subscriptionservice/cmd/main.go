@@ -1,18 +1,27 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/migrate"
+	"platform/shutdown"
+	"platform/tracing"
 	"subscription/internal/config"
+	"subscription/internal/dunning"
 	"subscription/internal/middleware"
 	"subscription/internal/repository"
 	"subscription/internal/routes"
+	"subscription/internal/scheduler"
 	"subscription/internal/services"
+	"subscription/migrations"
 	"subscription/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 func init() {
@@ -20,6 +29,25 @@ func init() {
 }
 
 func main() {
+	logging.Init("subscription-service")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(repository.DB, "subscription-service", migrations.FS, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrate.OnStart(repository.DB, "subscription-service", migrations.FS); err != nil {
+		slog.Error("Refusing to start against this schema", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Init("subscription-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
+	}
+
 	cfg := config.New()
 
 	// Initialize database connection
@@ -28,11 +56,20 @@ func main() {
 	// Setup repositories
 	planRepo := repository.NewPlanRepository(db)
 	subscriptionRepo := repository.NewSubscriptionRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
 
 	// Setup services
 	paymentService := services.NewPaymentService(cfg.PaymentServiceURL, cfg.APIKey)
 	planService := services.NewPlanService(planRepo)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepo, planRepo, paymentService)
+	couponService := services.NewCouponService(couponRepo)
+	notificationService := services.NewNotificationService(cfg.UserServiceURL, cfg.GoogleServiceURL, cfg.APIKey)
+	policy := dunning.NewPolicy(cfg.Dunning)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, planRepo, couponRepo, paymentService, policy)
+
+	// Every replica runs the scheduler, the lock in the database lets one sweep at a time
+	renewals := scheduler.NewScheduler(subscriptionRepo, planRepo, paymentService, notificationService,
+		repository.NewLocker(db), cfg.Renewal, policy)
+	renewals.Start()
 
 	// Initialize the app
 	app := fiber.New(fiber.Config{
@@ -50,14 +87,22 @@ func main() {
 	})
 
 	// Middleware
+	app.Use(tracing.Middleware("subscription-service"), logging.Middleware(), metrics.Middleware())
 	app.Use(cors.New())
-	app.Use(logger.New())
+
+	app.Get("/livez", health.Livez("subscription-service"))
+	// payment-service is a Spring app, its /health is the liveness endpoint
+	app.Get("/readyz", health.Readyz("subscription-service",
+		health.Database(db),
+		health.HTTP("payment-service", cfg.PaymentServiceURL+"/health"),
+	))
+	app.Get("/metrics", metrics.Handler())
 
 	// Setup API router group with middleware
 	api := app.Group("/api", middleware.Middleware(cfg.APIKey))
 
 	// Setup all routes
-	routes.SetupRoutes(api, planService, subscriptionService)
+	routes.SetupRoutes(api, planService, couponService, subscriptionService)
 
 	// Start the server
 	port := os.Getenv("PORT")
@@ -65,8 +110,13 @@ func main() {
 		port = "8086" // Default port for subscription service
 	}
 
-	log.Printf("Starting subscription service on port %s", port)
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	slog.Info("Starting subscription service", "port", port)
+	if err := shutdown.Serve(app, ":"+port,
+		shutdown.Step{Name: "subscription scheduler", Run: renewals.Stop},
+		shutdown.CloseDB(db),
+		shutdown.Step{Name: "tracing", Run: shutdownTracing},
+	); err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
 	}
 }
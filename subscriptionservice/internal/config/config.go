@@ -1,8 +1,11 @@
 package config
 
 import (
+	"log/slog"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +14,34 @@ type Config struct {
 	APIKey            string
 	PaymentServiceURL string
 	UserServiceURL    string
+	GoogleServiceURL  string
+	Renewal           RenewalConfig
+	Dunning           DunningConfig
+}
+
+// RenewalConfig drives the scheduler that ends, renews and collects on subscriptions
+type RenewalConfig struct {
+	// Interval is how often a sweep runs, 0 turns the scheduler off
+	Interval time.Duration
+	// Grace is how long a started renewal payment may stay unpaid before the subscription goes past_due
+	Grace time.Duration
+	// BatchSize caps the subscriptions handled per step of one sweep
+	BatchSize int
+	// TrialReminder is how long before a free trial ends the tutor is emailed, 0 sends no reminder
+	TrialReminder time.Duration
+}
+
+// DunningConfig is what happens to a past_due subscription until it is paid or given up on
+type DunningConfig struct {
+	// RetryAfter lists when, counted from going past_due, a new payment is asked for and the
+	// tutor is reminded. Steps at or after Grace are dropped.
+	RetryAfter []time.Duration
+	// Grace is how long plan features stay enabled while past_due, FinalAction runs when it ends
+	Grace time.Duration
+	// FinalAction is "cancel" or "downgrade"
+	FinalAction string
+	// DowngradePlanID is the plan "downgrade" moves to, a subscription already on it is canceled
+	DowngradePlanID uint
 }
 
 type ServerConfig struct {
@@ -26,6 +57,22 @@ func New() *Config {
 		APIKey:            os.Getenv("API_KEY"),
 		PaymentServiceURL: os.Getenv("PAYMENT_SERVICE_URL"),
 		UserServiceURL:    os.Getenv("USER_SERVICE_URL"),
+		GoogleServiceURL:  os.Getenv("GOOGLE_SERVICE_URL"),
+		Renewal:           loadRenewalConfig(),
+		Dunning:           loadDunningConfig(),
+	}
+}
+
+func loadRenewalConfig() RenewalConfig {
+	batchSize := getEnvInt("SUBSCRIPTION_SWEEP_BATCH", 100)
+	if batchSize == 0 {
+		batchSize = 100
+	}
+	return RenewalConfig{
+		Interval:      time.Duration(getEnvInt("SUBSCRIPTION_SWEEP_SECONDS", 60)) * time.Second,
+		Grace:         time.Duration(getEnvInt("SUBSCRIPTION_RENEWAL_GRACE_HOURS", 72)) * time.Hour,
+		BatchSize:     batchSize,
+		TrialReminder: time.Duration(getEnvInt("SUBSCRIPTION_TRIAL_REMINDER_DAYS", 3)) * 24 * time.Hour,
 	}
 }
 
@@ -52,6 +99,44 @@ func loadServerConfig() ServerConfig {
 	}
 }
 
+func loadDunningConfig() DunningConfig {
+	grace := time.Duration(getEnvInt("DUNNING_GRACE_DAYS", 7)) * 24 * time.Hour
+
+	var retryAfter []time.Duration
+	for _, field := range strings.Split(getEnvOrDefault("DUNNING_RETRY_DAYS", "1,3,5"), ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || days < 0 {
+			slog.Warn("Ignoring invalid DUNNING_RETRY_DAYS entry", "value", field)
+			continue
+		}
+		after := time.Duration(days) * 24 * time.Hour
+		if after >= grace {
+			slog.Warn("Ignoring dunning retry at or after the grace period", "days", days)
+			continue
+		}
+		retryAfter = append(retryAfter, after)
+	}
+	slices.Sort(retryAfter)
+
+	action := getEnvOrDefault("DUNNING_FINAL_ACTION", "cancel")
+	downgradePlanID := getEnvInt("DUNNING_DOWNGRADE_PLAN_ID", 0)
+	if action != "cancel" && action != "downgrade" {
+		slog.Warn("Unknown DUNNING_FINAL_ACTION, canceling instead", "value", action)
+		action = "cancel"
+	}
+	if action == "downgrade" && downgradePlanID == 0 {
+		slog.Warn("DUNNING_FINAL_ACTION is downgrade but DUNNING_DOWNGRADE_PLAN_ID is not set, canceling instead")
+		action = "cancel"
+	}
+
+	return DunningConfig{
+		RetryAfter:      retryAfter,
+		Grace:           grace,
+		FinalAction:     action,
+		DowngradePlanID: uint(downgradePlanID),
+	}
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -59,3 +144,11 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
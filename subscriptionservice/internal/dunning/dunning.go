@@ -0,0 +1,88 @@
+// Package dunning is the policy for a subscription whose payment failed. It goes past_due,
+// plan features stay on through a grace period, and on each retry step the scheduler asks for
+// a new payment and reminds the tutor. If nothing is paid by the end of the grace period the
+// subscription is downgraded or canceled.
+package dunning
+
+import (
+	"subscription/internal/config"
+	"subscription/internal/models"
+	"time"
+)
+
+const (
+	ActionCancel    = "cancel"
+	ActionDowngrade = "downgrade"
+)
+
+type Policy struct {
+	cfg config.DunningConfig
+}
+
+func NewPolicy(cfg config.DunningConfig) Policy {
+	return Policy{cfg: cfg}
+}
+
+// Start is the columns to set alongside status past_due, beginning dunning at now
+func (p Policy) Start(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"status":           models.SubscriptionPastDue,
+		"past_due_since":   now,
+		"dunning_attempts": 0,
+		"next_dunning_at":  p.stepAt(now, 0),
+	}
+}
+
+// Clear is the columns to set when a subscription leaves past_due
+func Clear() map[string]interface{} {
+	return map[string]interface{}{
+		"past_due_since":   nil,
+		"dunning_attempts": 0,
+		"next_dunning_at":  nil,
+	}
+}
+
+// Retries is how many payment retries the policy makes before its final action
+func (p Policy) Retries() int {
+	return len(p.cfg.RetryAfter)
+}
+
+// Final reports whether a subscription that has had attempts retries is due its final action
+func (p Policy) Final(attempts int) bool {
+	return attempts >= len(p.cfg.RetryAfter)
+}
+
+// NextAt is when the step after the given number of attempts is due
+func (p Policy) NextAt(pastDueSince time.Time, attempts int) time.Time {
+	return p.stepAt(pastDueSince, attempts)
+}
+
+// GraceEnds is when a subscription that went past_due at pastDueSince loses its plan features
+func (p Policy) GraceEnds(pastDueSince time.Time) time.Time {
+	return pastDueSince.Add(p.cfg.Grace)
+}
+
+// FinalAction is ActionCancel or ActionDowngrade, with the plan to downgrade to
+func (p Policy) FinalAction() (string, uint) {
+	return p.cfg.FinalAction, p.cfg.DowngradePlanID
+}
+
+// FeaturesEnabled reports whether the plan's features are available to the tutor at now
+func (p Policy) FeaturesEnabled(subscription *models.TutorSubscription, now time.Time) bool {
+	switch subscription.Status {
+	case models.SubscriptionActive, models.SubscriptionTrialing:
+		return true
+	case models.SubscriptionPastDue:
+		// A row set past_due without going through this policy has no start yet, it keeps its
+		// features until the scheduler gives it one
+		return subscription.PastDueSince == nil || now.Before(p.GraceEnds(*subscription.PastDueSince))
+	}
+	return false
+}
+
+func (p Policy) stepAt(pastDueSince time.Time, attempts int) time.Time {
+	if attempts < len(p.cfg.RetryAfter) {
+		return pastDueSince.Add(p.cfg.RetryAfter[attempts])
+	}
+	return p.GraceEnds(pastDueSince)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"platform/validation"
+	"strconv"
+	"subscription/internal/models"
+	"subscription/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CouponHandler struct {
+	couponService services.CouponService
+}
+
+func NewCouponHandler(couponService services.CouponService) *CouponHandler {
+	return &CouponHandler{
+		couponService: couponService,
+	}
+}
+
+func (h *CouponHandler) HandleGetAllCoupons(c *fiber.Ctx) error {
+	activeOnly := c.Query("active_only", "true") == "true"
+
+	coupons, err := h.couponService.GetAllCoupons(activeOnly)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get coupons: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": coupons,
+	})
+}
+
+func (h *CouponHandler) HandleGetCoupon(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coupon ID",
+		})
+	}
+
+	coupon, err := h.couponService.GetCouponByID(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coupon not found: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": coupon,
+	})
+}
+
+func (h *CouponHandler) HandleCreateCoupon(c *fiber.Ctx) error {
+	var req models.CouponRequest
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
+	}
+
+	// Create the coupon
+	coupon, err := h.couponService.CreateCoupon(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create coupon: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":    coupon,
+		"message": "Coupon created successfully",
+	})
+}
+
+func (h *CouponHandler) HandleUpdateCoupon(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coupon ID",
+		})
+	}
+
+	var req models.CouponRequest
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
+	}
+
+	coupon, err := h.couponService.UpdateCoupon(uint(id), req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update coupon: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    coupon,
+		"message": "Coupon updated successfully",
+	})
+}
+
+func (h *CouponHandler) HandleDeleteCoupon(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coupon ID",
+		})
+	}
+
+	if err := h.couponService.DeleteCoupon(uint(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete coupon: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Coupon deleted successfully",
+	})
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"platform/validation"
 	"strconv"
 	"subscription/internal/models"
 	"subscription/internal/services"
@@ -57,35 +57,8 @@ func (h *PlanHandler) HandleGetPlan(c *fiber.Ctx) error {
 
 func (h *PlanHandler) HandleCreatePlan(c *fiber.Ctx) error {
 	var req models.PlanRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
-	}
-
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Plan name is required",
-		})
-	}
-	fmt.Println(req)
-
-	if req.PriceMonthly <= 0 || req.PriceAnnually <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Plan prices must be greater than 0",
-		})
-	}
-
-	if req.MaxCourses <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Maximum courses must be greater than 0",
-		})
-	}
-
-	if req.CommissionRate < 0 || req.CommissionRate > 100 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Commission rate must be between 0 and 100",
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
 	// Create the plan
@@ -112,34 +85,8 @@ func (h *PlanHandler) HandleUpdatePlan(c *fiber.Ctx) error {
 	}
 
 	var req models.PlanRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
-	}
-
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Plan name is required",
-		})
-	}
-
-	if req.PriceMonthly <= 0 || req.PriceAnnually <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Plan prices must be greater than 0",
-		})
-	}
-
-	if req.MaxCourses <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Maximum courses must be greater than 0",
-		})
-	}
-
-	if req.CommissionRate < 0 || req.CommissionRate > 100 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Commission rate must be between 0 and 100",
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
 	plan, err := h.planService.UpdatePlan(uint(id), req)
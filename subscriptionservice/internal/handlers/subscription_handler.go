@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"platform/contracts"
+	"platform/validation"
 	"strconv"
 	"subscription/internal/models"
 	"subscription/internal/services"
@@ -22,22 +25,8 @@ func NewSubscriptionHandler(subscriptionService services.SubscriptionService) *S
 
 func (h *SubscriptionHandler) HandleCreateSubscription(c *fiber.Ctx) error {
 	var req models.SubscriptionRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
-	}
-
-	if req.PlanID == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Plan ID is required",
-		})
-	}
-
-	if req.BillingCycle != models.BillingMonthly && req.BillingCycle != models.BillingAnnually {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Billing cycle must be either 'monthly' or 'annually'",
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
 	// Create the subscription
@@ -57,21 +46,23 @@ func (h *SubscriptionHandler) HandleCreateSubscription(c *fiber.Ctx) error {
 func (h *SubscriptionHandler) HandleConfirmSubscription(c *fiber.Ctx) error {
 	// Parse request body
 	var req models.PaymentConfirmationRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
-	}
-
-	if req.OrderID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Order ID is required",
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
 	// Confirm the subscription
-	subscription, err := h.subscriptionService.ConfirmSubscription(req)
+	subscription, err := h.subscriptionService.ConfirmSubscription(c.UserContext(), req)
 	if err != nil {
+		if errors.Is(err, services.ErrPaymentOrderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrPaymentNotCompleted) || errors.Is(err, services.ErrPaymentAmountMismatch) {
+			return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to confirm subscription: " + err.Error(),
 		})
@@ -135,34 +126,51 @@ func (h *SubscriptionHandler) HandleChangePlan(c *fiber.Ctx) error {
 	}
 
 	var req models.ChangePlanRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
-	if req.NewPlanID == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "New plan ID is required",
+	updatedSubscription, err := h.subscriptionService.ChangePlan(c.UserContext(), uint(id), req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change subscription plan: " + err.Error(),
 		})
 	}
 
-	if req.BillingCycle != models.BillingMonthly && req.BillingCycle != models.BillingAnnually {
+	message := "Subscription plan change initiated. Please complete payment to activate the new plan."
+	if updatedSubscription.PlanChange.Status == models.PlanChangeApplied {
+		message = "Subscription plan changed successfully"
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    updatedSubscription,
+		"message": message,
+	})
+}
+
+func (h *SubscriptionHandler) HandlePreviewPlanChange(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Billing cycle must be either 'monthly' or 'annually'",
+			"error": "Invalid subscription ID",
 		})
 	}
 
-	updatedSubscription, err := h.subscriptionService.ChangePlan(uint(id), req)
+	var req models.ChangePlanRequest
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
+	}
+
+	quote, err := h.subscriptionService.PreviewPlanChange(uint(id), req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to change subscription plan: " + err.Error(),
+			"error": "Failed to quote plan change: " + err.Error(),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"data":    updatedSubscription,
-		"message": "Subscription plan change initiated. Please complete payment to activate the new plan.",
+		"data": quote,
 	})
 }
 
@@ -212,16 +220,9 @@ func (h *SubscriptionHandler) HandleGetAllSubscriptions(c *fiber.Ctx) error {
 		})
 	}
 
-	totalPages := (int(total) + pageSize - 1) / pageSize
-
-	return c.JSON(fiber.Map{
-		"data": subscriptions,
-		"pagination": fiber.Map{
-			"total":       total,
-			"page":        page,
-			"page_size":   pageSize,
-			"total_pages": totalPages,
-		},
+	return c.JSON(contracts.Page[models.SubscriptionResponse]{
+		Data:       subscriptions,
+		Pagination: contracts.NewPagination(total, page, pageSize),
 	})
 }
 
@@ -235,35 +236,44 @@ func (h *SubscriptionHandler) HandleUpdateSubscriptionStatus(c *fiber.Ctx) error
 	}
 
 	var req models.SubscriptionStatusUpdateRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return verr.Send(c)
 	}
 
-	// Validate status
-	validStatuses := map[models.SubscriptionStatus]bool{
-		models.SubscriptionActive:     true,
-		models.SubscriptionCanceled:   true,
-		models.SubscriptionPastDue:    true,
-		models.SubscriptionTrialing:   true,
-		models.SubscriptionIncomplete: true,
+	if err := h.subscriptionService.UpdateSubscriptionStatus(uint(id), req.Status); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update subscription status: " + err.Error(),
+		})
 	}
 
-	if !validStatuses[req.Status] {
+	return c.JSON(fiber.Map{
+		"message": "Subscription status updated successfully",
+	})
+}
+
+func (h *SubscriptionHandler) HandleGetSubscriptionEvents(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid subscription status",
+			"error": "Invalid subscription ID",
 		})
 	}
 
-	if err := h.subscriptionService.UpdateSubscriptionStatus(uint(id), req.Status); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update subscription status: " + err.Error(),
+	events, err := h.subscriptionService.GetSubscriptionEvents(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Subscription not found: " + err.Error(),
 		})
 	}
 
+	data := make([]contracts.SubscriptionEvent, len(events))
+	for i := range events {
+		data[i] = events[i].Contract()
+	}
+
 	return c.JSON(fiber.Map{
-		"message": "Subscription status updated successfully",
+		"data": data,
 	})
 }
 
@@ -275,7 +285,7 @@ func (h *SubscriptionHandler) HandlePaymentWebhook(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.subscriptionService.ProcessPaymentWebhook(payload); err != nil {
+	if err := h.subscriptionService.ProcessPaymentWebhook(c.UserContext(), payload); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to process payment webhook: " + err.Error(),
 		})
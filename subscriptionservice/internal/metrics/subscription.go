@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var subscriptionsActivated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "subscriptions_activated_total",
+	Help: "Subscriptions moved to active after a confirmed payment, by billing cycle.",
+}, []string{"billing_cycle"})
+
+func RecordSubscriptionActivated(billingCycle string) {
+	subscriptionsActivated.WithLabelValues(billingCycle).Inc()
+}
+
+var trialsStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "subscription_trials_started_total",
+	Help: "Free trials started, by the billing cycle they convert to.",
+}, []string{"billing_cycle"})
+
+func RecordTrialStarted(billingCycle string) {
+	trialsStarted.WithLabelValues(billingCycle).Inc()
+}
+
+var subscriptionTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "subscription_scheduler_transitions_total",
+	Help: "Subscriptions moved on by the renewal scheduler, by event type.",
+}, []string{"event"})
+
+func RecordSchedulerTransition(event string) {
+	subscriptionTransitions.WithLabelValues(event).Inc()
+}
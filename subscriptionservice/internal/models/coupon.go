@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"platform/contracts"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CouponDiscountType = contracts.CouponDiscountType
+
+const (
+	CouponPercent = contracts.CouponPercent
+	CouponFixed   = contracts.CouponFixed
+)
+
+type CouponDuration = contracts.CouponDuration
+
+const (
+	CouponOnce      = contracts.CouponOnce
+	CouponRepeating = contracts.CouponRepeating
+	CouponForever   = contracts.CouponForever
+)
+
+// Coupon is a promotion code that takes a percentage or a fixed amount off a subscription's
+// price for one, several or every billed period
+type Coupon struct {
+	gorm.Model
+	Code           string             `gorm:"size:50;not null;uniqueIndex" json:"code"`
+	Description    string             `gorm:"type:text" json:"description"`
+	DiscountType   CouponDiscountType `gorm:"type:enum('percent','fixed');not null" json:"discount_type"`
+	Amount         float64            `gorm:"type:double;not null" json:"amount"`
+	Duration       CouponDuration     `gorm:"type:enum('once','repeating','forever');not null" json:"duration"`
+	DurationCycles int                `gorm:"not null;default:0" json:"duration_cycles"`
+	PlanIDs        string             `gorm:"type:text" json:"-"`
+	PlanIDsJSON    []uint             `gorm:"-" json:"plan_ids"`
+	MaxRedemptions int                `gorm:"not null;default:0" json:"max_redemptions"` // 0 for no cap
+	TimesRedeemed  int                `gorm:"not null;default:0" json:"times_redeemed"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
+	IsActive       bool               `gorm:"default:true" json:"is_active"`
+}
+
+// TableName specifies the table name for the Coupon model
+func (Coupon) TableName() string {
+	return "Coupons"
+}
+
+// BeforeSave hook converts PlanIDsJSON to the PlanIDs JSON string and uppercases the code
+func (c *Coupon) BeforeSave(tx *gorm.DB) error {
+	c.Code = strings.ToUpper(c.Code)
+	if c.PlanIDsJSON == nil {
+		c.PlanIDsJSON = []uint{}
+	}
+	planIDs, err := json.Marshal(c.PlanIDsJSON)
+	if err != nil {
+		return err
+	}
+	c.PlanIDs = string(planIDs)
+	return nil
+}
+
+// AfterFind hook converts the PlanIDs JSON string to PlanIDsJSON after retrieval
+func (c *Coupon) AfterFind(tx *gorm.DB) error {
+	c.PlanIDsJSON = []uint{}
+	if c.PlanIDs != "" {
+		return json.Unmarshal([]byte(c.PlanIDs), &c.PlanIDsJSON)
+	}
+	return nil
+}
+
+// AppliesTo reports whether the coupon can discount planID
+func (c *Coupon) AppliesTo(planID uint) bool {
+	return len(c.PlanIDsJSON) == 0 || slices.Contains(c.PlanIDsJSON, planID)
+}
+
+// Redeemable checks that the coupon can still be redeemed for planID at now
+func (c *Coupon) Redeemable(planID uint, now time.Time) error {
+	switch {
+	case !c.IsActive:
+		return errors.New("coupon is not active")
+	case c.ExpiresAt != nil && !now.Before(*c.ExpiresAt):
+		return errors.New("coupon has expired")
+	case c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions:
+		return errors.New("coupon has been fully redeemed")
+	case !c.AppliesTo(planID):
+		return errors.New("coupon does not apply to this plan")
+	}
+	return nil
+}
+
+// Discount is what the coupon takes off amount, never more than amount itself
+func (c *Coupon) Discount(amount float64) float64 {
+	discount := c.Amount
+	if c.DiscountType == CouponPercent {
+		discount = amount * c.Amount / 100
+	}
+	return math.Round(math.Min(discount, amount)*100) / 100
+}
+
+// Cycles is how many billed periods a newly redeemed coupon discounts, nil when it lasts forever
+func (c *Coupon) Cycles() *int {
+	cycles := 1
+	switch c.Duration {
+	case CouponForever:
+		return nil
+	case CouponRepeating:
+		cycles = c.DurationCycles
+	}
+	return &cycles
+}
+
+// CouponRequest creates or replaces a coupon
+type CouponRequest = contracts.CouponRequest
+
+// CouponRedemption records a tutor redeeming a coupon, each tutor redeems a coupon only once
+type CouponRedemption struct {
+	gorm.Model
+	CouponID       uint `gorm:"not null;uniqueIndex:coupon_tutor" json:"coupon_id"`
+	TutorID        uint `gorm:"not null;uniqueIndex:coupon_tutor" json:"tutor_id"`
+	SubscriptionID uint `gorm:"not null" json:"subscription_id"`
+}
+
+// TableName specifies the table name for the CouponRedemption model
+func (CouponRedemption) TableName() string {
+	return "CouponRedemptions"
+}
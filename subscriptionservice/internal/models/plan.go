@@ -3,6 +3,7 @@ package models
 
 import (
 	"encoding/json"
+	"platform/contracts"
 
 	"gorm.io/gorm"
 )
@@ -15,11 +16,11 @@ const (
 	PlanProfessional PlanType = "professional"
 )
 
-type BillingCycle string
+type BillingCycle = contracts.BillingCycle
 
 const (
-	BillingMonthly  BillingCycle = "monthly"
-	BillingAnnually BillingCycle = "annually"
+	BillingMonthly  = contracts.BillingMonthly
+	BillingAnnually = contracts.BillingAnnually
 )
 
 // SubscriptionPlan represents a plan that tutors can subscribe to
@@ -27,11 +28,12 @@ type SubscriptionPlan struct {
 	gorm.Model
 	Name           string   `gorm:"size:100;not null" json:"name"`
 	Description    string   `gorm:"type:text" json:"description"`
-	PriceMonthly   float64  `gorm:"type:decimal(10,2);not null" json:"price_monthly"`
-	PriceAnnually  float64  `gorm:"type:decimal(10,2);not null" json:"price_annually"`
+	PriceMonthly   float64  `gorm:"type:double;not null" json:"price_monthly"`
+	PriceAnnually  float64  `gorm:"type:double;not null" json:"price_annually"`
 	MaxCourses     int      `gorm:"not null" json:"max_courses"`
-	CommissionRate float64  `gorm:"type:decimal(5,2);not null" json:"commission_rate"`
-	Features       string   `gorm:"type:json" json:"-"`
+	CommissionRate float64  `gorm:"type:double;not null" json:"commission_rate"`
+	TrialDays      int      `gorm:"not null;default:0" json:"trial_days"` // 0 for no free trial
+	Features       string   `gorm:"type:text;not null" json:"-"`
 	FeaturesJSON   []string `gorm:"-" json:"features"`
 	IsActive       bool     `gorm:"default:true" json:"is_active"`
 }
@@ -65,13 +67,5 @@ func (p *SubscriptionPlan) AfterFind(tx *gorm.DB) error {
 	return nil
 }
 
-type PlanRequest struct {
-	Name           string   `json:"name" validate:"required"`
-	Description    string   `json:"description"`
-	PriceMonthly   float64  `json:"price_monthly" validate:"required,gt=0"`
-	PriceAnnually  float64  `json:"price_annually" validate:"required,gt=0"`
-	MaxCourses     int      `json:"max_courses" validate:"required,gt=0"`
-	CommissionRate float64  `json:"commission_rate" validate:"required,gte=0,lte=100"`
-	Features       []string `json:"features"`
-	IsActive       bool     `json:"is_active"`
-}
+// PlanRequest creates or replaces a plan
+type PlanRequest = contracts.PlanRequest
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"platform/contracts"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PlanChangeStatus = contracts.PlanChangeStatus
+
+const (
+	PlanChangePending    = contracts.PlanChangePending
+	PlanChangeApplied    = contracts.PlanChangeApplied
+	PlanChangeSuperseded = contracts.PlanChangeSuperseded
+)
+
+// PlanChangeQuote is what a plan change would cost
+type PlanChangeQuote = contracts.PlanChangeQuote
+
+// PlanChange is a prorated move to another plan or billing cycle. The From fields are the
+// subscription as it was quoted, a change only applies while the subscription still matches them.
+type PlanChange struct {
+	gorm.Model
+	SubscriptionID   uint             `gorm:"not null" json:"subscription_id"`
+	FromPlanID       uint             `gorm:"not null" json:"from_plan_id"`
+	ToPlanID         uint             `gorm:"not null" json:"to_plan_id"`
+	FromBillingCycle BillingCycle     `gorm:"type:enum('monthly','annually');not null" json:"from_billing_cycle"`
+	ToBillingCycle   BillingCycle     `gorm:"type:enum('monthly','annually');not null" json:"to_billing_cycle"`
+	FromPeriodEnd    time.Time        `gorm:"not null" json:"from_period_end"`
+	Credit           float64          `gorm:"type:double;not null" json:"credit"`
+	Charge           float64          `gorm:"type:double;not null" json:"charge"`
+	CouponID         *uint            `json:"coupon_id,omitempty"`
+	Discount         float64          `gorm:"type:double;not null;default:0" json:"discount"` // taken off the charge
+	AmountDue        float64          `gorm:"type:double;not null" json:"amount_due"`
+	PeriodStart      time.Time        `gorm:"not null" json:"period_start"`
+	PeriodEnd        time.Time        `gorm:"not null" json:"period_end"`
+	PaymentOrderID   *string          `gorm:"size:191;uniqueIndex" json:"payment_order_id,omitempty"` // nil when nothing was due
+	Status           PlanChangeStatus `gorm:"type:enum('pending','applied','superseded');not null" json:"status"`
+	AppliedAt        *time.Time       `json:"applied_at,omitempty"`
+
+	// Relations
+	ToPlan SubscriptionPlan `gorm:"foreignKey:ToPlanID" json:"to_plan"`
+	Coupon *Coupon          `gorm:"foreignKey:CouponID" json:"coupon,omitempty"`
+}
+
+// TableName specifies the table name for the PlanChange model
+func (PlanChange) TableName() string {
+	return "SubscriptionPlanChanges"
+}
+
+// Matches reports whether subscription is still as it was when the change was quoted
+func (c *PlanChange) Matches(subscription *TutorSubscription) bool {
+	return subscription.Status == SubscriptionActive &&
+		subscription.PlanID == c.FromPlanID &&
+		subscription.BillingCycle == c.FromBillingCycle &&
+		subscription.CurrentPeriodEnd.Equal(c.FromPeriodEnd)
+}
+
+// Contract is the change as the tutor sees it, paymentURL is only known when it was created
+func (c *PlanChange) Contract(paymentURL string) contracts.PlanChange {
+	change := contracts.PlanChange{
+		PlanChangeQuote: contracts.PlanChangeQuote{
+			SubscriptionID:   c.SubscriptionID,
+			FromPlanID:       c.FromPlanID,
+			ToPlanID:         c.ToPlanID,
+			ToPlanName:       c.ToPlan.Name,
+			FromBillingCycle: c.FromBillingCycle,
+			ToBillingCycle:   c.ToBillingCycle,
+			Credit:           c.Credit,
+			Charge:           c.Charge,
+			Discount:         c.Discount,
+			AmountDue:        c.AmountDue,
+			PeriodStart:      c.PeriodStart,
+			PeriodEnd:        c.PeriodEnd,
+		},
+		Status:     c.Status,
+		PaymentURL: paymentURL,
+	}
+	if c.PaymentOrderID != nil {
+		change.PaymentOrderID = *c.PaymentOrderID
+	}
+	if c.Coupon != nil {
+		change.CouponCode = c.Coupon.Code
+	}
+	return change
+}
@@ -1,19 +1,20 @@
 package models
 
 import (
+	"platform/contracts"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-type SubscriptionStatus string
+type SubscriptionStatus = contracts.SubscriptionStatus
 
 const (
-	SubscriptionActive     SubscriptionStatus = "active"
-	SubscriptionCanceled   SubscriptionStatus = "canceled"
-	SubscriptionPastDue    SubscriptionStatus = "past_due"
-	SubscriptionTrialing   SubscriptionStatus = "trialing"
-	SubscriptionIncomplete SubscriptionStatus = "incomplete"
+	SubscriptionActive     = contracts.SubscriptionActive
+	SubscriptionCanceled   = contracts.SubscriptionCanceled
+	SubscriptionPastDue    = contracts.SubscriptionPastDue
+	SubscriptionTrialing   = contracts.SubscriptionTrialing
+	SubscriptionIncomplete = contracts.SubscriptionIncomplete
 )
 
 type TutorSubscription struct {
@@ -26,56 +27,61 @@ type TutorSubscription struct {
 	CancelAtPeriodEnd  bool               `gorm:"default:false" json:"cancel_at_period_end"`
 	BillingCycle       BillingCycle       `gorm:"type:enum('monthly','annually');not null" json:"billing_cycle"`
 	PaymentOrderID     string             `gorm:"size:255" json:"payment_order_id"`
+	RenewalDueAt       *time.Time         `json:"renewal_due_at,omitempty"` // set while a renewal payment is outstanding
+	PastDueSince       *time.Time         `json:"past_due_since,omitempty"`
+	DunningAttempts    int                `gorm:"not null;default:0" json:"dunning_attempts"`
+	NextDunningAt      *time.Time         `json:"next_dunning_at,omitempty"`
+	TrialEndsAt        *time.Time         `json:"trial_ends_at,omitempty"` // set on a subscription that started as a free trial
+	TrialRemindedAt    *time.Time         `json:"trial_reminded_at,omitempty"`
+	CouponID           *uint              `json:"coupon_id,omitempty"`
+	CouponCyclesLeft   *int               `json:"coupon_cycles_left,omitempty"`                               // billed periods the coupon still discounts, nil when it lasts forever
+	PeriodDiscountRate float64            `gorm:"type:double;not null;default:0" json:"period_discount_rate"` // share of the current period's price a coupon took off
 
 	// Relations
-	Plan SubscriptionPlan `gorm:"foreignKey:PlanID" json:"plan"`
+	Plan   SubscriptionPlan `gorm:"foreignKey:PlanID" json:"plan"`
+	Coupon *Coupon          `gorm:"foreignKey:CouponID" json:"coupon,omitempty"`
 }
 
 func (TutorSubscription) TableName() string {
 	return "TutorSubscriptions"
 }
 
-// SubscriptionRequest is used for creating a new subscription
-type SubscriptionRequest struct {
-	TutorID      uint         `json:"tutor_id" validate:"required"`
-	PlanID       uint         `json:"plan_id" validate:"required"`
-	BillingCycle BillingCycle `json:"billing_cycle" validate:"required,oneof=monthly annually"`
+// CouponApplies reports whether the subscription's coupon discounts its next billed period. A
+// coupon limited to other plans stays on the subscription but only applies back on its plans.
+func (s *TutorSubscription) CouponApplies() bool {
+	return s.Coupon != nil && (s.CouponCyclesLeft == nil || *s.CouponCyclesLeft > 0) && s.Coupon.AppliesTo(s.PlanID)
 }
 
-// SubscriptionResponse is the response returned after subscription operations
-type SubscriptionResponse struct {
-	ID                 uint               `json:"id"`
-	TutorID            uint               `json:"tutor_id"`
-	PlanName           string             `json:"plan_name"`
-	Status             SubscriptionStatus `json:"status"`
-	CurrentPeriodStart time.Time          `json:"current_period_start"`
-	CurrentPeriodEnd   time.Time          `json:"current_period_end"`
-	CancelAtPeriodEnd  bool               `json:"cancel_at_period_end"`
-	BillingCycle       BillingCycle       `json:"billing_cycle"`
-	Price              float64            `json:"price"`
-	Features           []string           `json:"features"`
-	MaxCourses         int                `json:"max_courses"`
-	CommissionRate     float64            `json:"commission_rate"`
-	PaymentOrderID     string             `json:"payment_order_id,omitempty"`
+// Discount is what the subscription's coupon takes off amount for its next billed period
+func (s *TutorSubscription) Discount(amount float64) float64 {
+	if !s.CouponApplies() {
+		return 0
+	}
+	return s.Coupon.Discount(amount)
 }
 
-// SubscriptionStatusUpdateRequest is used to update the status of a subscription
-type SubscriptionStatusUpdateRequest struct {
-	Status SubscriptionStatus `json:"status" validate:"required,oneof=active canceled past_due trialing incomplete"`
+// DiscountRate is the share of charge that discount takes off, for PeriodDiscountRate
+func DiscountRate(discount, charge float64) float64 {
+	if charge <= 0 {
+		return 0
+	}
+	return discount / charge
 }
 
+// SubscriptionRequest is used for creating a new subscription
+type SubscriptionRequest = contracts.SubscriptionRequest
+
+// SubscriptionResponse is the response returned after subscription operations
+type SubscriptionResponse = contracts.Subscription
+
+// SubscriptionStatusUpdateRequest is used to update the status of a subscription
+type SubscriptionStatusUpdateRequest = contracts.SubscriptionStatusUpdateRequest
+
 // ChangePlanRequest is used when a tutor wants to change their subscription plan
-type ChangePlanRequest struct {
-	NewPlanID    uint         `json:"new_plan_id" validate:"required"`
-	BillingCycle BillingCycle `json:"billing_cycle" validate:"required,oneof=monthly annually"`
-}
+type ChangePlanRequest = contracts.ChangePlanRequest
 
 // PaymentConfirmationRequest is used to confirm payment completion
-type PaymentConfirmationRequest struct {
-	OrderID   string `json:"order_id" validate:"required"`
-	PaymentID string `json:"payment_id"`
-	PayerID   string `json:"payer_id"`
-}
+type PaymentConfirmationRequest = contracts.PaymentConfirmationRequest
 
 // SubscriptionEvent represents an event in the subscription lifecycle
 type SubscriptionEvent struct {
@@ -92,6 +98,19 @@ func (SubscriptionEvent) TableName() string {
 	return "SubscriptionEvents"
 }
 
+// Contract is the event as admins see it
+func (e *SubscriptionEvent) Contract() contracts.SubscriptionEvent {
+	return contracts.SubscriptionEvent{
+		ID:             e.ID,
+		SubscriptionID: e.SubscriptionID,
+		EventType:      e.EventType,
+		PreviousStatus: e.PreviousStatus,
+		CurrentStatus:  e.CurrentStatus,
+		Notes:          e.Notes,
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
 // PaymentWebhookPayload represents the structure of payment webhooks
 type PaymentWebhookPayload struct {
 	Event   string `json:"event"`
@@ -0,0 +1,90 @@
+// Package proration prices a mid-cycle plan change. The unused part of the current period is
+// credited at what was paid for it: the old plan's price less the coupon discount it was billed with. Keeping the billing cycle keeps the period, so the new plan
+// is charged for what is left of it. Changing the cycle starts a new period now, charged in full.
+// A coupon takes its discount off the charge. Credit beyond what is left of the charge is not
+// refunded; it extends the new period at the new plan's price.
+package proration
+
+import (
+	"math"
+	"subscription/internal/models"
+	"time"
+)
+
+// Quote is the outcome of moving a subscription to another plan or billing cycle at a moment
+type Quote struct {
+	Credit      float64
+	Charge      float64
+	Discount    float64
+	AmountDue   float64
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Calculate quotes moving subscription, on its current plan, to plan billed every cycle at now,
+// discounted by coupon when it is not nil
+func Calculate(subscription *models.TutorSubscription, plan *models.SubscriptionPlan, cycle models.BillingCycle, coupon *models.Coupon, now time.Time) Quote {
+	unused := unusedFraction(subscription, now)
+	paid := Price(&subscription.Plan, subscription.BillingCycle) * (1 - subscription.PeriodDiscountRate)
+	quote := Quote{
+		Credit:      round(paid * unused),
+		PeriodStart: subscription.CurrentPeriodStart,
+		PeriodEnd:   subscription.CurrentPeriodEnd,
+	}
+
+	newPrice := Price(plan, cycle)
+	if cycle == subscription.BillingCycle {
+		quote.Charge = round(newPrice * unused)
+	} else {
+		quote.Charge = newPrice
+		quote.PeriodStart = now
+		quote.PeriodEnd = PeriodEnd(now, cycle)
+	}
+	if coupon != nil {
+		quote.Discount = coupon.Discount(quote.Charge)
+	}
+
+	charge := quote.Charge - quote.Discount
+	if charge >= quote.Credit {
+		quote.AmountDue = round(charge - quote.Credit)
+		return quote
+	}
+
+	// A free plan has no price to turn credit into time, the credit is forfeited
+	if newPrice > 0 {
+		length := PeriodEnd(quote.PeriodEnd, cycle).Sub(quote.PeriodEnd)
+		extra := time.Duration(float64(length) * (quote.Credit - charge) / newPrice)
+		quote.PeriodEnd = quote.PeriodEnd.Add(extra.Truncate(time.Second))
+	}
+	return quote
+}
+
+// Price is what plan costs per cycle
+func Price(plan *models.SubscriptionPlan, cycle models.BillingCycle) float64 {
+	if cycle == models.BillingAnnually {
+		return plan.PriceAnnually
+	}
+	return plan.PriceMonthly
+}
+
+// PeriodEnd is when a period of cycle that starts at start ends
+func PeriodEnd(start time.Time, cycle models.BillingCycle) time.Time {
+	if cycle == models.BillingAnnually {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// unusedFraction is how much of the current period is left at now, between 0 and 1
+func unusedFraction(subscription *models.TutorSubscription, now time.Time) float64 {
+	total := subscription.CurrentPeriodEnd.Sub(subscription.CurrentPeriodStart)
+	if total <= 0 {
+		return 0
+	}
+	left := subscription.CurrentPeriodEnd.Sub(now)
+	return math.Min(math.Max(float64(left)/float64(total), 0), 1)
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
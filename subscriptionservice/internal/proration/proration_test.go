@@ -0,0 +1,101 @@
+package proration
+
+import (
+	"subscription/internal/models"
+	"testing"
+	"time"
+)
+
+var (
+	basic = models.SubscriptionPlan{PriceMonthly: 10, PriceAnnually: 100}
+	pro   = models.SubscriptionPlan{PriceMonthly: 30, PriceAnnually: 300}
+	free  = models.SubscriptionPlan{}
+)
+
+// halfway is a 30 day monthly period on plan with 15 days left at now
+func halfway(plan models.SubscriptionPlan, cycle models.BillingCycle, now time.Time) *models.TutorSubscription {
+	return &models.TutorSubscription{
+		CurrentPeriodStart: now.AddDate(0, 0, -15),
+		CurrentPeriodEnd:   now.AddDate(0, 0, 15),
+		BillingCycle:       cycle,
+		Plan:               plan,
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	now := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name      string
+		from      models.SubscriptionPlan
+		to        *models.SubscriptionPlan
+		cycle     models.BillingCycle
+		credit    float64
+		charge    float64
+		due       float64
+		periodEnd time.Time
+	}{
+		{"upgrade keeps the period", basic, &pro, models.BillingMonthly, 5, 15, 10, now.AddDate(0, 0, 15)},
+		{"monthly to annual starts a year now", basic, &pro, models.BillingAnnually, 5, 300, 295, now.AddDate(1, 0, 0)},
+		// 10 left over after the charge buys another month of basic
+		{"downgrade extends the period", pro, &basic, models.BillingMonthly, 15, 5, 0, now.AddDate(0, 0, 15).AddDate(0, 1, 0)},
+		{"downgrade to free forfeits the credit", pro, &free, models.BillingMonthly, 15, 0, 0, now.AddDate(0, 0, 15)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			quote := Calculate(halfway(tc.from, models.BillingMonthly, now), tc.to, tc.cycle, nil, now)
+
+			if quote.Credit != tc.credit || quote.Charge != tc.charge || quote.AmountDue != tc.due {
+				t.Errorf("credit %v charge %v due %v, want %v %v %v", quote.Credit, quote.Charge, quote.AmountDue, tc.credit, tc.charge, tc.due)
+			}
+			if !quote.PeriodEnd.Equal(tc.periodEnd) {
+				t.Errorf("period ends %s, want %s", quote.PeriodEnd, tc.periodEnd)
+			}
+		})
+	}
+}
+
+func TestCalculateAnnualToMonthly(t *testing.T) {
+	now := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+	sub := &models.TutorSubscription{
+		CurrentPeriodStart: now.AddDate(0, -6, 0),
+		CurrentPeriodEnd:   now.AddDate(0, 6, 0),
+		BillingCycle:       models.BillingAnnually,
+		Plan:               basic,
+	}
+
+	quote := Calculate(sub, &pro, models.BillingMonthly, nil, now)
+
+	// Half a year of basic is worth about 50, more than a month of pro, the rest becomes time
+	if quote.AmountDue != 0 || !quote.PeriodStart.Equal(now) || !quote.PeriodEnd.After(now.AddDate(0, 1, 0)) {
+		t.Errorf("due %v period %s to %s, want nothing due and more than a month from now", quote.AmountDue, quote.PeriodStart, quote.PeriodEnd)
+	}
+}
+
+func TestCalculateWithCoupon(t *testing.T) {
+	now := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+	coupon := &models.Coupon{DiscountType: models.CouponPercent, Amount: 50}
+
+	quote := Calculate(halfway(basic, models.BillingMonthly, now), &pro, models.BillingAnnually, coupon, now)
+
+	// Half off the year of pro, less the half month of basic left
+	if quote.Discount != 150 || quote.AmountDue != 145 {
+		t.Errorf("discount %v due %v, want 150 and 145", quote.Discount, quote.AmountDue)
+	}
+}
+
+func TestCalculateCreditsWhatWasPaid(t *testing.T) {
+	now := time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC)
+	// pro was bought at half price, so its half month left is worth 7.50 rather than 15
+	sub := halfway(pro, models.BillingMonthly, now)
+	sub.PeriodDiscountRate = 0.5
+
+	quote := Calculate(sub, &basic, models.BillingMonthly, nil, now)
+
+	full := Calculate(halfway(pro, models.BillingMonthly, now), &basic, models.BillingMonthly, nil, now)
+	if quote.Credit != 7.5 || quote.AmountDue != 0 {
+		t.Errorf("credit %v due %v, want 7.5 and nothing due", quote.Credit, quote.AmountDue)
+	}
+	if !quote.PeriodEnd.Before(full.PeriodEnd) {
+		t.Errorf("period ends %s, want before the %s a full price credit buys", quote.PeriodEnd, full.PeriodEnd)
+	}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"subscription/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CouponRepository interface {
+	Create(coupon *models.Coupon) error
+	GetByID(id uint) (*models.Coupon, error)
+	GetByCode(code string) (*models.Coupon, error)
+	GetAll(activeOnly bool) ([]models.Coupon, error)
+	Update(coupon *models.Coupon) error
+	Delete(id uint) error
+	HasRedeemed(couponID, tutorID uint) (bool, error)
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+// NewCouponRepository creates a new instance of CouponRepository
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{
+		db: db,
+	}
+}
+
+// Create adds a new coupon
+func (r *couponRepository) Create(coupon *models.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+// GetByID retrieves a coupon by its ID
+func (r *couponRepository) GetByID(id uint) (*models.Coupon, error) {
+	var coupon models.Coupon
+	result := r.db.First(&coupon, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, result.Error
+	}
+	return &coupon, nil
+}
+
+// GetByCode retrieves a coupon by its code, which is case insensitive
+func (r *couponRepository) GetByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	result := r.db.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&coupon)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, result.Error
+	}
+	return &coupon, nil
+}
+
+// GetAll retrieves all coupons, newest first
+func (r *couponRepository) GetAll(activeOnly bool) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	query := r.db.Order("created_at DESC")
+
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	result := query.Find(&coupons)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return coupons, nil
+}
+
+// Update updates an existing coupon
+func (r *couponRepository) Update(coupon *models.Coupon) error {
+	return r.db.Save(coupon).Error
+}
+
+// Delete soft-deletes a coupon by setting is_active to false. Subscriptions that already
+// redeemed it keep their discount.
+func (r *couponRepository) Delete(id uint) error {
+	return r.db.Model(&models.Coupon{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"is_active":  false,
+			"deleted_at": time.Now(),
+		}).
+		Error
+}
+
+// HasRedeemed reports whether the tutor already redeemed the coupon
+func (r *couponRepository) HasRedeemed(couponID, tutorID uint) (bool, error) {
+	var count int64
+	result := r.db.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND tutor_id = ?", couponID, tutorID).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
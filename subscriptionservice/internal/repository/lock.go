@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// Locker hands out MySQL named locks, which every replica sharing the database sees
+type Locker interface {
+	// TryLock takes name without waiting. ok is false when another connection holds it,
+	// otherwise release must be called to give it back.
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+type mysqlLocker struct {
+	db *gorm.DB
+}
+
+// NewLocker creates a Locker on db's connection pool
+func NewLocker(db *gorm.DB) Locker {
+	return &mysqlLocker{db: db}
+}
+
+func (l *mysqlLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	// A named lock belongs to the session that took it, so it is held on a connection of its own
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		conn.Close()
+	}, true, nil
+}
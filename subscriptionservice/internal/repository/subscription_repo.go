@@ -3,10 +3,12 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"subscription/internal/models"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepository interface {
@@ -19,6 +21,17 @@ type SubscriptionRepository interface {
 	SetCancelAtPeriodEnd(id uint, cancel bool) error
 	LogEvent(event *models.SubscriptionEvent) error
 	GetExpiringSoon(days int) ([]models.TutorSubscription, error)
+	GetPeriodEnded(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetRenewalOverdue(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetDunningDue(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetTrialEnding(before time.Time, limit int) ([]models.TutorSubscription, error)
+	HasHadTrial(tutorID uint) (bool, error)
+	GetEvents(subscriptionID uint) ([]models.SubscriptionEvent, error)
+	Transition(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error)
+	TransitionPaid(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error)
+	CreatePlanChange(change *models.PlanChange) error
+	GetPlanChangeByOrderID(orderID string) (*models.PlanChange, error)
+	ApplyPlanChange(subscription *models.TutorSubscription, change *models.PlanChange, redeemed *models.Coupon, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error)
 }
 
 type subscriptionRepository struct {
@@ -40,7 +53,7 @@ func (r *subscriptionRepository) Create(subscription *models.TutorSubscription)
 // GetByID retrieves a subscription by its ID
 func (r *subscriptionRepository) GetByID(id uint) (*models.TutorSubscription, error) {
 	var subscription models.TutorSubscription
-	result := r.db.Preload("Plan").First(&subscription, id)
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).First(&subscription, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("subscription not found")
@@ -53,7 +66,7 @@ func (r *subscriptionRepository) GetByID(id uint) (*models.TutorSubscription, er
 // GetByTutorID retrieves a subscription by tutor ID
 func (r *subscriptionRepository) GetByTutorID(tutorID uint) (*models.TutorSubscription, error) {
 	var subscription models.TutorSubscription
-	result := r.db.Preload("Plan").Where("tutor_id = ? AND status != ?", tutorID, models.SubscriptionCanceled).First(&subscription)
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).Where("tutor_id = ? AND status != ?", tutorID, models.SubscriptionCanceled).First(&subscription)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("subscription not found")
@@ -84,6 +97,10 @@ func (r *subscriptionRepository) GetAll(page, pageSize int, filters map[string]i
 			query = query.Where("plan_id = ?", planID)
 		}
 
+		if orderID, ok := filters["payment_order_id"].(string); ok {
+			query = query.Where("payment_order_id = ?", orderID)
+		}
+
 		if fromDate, ok := filters["from_date"].(time.Time); ok {
 			query = query.Where("created_at >= ?", fromDate)
 		}
@@ -100,7 +117,7 @@ func (r *subscriptionRepository) GetAll(page, pageSize int, filters map[string]i
 
 	// Apply pagination
 	offset := (page - 1) * pageSize
-	result := query.Preload("Plan").
+	result := query.Preload("Plan").Preload("Coupon", unscoped).
 		Limit(pageSize).
 		Offset(offset).
 		Find(&subscriptions)
@@ -117,11 +134,17 @@ func (r *subscriptionRepository) Update(subscription *models.TutorSubscription)
 	return r.db.Save(subscription).Error
 }
 
-// UpdateStatus updates the status of a subscription
+// UpdateStatus updates the status of a subscription. Any dunning in progress is reset, a
+// subscription set past_due this way starts dunning afresh on the next scheduler sweep.
 func (r *subscriptionRepository) UpdateStatus(id uint, status models.SubscriptionStatus) error {
 	result := r.db.Model(&models.TutorSubscription{}).
 		Where("id = ?", id).
-		Update("status", status)
+		Updates(map[string]interface{}{
+			"status":           status,
+			"past_due_since":   nil,
+			"dunning_attempts": 0,
+			"next_dunning_at":  nil,
+		})
 
 	if result.Error != nil {
 		return result.Error
@@ -163,7 +186,7 @@ func (r *subscriptionRepository) GetExpiringSoon(days int) ([]models.TutorSubscr
 	now := time.Now()
 	futureDate := now.AddDate(0, 0, days)
 
-	result := r.db.Preload("Plan").
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).
 		Where("status = ? AND current_period_end BETWEEN ? AND ?",
 			models.SubscriptionActive, now, futureDate).
 		Find(&subscriptions)
@@ -174,3 +197,286 @@ func (r *subscriptionRepository) GetExpiringSoon(days int) ([]models.TutorSubscr
 
 	return subscriptions, nil
 }
+
+// GetPeriodEnded finds active and trialing subscriptions whose current period is over, oldest first
+func (r *subscriptionRepository) GetPeriodEnded(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var subscriptions []models.TutorSubscription
+
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).
+		Where("status IN ? AND current_period_end <= ?",
+			[]models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionTrialing}, now).
+		Order("current_period_end").
+		Limit(limit).
+		Find(&subscriptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return subscriptions, nil
+}
+
+// GetRenewalOverdue finds active subscriptions whose renewal payment was due by now
+func (r *subscriptionRepository) GetRenewalOverdue(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var subscriptions []models.TutorSubscription
+
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).
+		Where("status = ? AND renewal_due_at <= ?", models.SubscriptionActive, now).
+		Order("renewal_due_at").
+		Limit(limit).
+		Find(&subscriptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return subscriptions, nil
+}
+
+// GetDunningDue finds past_due subscriptions whose next dunning step is due by now, along
+// with those that went past_due without starting dunning
+func (r *subscriptionRepository) GetDunningDue(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var subscriptions []models.TutorSubscription
+
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).
+		Where("status = ? AND (next_dunning_at <= ? OR next_dunning_at IS NULL)", models.SubscriptionPastDue, now).
+		Order("next_dunning_at").
+		Limit(limit).
+		Find(&subscriptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return subscriptions, nil
+}
+
+// GetTrialEnding finds trials that end by before and go on to a paid period, whose tutor has
+// not been reminded yet
+func (r *subscriptionRepository) GetTrialEnding(before time.Time, limit int) ([]models.TutorSubscription, error) {
+	var subscriptions []models.TutorSubscription
+
+	result := r.db.Preload("Plan").Preload("Coupon", unscoped).
+		Where("status = ? AND current_period_end <= ? AND cancel_at_period_end = ? AND trial_reminded_at IS NULL",
+			models.SubscriptionTrialing, before, false).
+		Order("current_period_end").
+		Limit(limit).
+		Find(&subscriptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return subscriptions, nil
+}
+
+// HasHadTrial reports whether the tutor ever started a free trial, deleted subscriptions included
+func (r *subscriptionRepository) HasHadTrial(tutorID uint) (bool, error) {
+	var count int64
+	result := r.db.Unscoped().Model(&models.TutorSubscription{}).
+		Where("tutor_id = ? AND trial_ends_at IS NOT NULL", tutorID).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// GetEvents returns a subscription's history, oldest first
+func (r *subscriptionRepository) GetEvents(subscriptionID uint) ([]models.SubscriptionEvent, error) {
+	var events []models.SubscriptionEvent
+
+	result := r.db.Where("subscription_id = ?", subscriptionID).
+		Order("created_at, id").
+		Find(&events)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return events, nil
+}
+
+// Transition applies updates and records event in one transaction, but only while the row
+// still has the status, period end and dunning attempts subscription was read with. It reports false when
+// someone else changed the subscription first, in which case nothing is written.
+func (r *subscriptionRepository) Transition(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := transition(tx, subscription, updates)
+		if err != nil || !ok {
+			return err
+		}
+		applied = true
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// TransitionPaid is Transition for a payment that went through. A subscription checked out with a
+// coupon redeems it with its first paid period, so a checkout that is never paid for or a trial
+// that is canceled does not use up the tutor's redemption or a place under the coupon's cap.
+func (r *subscriptionRepository) TransitionPaid(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := transition(tx, subscription, updates)
+		if err != nil || !ok {
+			return err
+		}
+		if subscription.Coupon != nil {
+			if err := redeemPaid(tx, subscription, event); err != nil {
+				return err
+			}
+		}
+		applied = true
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// CreatePlanChange records a pending plan change, superseding any earlier one still pending
+func (r *subscriptionRepository) CreatePlanChange(change *models.PlanChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := supersedePlanChanges(tx, change.SubscriptionID); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(change).Error
+	})
+}
+
+// GetPlanChangeByOrderID finds the plan change paid for by orderID, nil when the order is not
+// for a plan change
+func (r *subscriptionRepository) GetPlanChangeByOrderID(orderID string) (*models.PlanChange, error) {
+	var change models.PlanChange
+	result := r.db.Preload("ToPlan").Preload("Coupon", unscoped).Where("payment_order_id = ?", orderID).First(&change)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &change, nil
+}
+
+// ApplyPlanChange is Transition for a plan change: the updates, the change marked applied, any
+// other pending change superseded and the redemption of a newly redeemed coupon all happen
+// together or not at all. A change that has no ID yet, because nothing was due for it, is
+// created already applied.
+func (r *subscriptionRepository) ApplyPlanChange(subscription *models.TutorSubscription, change *models.PlanChange, redeemed *models.Coupon, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := transition(tx, subscription, updates)
+		if err != nil || !ok {
+			return err
+		}
+		if redeemed != nil {
+			if err := redeem(tx, redeemed, subscription); err != nil {
+				return err
+			}
+		}
+
+		if err := supersedePlanChanges(tx, change.SubscriptionID); err != nil {
+			return err
+		}
+		now := time.Now()
+		change.Status = models.PlanChangeApplied
+		change.AppliedAt = &now
+		if err := tx.Omit(clause.Associations).Save(change).Error; err != nil {
+			return err
+		}
+		applied = true
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// transition applies updates while the row is still as subscription was read
+func transition(tx *gorm.DB, subscription *models.TutorSubscription, updates map[string]interface{}) (bool, error) {
+	result := tx.Model(&models.TutorSubscription{}).
+		Where("id = ? AND status = ? AND current_period_end = ? AND dunning_attempts = ?",
+			subscription.ID, subscription.Status, subscription.CurrentPeriodEnd, subscription.DunningAttempts).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+var (
+	errAlreadyRedeemed = errors.New("coupon has already been redeemed")
+	errFullyRedeemed   = errors.New("coupon has been fully redeemed")
+)
+
+// redeem counts subscription's tutor redeeming coupon, within its cap and only once per tutor
+func redeem(tx *gorm.DB, coupon *models.Coupon, subscription *models.TutorSubscription) error {
+	var count int64
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND tutor_id = ?", coupon.ID, subscription.TutorID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errAlreadyRedeemed
+	}
+
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (max_redemptions = 0 OR times_redeemed < max_redemptions)", coupon.ID).
+		Update("times_redeemed", gorm.Expr("times_redeemed + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errFullyRedeemed
+	}
+
+	return tx.Create(&models.CouponRedemption{
+		CouponID:       coupon.ID,
+		TutorID:        subscription.TutorID,
+		SubscriptionID: subscription.ID,
+	}).Error
+}
+
+// redeemPaid redeems subscription's coupon unless this subscription already has. A coupon that
+// went out of reach since checkout, because it was fully redeemed or the tutor redeemed it on
+// another subscription meanwhile, is taken off the subscription; the payment it discounted stands.
+func redeemPaid(tx *gorm.DB, subscription *models.TutorSubscription, event *models.SubscriptionEvent) error {
+	var count int64
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND subscription_id = ?", subscription.Coupon.ID, subscription.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	err := redeem(tx, subscription.Coupon, subscription)
+	if !errors.Is(err, errAlreadyRedeemed) && !errors.Is(err, errFullyRedeemed) {
+		return err
+	}
+	event.Notes += fmt.Sprintf(", coupon %s removed: %v", subscription.Coupon.Code, err)
+	return tx.Model(&models.TutorSubscription{}).Where("id = ?", subscription.ID).Updates(map[string]interface{}{
+		"coupon_id":          nil,
+		"coupon_cycles_left": nil,
+	}).Error
+}
+
+// unscoped preloads a coupon even after it was deleted, subscriptions keep their discount
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+func supersedePlanChanges(tx *gorm.DB, subscriptionID uint) error {
+	return tx.Model(&models.PlanChange{}).
+		Where("subscription_id = ? AND status = ?", subscriptionID, models.PlanChangePending).
+		Update("status", models.PlanChangeSuperseded).Error
+}
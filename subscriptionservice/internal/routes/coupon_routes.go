@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"subscription/internal/handlers"
+	"subscription/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupCouponRoutes configures the routes for coupon management, tutors redeem coupons through
+// the subscription routes
+func SetupCouponRoutes(api fiber.Router, couponService services.CouponService) {
+	// Initialize the handler
+	couponHandler := handlers.NewCouponHandler(couponService)
+
+	// Admin routes for coupon management
+	couponAdmin := api.Group("/admin/coupons")
+	couponAdmin.Get("/", couponHandler.HandleGetAllCoupons)
+	couponAdmin.Get("/:id", couponHandler.HandleGetCoupon)
+	couponAdmin.Post("/", couponHandler.HandleCreateCoupon)
+	couponAdmin.Put("/:id", couponHandler.HandleUpdateCoupon)
+	couponAdmin.Delete("/:id", couponHandler.HandleDeleteCoupon)
+}
@@ -7,9 +7,10 @@ import (
 )
 
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(api fiber.Router, planService services.PlanService, subscriptionService services.SubscriptionService) {
+func SetupRoutes(api fiber.Router, planService services.PlanService, couponService services.CouponService, subscriptionService services.SubscriptionService) {
 	// Register all route groups
 	SetupPlanRoutes(api, planService)
+	SetupCouponRoutes(api, couponService)
 	SetupSubscriptionRoutes(api, subscriptionService)
 	SetupWebhookRoutes(api, subscriptionService)
 }
@@ -18,10 +18,12 @@ func SetupSubscriptionRoutes(api fiber.Router, subscriptionService services.Subs
 	subscriptions.Get("/tutor/:tutorId", subscriptionHandler.HandleGetTutorSubscription)
 	subscriptions.Put("/:id/cancel", subscriptionHandler.HandleCancelSubscription)
 	subscriptions.Put("/:id/change-plan", subscriptionHandler.HandleChangePlan)
+	subscriptions.Post("/:id/change-plan/preview", subscriptionHandler.HandlePreviewPlanChange)
 	subscriptions.Post("/confirm", subscriptionHandler.HandleConfirmSubscription)
 
 	// Admin subscription routes
 	subscriptionAdmin := api.Group("/admin/subscriptions")
 	subscriptionAdmin.Get("/", subscriptionHandler.HandleGetAllSubscriptions)
 	subscriptionAdmin.Put("/:id/status", subscriptionHandler.HandleUpdateSubscriptionStatus)
+	subscriptionAdmin.Get("/:id/events", subscriptionHandler.HandleGetSubscriptionEvents)
 }
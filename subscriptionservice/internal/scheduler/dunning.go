@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"subscription/internal/dunning"
+	"subscription/internal/models"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// dun takes a past_due subscription one step on: it starts dunning for a subscription that went
+// past_due without it, retries the payment while retries are left, and otherwise downgrades or
+// cancels it
+func (s *Scheduler) dun(ctx context.Context, subscription *models.TutorSubscription, now time.Time) {
+	if subscription.PastDueSince == nil {
+		s.transition(ctx, subscription, s.dunning.Start(now), "dunning_started", models.SubscriptionPastDue,
+			fmt.Sprintf("Dunning started, plan features stay on until %s", s.dunning.GraceEnds(now).Format(dateFormat)))
+		return
+	}
+
+	if s.dunning.Final(subscription.DunningAttempts) {
+		s.finish(ctx, subscription, now)
+		return
+	}
+	s.retry(ctx, subscription)
+}
+
+// retry asks for a new payment and sends the tutor a link to it
+func (s *Scheduler) retry(ctx context.Context, subscription *models.TutorSubscription) {
+	attempt := subscription.DunningAttempts + 1
+	graceEnds := s.dunning.GraceEnds(*subscription.PastDueSince)
+	updates := map[string]interface{}{
+		"dunning_attempts": attempt,
+		"next_dunning_at":  s.dunning.NextAt(*subscription.PastDueSince, attempt),
+	}
+
+	// The period was already counted against the coupon when it was billed
+	amount := charge(subscription)
+	orderID, link, err := s.paymentService.CreatePayment(ctx, subscription.TutorID, subscription.PlanID, amount, string(subscription.BillingCycle))
+
+	eventType := "dunning_retry"
+	notes := fmt.Sprintf("Payment retry %d of %d, payment %s", attempt, s.dunning.Retries(), orderID)
+	body := fmt.Sprintf("We could not collect the payment for your %s subscription. You can complete it here: %s\n\n"+
+		"Your plan features stay available until %s.", subscription.Plan.Name, link, graceEnds.Format(dateFormat))
+	if err != nil {
+		// The tutor is still reminded, paying from the app starts a new subscription payment
+		slog.ErrorContext(ctx, "Failed to create dunning payment", "subscription_id", subscription.ID, "error", err)
+		eventType = "dunning_retry_failed"
+		notes = fmt.Sprintf("Payment retry %d of %d could not be created: %v", attempt, s.dunning.Retries(), err)
+		body = fmt.Sprintf("We could not collect the payment for your %s subscription. Please sign in and pay for it before %s "+
+			"to keep your plan features.", subscription.Plan.Name, graceEnds.Format(dateFormat))
+	} else {
+		updates["payment_order_id"] = orderID
+	}
+
+	if !s.transition(ctx, subscription, updates, eventType, models.SubscriptionPastDue, notes) {
+		return
+	}
+	s.notify(ctx, subscription, models.SubscriptionPastDue, "Your subscription payment is past due", body)
+}
+
+// finish ends dunning with the configured final action. A downgrade that cannot be billed
+// cancels instead, as does one for a subscription already on the downgrade plan.
+func (s *Scheduler) finish(ctx context.Context, subscription *models.TutorSubscription, now time.Time) {
+	action, planID := s.dunning.FinalAction()
+	if action == dunning.ActionDowngrade && planID != subscription.PlanID && s.downgrade(ctx, subscription, planID, now) {
+		return
+	}
+
+	updates := dunning.Clear()
+	updates["status"] = models.SubscriptionCanceled
+	updates["renewal_due_at"] = nil
+	if !s.transition(ctx, subscription, updates, "dunning_canceled", models.SubscriptionCanceled,
+		fmt.Sprintf("Nothing paid since %s after %d retries, subscription canceled",
+			subscription.PastDueSince.Format(dateFormat), subscription.DunningAttempts)) {
+		return
+	}
+	s.notify(ctx, subscription, models.SubscriptionCanceled, "Your subscription has been canceled",
+		fmt.Sprintf("We did not receive the payment for your %s subscription, so it has been canceled and its features "+
+			"are no longer available. You can subscribe again at any time.", subscription.Plan.Name))
+}
+
+// downgrade moves the subscription to planID for a new period, billed like a renewal. It
+// reports false when the downgrade could not be set up and the subscription should be canceled.
+func (s *Scheduler) downgrade(ctx context.Context, subscription *models.TutorSubscription, planID uint, now time.Time) bool {
+	plan, err := s.planRepo.GetByID(planID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find the dunning downgrade plan", "plan_id", planID, "error", err)
+		return false
+	}
+
+	updates := dunning.Clear()
+	updates["plan_id"] = plan.ID
+	updates["status"] = models.SubscriptionActive
+	updates["current_period_start"] = now
+	updates["current_period_end"] = nextPeriodEnd(now, subscription.BillingCycle)
+	updates["renewal_due_at"] = nil
+
+	// Billed like a renewal on the new plan, with the coupon if it covers that plan
+	next := *subscription
+	next.PlanID = plan.ID
+	next.Plan = *plan
+	useCoupon(&next, updates)
+
+	body := fmt.Sprintf("We did not receive the payment for your %s subscription, so it has been moved to the %s plan.",
+		subscription.Plan.Name, plan.Name)
+	notes := fmt.Sprintf("Nothing paid since %s, downgraded from plan %d to %d",
+		subscription.PastDueSince.Format(dateFormat), subscription.PlanID, plan.ID)
+
+	// A free or fully discounted plan has nothing to pay, any other is due within the renewal grace period
+	if amount := charge(&next); amount > 0 {
+		orderID, link, err := s.paymentService.CreatePayment(ctx, subscription.TutorID, plan.ID, amount, string(subscription.BillingCycle))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create downgrade payment", "subscription_id", subscription.ID, "error", err)
+			return false
+		}
+		dueAt := now.Add(s.cfg.Grace)
+		updates["payment_order_id"] = orderID
+		updates["renewal_due_at"] = dueAt
+		body += fmt.Sprintf(" Please complete its payment by %s: %s", dueAt.Format(dateFormat), link)
+		notes += fmt.Sprintf(", payment %s of %.2f%s due by %s", orderID, amount, couponNote(&next), dueAt.Format("2006-01-02 15:04"))
+	}
+
+	// Someone else moving the subscription on first is not a reason to cancel it
+	if s.transition(ctx, subscription, updates, "dunning_downgraded", models.SubscriptionActive, notes) {
+		s.notify(ctx, subscription, models.SubscriptionActive, "Your subscription has been downgraded", body)
+	}
+	return true
+}
+
+// notify emails the tutor, a failure is recorded on the subscription for admins but does not
+// hold dunning up
+func (s *Scheduler) notify(ctx context.Context, subscription *models.TutorSubscription, status models.SubscriptionStatus, title, body string) {
+	err := s.notifier.NotifyTutor(ctx, subscription.TutorID, title, body)
+	if err == nil {
+		return
+	}
+	slog.ErrorContext(ctx, "Failed to email tutor", "subscription_id", subscription.ID, "tutor_id", subscription.TutorID, "error", err)
+
+	event := &models.SubscriptionEvent{
+		SubscriptionID: subscription.ID,
+		EventType:      "notification_failed",
+		PreviousStatus: status,
+		CurrentStatus:  status,
+		Notes:          fmt.Sprintf("%q could not be sent: %v", title, err),
+	}
+	if err := s.subscriptionRepo.LogEvent(event); err != nil {
+		slog.ErrorContext(ctx, "Failed to log subscription event", "subscription_id", subscription.ID, "error", err)
+	}
+}
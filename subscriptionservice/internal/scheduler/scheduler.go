@@ -0,0 +1,357 @@
+// Package scheduler moves subscriptions on when their period ends: those set to cancel are
+// canceled, ended free trials and the rest get a payment for their next period, and a renewal left unpaid past its grace period
+// turns the subscription past_due, from where dunning retries the payment and finally
+// downgrades or cancels it. Every replica runs it, a MySQL named lock lets one sweep at
+// a time and each change only applies to the row as it was read, so none happens twice.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"subscription/internal/config"
+	"subscription/internal/dunning"
+	"subscription/internal/metrics"
+	"subscription/internal/models"
+	"sync"
+	"time"
+)
+
+const lockName = "subscription-service:scheduler"
+
+// Store is the part of repository.SubscriptionRepository the scheduler works through
+type Store interface {
+	GetPeriodEnded(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetRenewalOverdue(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetDunningDue(now time.Time, limit int) ([]models.TutorSubscription, error)
+	GetTrialEnding(before time.Time, limit int) ([]models.TutorSubscription, error)
+	LogEvent(event *models.SubscriptionEvent) error
+	Transition(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error)
+	TransitionPaid(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error)
+}
+
+// Payments is the part of services.PaymentService the scheduler bills through
+type Payments interface {
+	CreatePayment(ctx context.Context, tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error)
+	ValidatePaymentStatus(ctx context.Context, orderID string) (bool, error)
+}
+
+// Plans is the part of repository.PlanRepository the scheduler prices downgrades with
+type Plans interface {
+	GetByID(id uint) (*models.SubscriptionPlan, error)
+}
+
+// Notifier is services.NotificationService
+type Notifier interface {
+	NotifyTutor(ctx context.Context, tutorID uint, title, body string) error
+}
+
+// Locker is repository.Locker
+type Locker interface {
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+type Scheduler struct {
+	subscriptionRepo Store
+	planRepo         Plans
+	paymentService   Payments
+	notifier         Notifier
+	locker           Locker
+	cfg              config.RenewalConfig
+	dunning          dunning.Policy
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewScheduler(
+	subscriptionRepo Store,
+	planRepo Plans,
+	paymentService Payments,
+	notifier Notifier,
+	locker Locker,
+	cfg config.RenewalConfig,
+	policy dunning.Policy,
+) *Scheduler {
+	return &Scheduler{
+		subscriptionRepo: subscriptionRepo,
+		planRepo:         planRepo,
+		paymentService:   paymentService,
+		notifier:         notifier,
+		locker:           locker,
+		cfg:              cfg,
+		dunning:          policy,
+		done:             make(chan struct{}),
+	}
+}
+
+// Start sweeps every cfg.Interval until Stop, doing nothing when the interval is 0
+func (s *Scheduler) Start() {
+	if s.cfg.Interval <= 0 {
+		slog.Info("Subscription scheduler is disabled")
+		close(s.done)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			s.Sweep(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the loop and waits, up to ctx's deadline, for a sweep in progress to return
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
+	})
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep handles every subscription that is due, when no other replica is already doing so
+func (s *Scheduler) Sweep(ctx context.Context) {
+	release, ok, err := s.locker.TryLock(ctx, lockName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to take the subscription scheduler lock", "error", err)
+		return
+	}
+	if !ok {
+		slog.DebugContext(ctx, "Another replica is sweeping subscriptions")
+		return
+	}
+	defer release()
+
+	now := time.Now()
+
+	ended, err := s.subscriptionRepo.GetPeriodEnded(now, s.cfg.BatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find subscriptions whose period ended", "error", err)
+	}
+	for i := range ended {
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case ended[i].CancelAtPeriodEnd:
+			s.expire(ctx, &ended[i])
+		case ended[i].Status == models.SubscriptionTrialing:
+			s.convert(ctx, &ended[i], now)
+		default:
+			s.renew(ctx, &ended[i], now)
+		}
+	}
+
+	if s.cfg.TrialReminder > 0 {
+		trials, err := s.subscriptionRepo.GetTrialEnding(now.Add(s.cfg.TrialReminder), s.cfg.BatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to find ending trials", "error", err)
+		}
+		for i := range trials {
+			if ctx.Err() != nil {
+				return
+			}
+			s.remindTrial(ctx, &trials[i])
+		}
+	}
+
+	overdue, err := s.subscriptionRepo.GetRenewalOverdue(now, s.cfg.BatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find overdue renewals", "error", err)
+	}
+	for i := range overdue {
+		if ctx.Err() != nil {
+			return
+		}
+		s.collect(ctx, &overdue[i], now)
+	}
+
+	pastDue, err := s.subscriptionRepo.GetDunningDue(now, s.cfg.BatchSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find subscriptions due a dunning step", "error", err)
+	}
+	for i := range pastDue {
+		if ctx.Err() != nil {
+			return
+		}
+		s.dun(ctx, &pastDue[i], now)
+	}
+}
+
+// expire ends a subscription that was canceled for the end of its period
+func (s *Scheduler) expire(ctx context.Context, subscription *models.TutorSubscription) {
+	s.transition(ctx, subscription, map[string]interface{}{
+		"status":         models.SubscriptionCanceled,
+		"renewal_due_at": nil,
+	}, "expired", models.SubscriptionCanceled,
+		fmt.Sprintf("Subscription ended on %s as requested", subscription.CurrentPeriodEnd.Format("2006-01-02")))
+}
+
+// renew starts the next period and asks for its payment, which must be completed within the
+// grace period. A subscription that could not be billed goes past_due straight away, one with
+// nothing to pay is renewed at once.
+func (s *Scheduler) renew(ctx context.Context, subscription *models.TutorSubscription, now time.Time) {
+	amount := charge(subscription)
+	start, end := nextPeriod(subscription, now)
+
+	if amount <= 0 {
+		updates := map[string]interface{}{
+			"current_period_start": start,
+			"current_period_end":   end,
+			"renewal_due_at":       nil,
+		}
+		useCoupon(subscription, updates)
+		s.apply(ctx, s.subscriptionRepo.TransitionPaid, subscription, updates, "renewed", models.SubscriptionActive,
+			fmt.Sprintf("Renewed for %s to %s with nothing to pay%s", start.Format(dateFormat), end.Format(dateFormat), couponNote(subscription)))
+		return
+	}
+
+	orderID, link, err := s.paymentService.CreatePayment(ctx, subscription.TutorID, subscription.PlanID, amount, string(subscription.BillingCycle))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create renewal payment", "subscription_id", subscription.ID, "error", err)
+		s.transition(ctx, subscription, s.dunning.Start(now), "renewal_failed", models.SubscriptionPastDue,
+			fmt.Sprintf("Renewal payment could not be created: %v", err))
+		return
+	}
+
+	dueAt := now.Add(s.cfg.Grace)
+	updates := map[string]interface{}{
+		"current_period_start": start,
+		"current_period_end":   end,
+		"payment_order_id":     orderID,
+		"renewal_due_at":       dueAt,
+	}
+	useCoupon(subscription, updates)
+
+	applied := s.transition(ctx, subscription, updates, "renewal_initiated", models.SubscriptionActive,
+		fmt.Sprintf("Renewal for %s to %s, payment %s of %.2f%s due by %s",
+			start.Format("2006-01-02"), end.Format("2006-01-02"), orderID, amount, couponNote(subscription), dueAt.Format("2006-01-02 15:04")))
+	if !applied {
+		slog.WarnContext(ctx, "Renewal payment created for a subscription that changed meanwhile", "subscription_id", subscription.ID, "order_id", orderID)
+		return
+	}
+
+	s.notify(ctx, subscription, models.SubscriptionActive, "Your subscription has renewed",
+		fmt.Sprintf("Your %s subscription has renewed until %s. Please complete its payment of %.2f by %s to keep your plan features: %s",
+			subscription.Plan.Name, end.Format(dateFormat), amount, dueAt.Format(dateFormat), link))
+}
+
+// collect settles a renewal whose grace period is over: renewed if the payment went through
+// without its confirmation reaching us, past_due if it did not
+func (s *Scheduler) collect(ctx context.Context, subscription *models.TutorSubscription, now time.Time) {
+	paid, err := s.paymentService.ValidatePaymentStatus(ctx, subscription.PaymentOrderID)
+	if err != nil {
+		// Left for the next sweep rather than punishing the tutor for payment-service being down
+		slog.ErrorContext(ctx, "Failed to check renewal payment", "subscription_id", subscription.ID, "order_id", subscription.PaymentOrderID, "error", err)
+		return
+	}
+
+	if paid {
+		// Settled like a confirmed payment, which redeems a coupon on a trial's first paid period
+		s.apply(ctx, s.subscriptionRepo.TransitionPaid, subscription, map[string]interface{}{
+			"renewal_due_at": nil,
+		}, "renewed", models.SubscriptionActive,
+			fmt.Sprintf("Renewal payment %s completed", subscription.PaymentOrderID))
+		return
+	}
+	updates := s.dunning.Start(now)
+	updates["renewal_due_at"] = nil
+	s.transition(ctx, subscription, updates, "payment_failed", models.SubscriptionPastDue,
+		fmt.Sprintf("Renewal payment %s was not completed in time, subscription marked as past due", subscription.PaymentOrderID))
+}
+
+func (s *Scheduler) transition(ctx context.Context, subscription *models.TutorSubscription, updates map[string]interface{},
+	eventType string, status models.SubscriptionStatus, notes string) bool {
+	return s.apply(ctx, s.subscriptionRepo.Transition, subscription, updates, eventType, status, notes)
+}
+
+// apply records eventType through write, Store.Transition or Store.TransitionPaid
+func (s *Scheduler) apply(ctx context.Context, write func(*models.TutorSubscription, map[string]interface{}, *models.SubscriptionEvent) (bool, error),
+	subscription *models.TutorSubscription, updates map[string]interface{}, eventType string, status models.SubscriptionStatus, notes string) bool {
+	event := &models.SubscriptionEvent{
+		SubscriptionID: subscription.ID,
+		EventType:      eventType,
+		PreviousStatus: subscription.Status,
+		CurrentStatus:  status,
+		Notes:          notes,
+	}
+	applied, err := write(subscription, updates, event)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update subscription", "subscription_id", subscription.ID, "event", eventType, "error", err)
+		return false
+	}
+	if applied {
+		metrics.RecordSchedulerTransition(eventType)
+		slog.InfoContext(ctx, "Subscription moved on", "subscription_id", subscription.ID, "event", eventType,
+			"previous_status", subscription.Status, "status", status)
+	}
+	return applied
+}
+
+// nextPeriod follows on from the current period, which keeps the billing date, unless the
+// service was down for so long that the next period would already be over too
+func nextPeriod(subscription *models.TutorSubscription, now time.Time) (time.Time, time.Time) {
+	start := subscription.CurrentPeriodEnd
+	end := nextPeriodEnd(start, subscription.BillingCycle)
+	if !end.After(now) {
+		start = now
+		end = nextPeriodEnd(now, subscription.BillingCycle)
+	}
+	return start, end
+}
+
+// charge is what the subscription's next billed period costs, less its coupon's discount
+func charge(subscription *models.TutorSubscription) float64 {
+	amount := price(&subscription.Plan, subscription.BillingCycle)
+	return math.Round((amount-subscription.Discount(amount))*100) / 100
+}
+
+// useCoupon counts a newly billed period against a coupon that lasts a number of them and
+// records how much of the period's price it took off
+func useCoupon(subscription *models.TutorSubscription, updates map[string]interface{}) {
+	amount := price(&subscription.Plan, subscription.BillingCycle)
+	updates["period_discount_rate"] = models.DiscountRate(subscription.Discount(amount), amount)
+	if subscription.CouponApplies() && subscription.CouponCyclesLeft != nil {
+		updates["coupon_cycles_left"] = *subscription.CouponCyclesLeft - 1
+	}
+}
+
+// couponNote names the coupon a charge was discounted with, for event notes
+func couponNote(subscription *models.TutorSubscription) string {
+	if !subscription.CouponApplies() {
+		return ""
+	}
+	return fmt.Sprintf(" with coupon %s", subscription.Coupon.Code)
+}
+
+func price(plan *models.SubscriptionPlan, cycle models.BillingCycle) float64 {
+	if cycle == models.BillingAnnually {
+		return plan.PriceAnnually
+	}
+	return plan.PriceMonthly
+}
+
+func nextPeriodEnd(start time.Time, cycle models.BillingCycle) time.Time {
+	if cycle == models.BillingAnnually {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,537 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"subscription/internal/config"
+	"subscription/internal/dunning"
+	"subscription/internal/models"
+	"testing"
+	"time"
+)
+
+// fakeRepo keeps subscriptions in memory and applies Transition the way the MySQL one does.
+// Like table rows, subs only hold a coupon's ID, the queries preload it from coupons.
+type fakeRepo struct {
+	subs    map[uint]*models.TutorSubscription
+	coupons map[uint]*models.Coupon
+	events  []models.SubscriptionEvent
+	// redeemed holds the subscriptions whose coupon TransitionPaid redeemed
+	redeemed []uint
+}
+
+func (r *fakeRepo) GetPeriodEnded(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var out []models.TutorSubscription
+	for _, sub := range r.subs {
+		if (sub.Status == models.SubscriptionActive || sub.Status == models.SubscriptionTrialing) && !sub.CurrentPeriodEnd.After(now) {
+			out = append(out, r.preload(sub))
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) GetRenewalOverdue(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var out []models.TutorSubscription
+	for _, sub := range r.subs {
+		if sub.Status == models.SubscriptionActive && sub.RenewalDueAt != nil && !sub.RenewalDueAt.After(now) {
+			out = append(out, r.preload(sub))
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) GetDunningDue(now time.Time, limit int) ([]models.TutorSubscription, error) {
+	var out []models.TutorSubscription
+	for _, sub := range r.subs {
+		if sub.Status == models.SubscriptionPastDue && (sub.NextDunningAt == nil || !sub.NextDunningAt.After(now)) {
+			out = append(out, r.preload(sub))
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) GetTrialEnding(before time.Time, limit int) ([]models.TutorSubscription, error) {
+	var out []models.TutorSubscription
+	for _, sub := range r.subs {
+		if sub.Status == models.SubscriptionTrialing && !sub.CurrentPeriodEnd.After(before) && !sub.CancelAtPeriodEnd && sub.TrialRemindedAt == nil {
+			out = append(out, r.preload(sub))
+		}
+	}
+	return out, nil
+}
+
+// preload is Preload("Coupon"), which every sweep query needs for discounts and redemptions
+func (r *fakeRepo) preload(sub *models.TutorSubscription) models.TutorSubscription {
+	out := *sub
+	if sub.CouponID != nil {
+		out.Coupon = r.coupons[*sub.CouponID]
+	}
+	return out
+}
+
+func (r *fakeRepo) LogEvent(event *models.SubscriptionEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *fakeRepo) Transition(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error) {
+	sub := r.subs[subscription.ID]
+	if sub.Status != subscription.Status || !sub.CurrentPeriodEnd.Equal(subscription.CurrentPeriodEnd) ||
+		sub.DunningAttempts != subscription.DunningAttempts {
+		return false, nil
+	}
+	for column, value := range updates {
+		switch column {
+		case "status":
+			sub.Status = value.(models.SubscriptionStatus)
+		case "current_period_start":
+			sub.CurrentPeriodStart = value.(time.Time)
+		case "current_period_end":
+			sub.CurrentPeriodEnd = value.(time.Time)
+		case "payment_order_id":
+			sub.PaymentOrderID = value.(string)
+		case "plan_id":
+			sub.PlanID = value.(uint)
+		case "dunning_attempts":
+			sub.DunningAttempts = value.(int)
+		case "renewal_due_at":
+			sub.RenewalDueAt = timeOrNil(value)
+		case "past_due_since":
+			sub.PastDueSince = timeOrNil(value)
+		case "next_dunning_at":
+			sub.NextDunningAt = timeOrNil(value)
+		case "trial_reminded_at":
+			sub.TrialRemindedAt = timeOrNil(value)
+		case "period_discount_rate":
+			sub.PeriodDiscountRate = value.(float64)
+		case "coupon_cycles_left":
+			left := value.(int)
+			sub.CouponCyclesLeft = &left
+		}
+	}
+	r.events = append(r.events, *event)
+	return true, nil
+}
+
+func (r *fakeRepo) TransitionPaid(subscription *models.TutorSubscription, updates map[string]interface{}, event *models.SubscriptionEvent) (bool, error) {
+	applied, err := r.Transition(subscription, updates, event)
+	if applied && subscription.Coupon != nil && !slices.Contains(r.redeemed, subscription.ID) {
+		r.redeemed = append(r.redeemed, subscription.ID)
+	}
+	return applied, err
+}
+
+func timeOrNil(value interface{}) *time.Time {
+	if value == nil {
+		return nil
+	}
+	t := value.(time.Time)
+	return &t
+}
+
+type fakePlans map[uint]*models.SubscriptionPlan
+
+func (p fakePlans) GetByID(id uint) (*models.SubscriptionPlan, error) {
+	if plan, ok := p[id]; ok {
+		return plan, nil
+	}
+	return nil, errors.New("plan not found")
+}
+
+type fakeNotifier struct {
+	sent   []string
+	bodies []string
+	err    error
+}
+
+func (n *fakeNotifier) NotifyTutor(ctx context.Context, tutorID uint, title, body string) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, title)
+	n.bodies = append(n.bodies, body)
+	return nil
+}
+
+type fakePayments struct {
+	createErr error
+	paid      bool
+	created   int
+	amount    float64
+}
+
+func (p *fakePayments) CreatePayment(ctx context.Context, tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error) {
+	if p.createErr != nil {
+		return "", "", p.createErr
+	}
+	p.created++
+	p.amount = amount
+	return "SUB-renewal", "https://paypal.test", nil
+}
+
+func (p *fakePayments) ValidatePaymentStatus(ctx context.Context, orderID string) (bool, error) {
+	return p.paid, nil
+}
+
+type fakeLocker struct{ held bool }
+
+func (l *fakeLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	l.held = true
+	return func() { l.held = false }, true, nil
+}
+
+func newTest(subs ...models.TutorSubscription) (*Scheduler, *fakeRepo, *fakePayments, *fakeLocker) {
+	s, repo, payments, locker, _ := newDunningTest(config.DunningConfig{
+		RetryAfter:  []time.Duration{24 * time.Hour},
+		Grace:       72 * time.Hour,
+		FinalAction: dunning.ActionCancel,
+	}, subs...)
+	return s, repo, payments, locker
+}
+
+func newDunningTest(dunningCfg config.DunningConfig, subs ...models.TutorSubscription) (*Scheduler, *fakeRepo, *fakePayments, *fakeLocker, *fakeNotifier) {
+	repo := &fakeRepo{subs: make(map[uint]*models.TutorSubscription), coupons: make(map[uint]*models.Coupon)}
+	for i := range subs {
+		if coupon := subs[i].Coupon; coupon != nil {
+			if coupon.ID == 0 {
+				coupon.ID = uint(len(repo.coupons) + 1)
+			}
+			repo.coupons[coupon.ID] = coupon
+			subs[i].CouponID = &coupon.ID
+			subs[i].Coupon = nil
+		}
+		repo.subs[subs[i].ID] = &subs[i]
+	}
+	plans := fakePlans{2: {Name: "Basic", PriceMonthly: 5}}
+	plans[2].ID = 2
+	payments := &fakePayments{}
+	notifier := &fakeNotifier{}
+	locker := &fakeLocker{}
+	cfg := config.RenewalConfig{Grace: 72 * time.Hour, BatchSize: 10, TrialReminder: 72 * time.Hour}
+	return NewScheduler(repo, plans, payments, notifier, locker, cfg, dunning.NewPolicy(dunningCfg)), repo, payments, locker, notifier
+}
+
+func ended(id uint, cancel bool) models.TutorSubscription {
+	sub := models.TutorSubscription{
+		Status:             models.SubscriptionActive,
+		CurrentPeriodStart: time.Now().AddDate(0, -1, -1),
+		CurrentPeriodEnd:   time.Now().Add(-time.Hour),
+		CancelAtPeriodEnd:  cancel,
+		BillingCycle:       models.BillingMonthly,
+		Plan:               models.SubscriptionPlan{PriceMonthly: 10},
+	}
+	sub.ID = id
+	return sub
+}
+
+func TestSweepCancelsAtPeriodEnd(t *testing.T) {
+	s, repo, payments, _ := newTest(ended(1, true))
+
+	s.Sweep(context.Background())
+
+	if got := repo.subs[1].Status; got != models.SubscriptionCanceled {
+		t.Errorf("status %s, want canceled", got)
+	}
+	if payments.created != 0 {
+		t.Errorf("created %d renewal payments for a canceled subscription", payments.created)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != "expired" {
+		t.Errorf("events %+v, want one expired", repo.events)
+	}
+}
+
+func TestSweepRenewsOnce(t *testing.T) {
+	s, repo, payments, _, notifier := newDunningTest(config.DunningConfig{}, ended(1, false))
+	oldEnd := repo.subs[1].CurrentPeriodEnd
+
+	s.Sweep(context.Background())
+	s.Sweep(context.Background())
+
+	sub := repo.subs[1]
+	if payments.created != 1 {
+		t.Errorf("created %d renewal payments, want 1", payments.created)
+	}
+	if sub.Status != models.SubscriptionActive || sub.RenewalDueAt == nil {
+		t.Errorf("status %s renewal_due_at %v, want active with a due date", sub.Status, sub.RenewalDueAt)
+	}
+	if !sub.CurrentPeriodStart.Equal(oldEnd) || !sub.CurrentPeriodEnd.Equal(oldEnd.AddDate(0, 1, 0)) {
+		t.Errorf("period %s to %s, want a month from %s", sub.CurrentPeriodStart, sub.CurrentPeriodEnd, oldEnd)
+	}
+	if sub.PaymentOrderID != "SUB-renewal" {
+		t.Errorf("payment order %q, want the renewal order", sub.PaymentOrderID)
+	}
+	if len(notifier.bodies) != 1 || !strings.Contains(notifier.bodies[0], "https://paypal.test") {
+		t.Errorf("sent %q, want one email with the payment link", notifier.bodies)
+	}
+}
+
+func TestSweepRenewsWithCoupon(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		left   int
+		amount float64
+	}{
+		{"discounts while cycles are left", 1, 8},
+		{"charges in full once they are used up", 0, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := ended(1, false)
+			sub.Coupon = &models.Coupon{Code: "SPRING20", DiscountType: models.CouponPercent, Amount: 20, Duration: models.CouponRepeating}
+			sub.CouponCyclesLeft = &tc.left
+			s, repo, payments, _ := newTest(sub)
+
+			s.Sweep(context.Background())
+
+			if payments.amount != tc.amount || *repo.subs[1].CouponCyclesLeft != 0 {
+				t.Errorf("charged %v with %d cycles left, want %v with none", payments.amount, *repo.subs[1].CouponCyclesLeft, tc.amount)
+			}
+		})
+	}
+}
+
+func TestSweepRenewsWithNothingToPayWithoutAPayment(t *testing.T) {
+	sub := ended(1, false)
+	sub.Coupon = &models.Coupon{Code: "FREE", DiscountType: models.CouponPercent, Amount: 100, Duration: models.CouponForever}
+	s, repo, payments, _ := newTest(sub)
+	oldEnd := repo.subs[1].CurrentPeriodEnd
+
+	s.Sweep(context.Background())
+
+	got := repo.subs[1]
+	if payments.created != 0 {
+		t.Errorf("created %d payments with nothing due, want none", payments.created)
+	}
+	if got.Status != models.SubscriptionActive || got.RenewalDueAt != nil || !got.CurrentPeriodEnd.Equal(oldEnd.AddDate(0, 1, 0)) {
+		t.Errorf("status %s renewal_due_at %v period end %s, want active, nothing due and renewed for a month",
+			got.Status, got.RenewalDueAt, got.CurrentPeriodEnd)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != "renewed" {
+		t.Errorf("events %+v, want one renewed", repo.events)
+	}
+}
+
+func TestSweepMovesFailedRenewalToPastDue(t *testing.T) {
+	s, repo, payments, _ := newTest(ended(1, false))
+	payments.createErr = errors.New("payment-service down")
+
+	s.Sweep(context.Background())
+
+	if got := repo.subs[1].Status; got != models.SubscriptionPastDue || repo.subs[1].PastDueSince == nil {
+		t.Errorf("status %s past_due_since %v, want past_due with dunning started", got, repo.subs[1].PastDueSince)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != "renewal_failed" {
+		t.Errorf("events %+v, want one renewal_failed", repo.events)
+	}
+}
+
+func TestSweepCollectsOverdueRenewal(t *testing.T) {
+	for _, paid := range []bool{true, false} {
+		sub := ended(1, false)
+		sub.CurrentPeriodEnd = time.Now().AddDate(0, 0, 20)
+		due := time.Now().Add(-time.Minute)
+		sub.RenewalDueAt = &due
+		s, repo, payments, _ := newTest(sub)
+		payments.paid = paid
+
+		s.Sweep(context.Background())
+
+		want := models.SubscriptionPastDue
+		if paid {
+			want = models.SubscriptionActive
+		}
+		if got := repo.subs[1].Status; got != want || repo.subs[1].RenewalDueAt != nil {
+			t.Errorf("paid %v: status %s renewal_due_at %v, want %s and cleared", paid, got, repo.subs[1].RenewalDueAt, want)
+		}
+	}
+}
+
+func TestSweepRedeemsCouponOnlyOncePaid(t *testing.T) {
+	sub := trial(1, time.Now().Add(-time.Minute))
+	sub.Coupon = &models.Coupon{Code: "SPRING20", DiscountType: models.CouponPercent, Amount: 20, Duration: models.CouponForever}
+	s, repo, payments, _ := newTest(sub)
+
+	s.Sweep(context.Background())
+	if len(repo.redeemed) != 0 {
+		t.Fatalf("redeemed %v when the trial converted, want nothing until its payment is in", repo.redeemed)
+	}
+
+	payments.paid = true
+	due := time.Now().Add(-time.Minute)
+	repo.subs[1].RenewalDueAt = &due
+	s.Sweep(context.Background())
+	s.Sweep(context.Background())
+
+	if !slices.Equal(repo.redeemed, []uint{1}) {
+		t.Errorf("redeemed %v, want the subscription's coupon once its first period was paid", repo.redeemed)
+	}
+}
+
+func TestSweepSkipsWhileAnotherReplicaHoldsTheLock(t *testing.T) {
+	s, repo, payments, locker := newTest(ended(1, false))
+	locker.held = true
+
+	s.Sweep(context.Background())
+
+	if payments.created != 0 || len(repo.events) != 0 {
+		t.Errorf("swept without the lock: %d payments, %d events", payments.created, len(repo.events))
+	}
+}
+
+// pastDue is a subscription that went past_due at since, with its next dunning step due already
+func pastDue(id uint, since time.Time) models.TutorSubscription {
+	sub := ended(id, false)
+	sub.Status = models.SubscriptionPastDue
+	sub.PlanID = 1
+	sub.Plan.Name = "Pro"
+	sub.PastDueSince = &since
+	due := since.Add(time.Hour)
+	sub.NextDunningAt = &due
+	return sub
+}
+
+func TestSweepDunsToCancel(t *testing.T) {
+	cfg := config.DunningConfig{
+		RetryAfter:  []time.Duration{time.Hour, 2 * time.Hour},
+		Grace:       3 * time.Hour,
+		FinalAction: dunning.ActionCancel,
+	}
+	s, repo, payments, _, notifier := newDunningTest(cfg, pastDue(1, time.Now().Add(-4*time.Hour)))
+
+	for i := 0; i < 4; i++ {
+		s.Sweep(context.Background())
+	}
+
+	sub := repo.subs[1]
+	if sub.Status != models.SubscriptionCanceled || sub.PastDueSince != nil || sub.NextDunningAt != nil {
+		t.Errorf("status %s past_due_since %v next_dunning_at %v, want canceled and cleared", sub.Status, sub.PastDueSince, sub.NextDunningAt)
+	}
+	if payments.created != 2 {
+		t.Errorf("created %d retry payments, want 2", payments.created)
+	}
+	var events []string
+	for _, event := range repo.events {
+		events = append(events, event.EventType)
+	}
+	if want := []string{"dunning_retry", "dunning_retry", "dunning_canceled"}; !slices.Equal(events, want) {
+		t.Errorf("events %v, want %v", events, want)
+	}
+	if len(notifier.sent) != 3 {
+		t.Errorf("sent %v, want a reminder per retry and a cancellation", notifier.sent)
+	}
+}
+
+func TestSweepDunsToDowngrade(t *testing.T) {
+	cfg := config.DunningConfig{
+		Grace:           time.Hour,
+		FinalAction:     dunning.ActionDowngrade,
+		DowngradePlanID: 2,
+	}
+	s, repo, _, _, notifier := newDunningTest(cfg, pastDue(1, time.Now().Add(-2*time.Hour)))
+	notifier.err = errors.New("google-service down")
+
+	s.Sweep(context.Background())
+
+	sub := repo.subs[1]
+	if sub.Status != models.SubscriptionActive || sub.PlanID != 2 || sub.PastDueSince != nil || sub.RenewalDueAt == nil {
+		t.Errorf("status %s plan %d renewal_due_at %v, want active on plan 2 with its payment due", sub.Status, sub.PlanID, sub.RenewalDueAt)
+	}
+	if len(repo.events) != 2 || repo.events[0].EventType != "dunning_downgraded" || repo.events[1].EventType != "notification_failed" {
+		t.Errorf("events %+v, want dunning_downgraded then notification_failed", repo.events)
+	}
+}
+
+func TestSweepStartsDunningForManualPastDue(t *testing.T) {
+	sub := pastDue(1, time.Now())
+	sub.PastDueSince, sub.NextDunningAt = nil, nil
+	s, repo, payments, _ := newTest(sub)
+
+	s.Sweep(context.Background())
+
+	if repo.subs[1].PastDueSince == nil || repo.subs[1].NextDunningAt == nil || payments.created != 0 {
+		t.Errorf("past_due_since %v next_dunning_at %v payments %d, want dunning started without a retry yet",
+			repo.subs[1].PastDueSince, repo.subs[1].NextDunningAt, payments.created)
+	}
+}
+
+// trial is a trialing subscription whose trial ends at end
+func trial(id uint, end time.Time) models.TutorSubscription {
+	sub := ended(id, false)
+	sub.Status = models.SubscriptionTrialing
+	sub.CurrentPeriodStart = end.AddDate(0, 0, -14)
+	sub.CurrentPeriodEnd = end
+	sub.TrialEndsAt = &end
+	return sub
+}
+
+func TestSweepRemindsOnceBeforeTrialEnds(t *testing.T) {
+	s, repo, _, _, notifier := newDunningTest(config.DunningConfig{}, trial(1, time.Now().AddDate(0, 0, 2)), trial(2, time.Now().AddDate(0, 0, 5)))
+
+	s.Sweep(context.Background())
+	s.Sweep(context.Background())
+
+	if repo.subs[1].TrialRemindedAt == nil || repo.subs[2].TrialRemindedAt != nil {
+		t.Errorf("reminded %v and %v, want only the trial ending within 3 days", repo.subs[1].TrialRemindedAt, repo.subs[2].TrialRemindedAt)
+	}
+	if len(notifier.sent) != 1 {
+		t.Errorf("sent %v, want one reminder", notifier.sent)
+	}
+}
+
+func TestSweepConvertsEndedTrial(t *testing.T) {
+	s, repo, payments, _, notifier := newDunningTest(config.DunningConfig{}, trial(1, time.Now().Add(-time.Minute)))
+	end := repo.subs[1].CurrentPeriodEnd
+
+	s.Sweep(context.Background())
+
+	sub := repo.subs[1]
+	if sub.Status != models.SubscriptionActive || sub.RenewalDueAt == nil || payments.created != 1 {
+		t.Errorf("status %s renewal_due_at %v payments %d, want active with its first payment due", sub.Status, sub.RenewalDueAt, payments.created)
+	}
+	if !sub.CurrentPeriodStart.Equal(end) || !sub.CurrentPeriodEnd.Equal(end.AddDate(0, 1, 0)) {
+		t.Errorf("period %s to %s, want a month from the trial end", sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+	}
+	if len(repo.events) != 1 || repo.events[0].EventType != "trial_converted" || len(notifier.sent) != 1 {
+		t.Errorf("events %+v sent %v, want trial_converted with an email", repo.events, notifier.sent)
+	}
+}
+
+func TestSweepConvertsFullyDiscountedTrialWithoutAPayment(t *testing.T) {
+	sub := trial(1, time.Now().Add(-time.Minute))
+	sub.Coupon = &models.Coupon{Code: "FREE", DiscountType: models.CouponPercent, Amount: 100, Duration: models.CouponOnce}
+	s, repo, payments, _, notifier := newDunningTest(config.DunningConfig{}, sub)
+
+	s.Sweep(context.Background())
+
+	got := repo.subs[1]
+	if payments.created != 0 || got.Status != models.SubscriptionActive || got.RenewalDueAt != nil {
+		t.Errorf("payments %d status %s renewal_due_at %v, want active with nothing to pay", payments.created, got.Status, got.RenewalDueAt)
+	}
+	if !slices.Equal(repo.redeemed, []uint{1}) {
+		t.Errorf("redeemed %v, want the coupon that paid for the first period", repo.redeemed)
+	}
+	if len(notifier.sent) != 1 {
+		t.Errorf("sent %v, want the trial ended email", notifier.sent)
+	}
+}
+
+func TestFeaturesStayOnThroughGrace(t *testing.T) {
+	policy := dunning.NewPolicy(config.DunningConfig{Grace: 72 * time.Hour})
+	now := time.Now()
+	for _, tc := range []struct {
+		since time.Time
+		want  bool
+	}{
+		{now.Add(-time.Hour), true},
+		{now.Add(-73 * time.Hour), false},
+	} {
+		sub := pastDue(1, tc.since)
+		if got := policy.FeaturesEnabled(&sub, now); got != tc.want {
+			t.Errorf("past_due since %s: features %v, want %v", tc.since, got, tc.want)
+		}
+	}
+}
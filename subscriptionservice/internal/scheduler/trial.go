@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"subscription/internal/models"
+	"time"
+)
+
+// convert bills the first paid period of a free trial that ended, like a renewal. A trial that
+// could not be billed goes past_due and into dunning, one with nothing to pay is settled at once.
+func (s *Scheduler) convert(ctx context.Context, subscription *models.TutorSubscription, now time.Time) {
+	amount := charge(subscription)
+	start, end := nextPeriod(subscription, now)
+
+	if amount <= 0 {
+		updates := map[string]interface{}{
+			"status":               models.SubscriptionActive,
+			"current_period_start": start,
+			"current_period_end":   end,
+			"renewal_due_at":       nil,
+		}
+		useCoupon(subscription, updates)
+		// Settled like a paid first period, which redeems the coupon that covers it
+		if !s.apply(ctx, s.subscriptionRepo.TransitionPaid, subscription, updates, "trial_converted", models.SubscriptionActive,
+			fmt.Sprintf("Trial ended, first period %s to %s with nothing to pay%s",
+				start.Format(dateFormat), end.Format(dateFormat), couponNote(subscription))) {
+			return
+		}
+		s.notify(ctx, subscription, models.SubscriptionActive, "Your free trial has ended",
+			fmt.Sprintf("Your free trial of the %s plan has ended and your %s subscription has started. "+
+				"There is nothing to pay for it until %s.", subscription.Plan.Name, subscription.BillingCycle, end.Format(dateFormat)))
+		return
+	}
+
+	orderID, link, err := s.paymentService.CreatePayment(ctx, subscription.TutorID, subscription.PlanID, amount, string(subscription.BillingCycle))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create trial conversion payment", "subscription_id", subscription.ID, "error", err)
+		s.transition(ctx, subscription, s.dunning.Start(now), "trial_conversion_failed", models.SubscriptionPastDue,
+			fmt.Sprintf("Payment for the first period after the trial could not be created: %v", err))
+		return
+	}
+
+	dueAt := now.Add(s.cfg.Grace)
+
+	updates := map[string]interface{}{
+		"status":               models.SubscriptionActive,
+		"current_period_start": start,
+		"current_period_end":   end,
+		"payment_order_id":     orderID,
+		"renewal_due_at":       dueAt,
+	}
+	useCoupon(subscription, updates)
+
+	applied := s.transition(ctx, subscription, updates, "trial_converted", models.SubscriptionActive,
+		fmt.Sprintf("Trial ended, first paid period %s to %s, payment %s of %.2f%s due by %s",
+			start.Format(dateFormat), end.Format(dateFormat), orderID, amount, couponNote(subscription), dueAt.Format("2006-01-02 15:04")))
+	if !applied {
+		slog.WarnContext(ctx, "Trial conversion payment created for a subscription that changed meanwhile", "subscription_id", subscription.ID, "order_id", orderID)
+		return
+	}
+
+	s.notify(ctx, subscription, models.SubscriptionActive, "Your free trial has ended",
+		fmt.Sprintf("Your free trial of the %s plan has ended and your %s subscription has started. "+
+			"Please complete its payment of %.2f by %s to keep your plan features: %s",
+			subscription.Plan.Name, subscription.BillingCycle, amount, dueAt.Format(dateFormat), link))
+}
+
+// remindTrial tells the tutor their trial ends soon and what it turns into
+func (s *Scheduler) remindTrial(ctx context.Context, subscription *models.TutorSubscription) {
+	if !s.transition(ctx, subscription, map[string]interface{}{
+		"trial_reminded_at": time.Now(),
+	}, "trial_reminder", models.SubscriptionTrialing,
+		fmt.Sprintf("Reminded that the trial ends on %s", subscription.CurrentPeriodEnd.Format(dateFormat))) {
+		return
+	}
+
+	s.notify(ctx, subscription, models.SubscriptionTrialing, "Your free trial ends soon",
+		fmt.Sprintf("Your free trial of the %s plan ends on %s. Your %s subscription then starts at %.2f and we will "+
+			"email you a link to pay for it. Cancel your subscription before then if you do not want to continue.",
+			subscription.Plan.Name, subscription.CurrentPeriodEnd.Format(dateFormat), subscription.BillingCycle,
+			charge(subscription)))
+}
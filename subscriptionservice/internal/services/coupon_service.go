@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"subscription/internal/models"
+	"subscription/internal/repository"
+)
+
+type CouponService interface {
+	CreateCoupon(req models.CouponRequest) (*models.Coupon, error)
+	GetCouponByID(id uint) (*models.Coupon, error)
+	GetAllCoupons(activeOnly bool) ([]models.Coupon, error)
+	UpdateCoupon(id uint, req models.CouponRequest) (*models.Coupon, error)
+	DeleteCoupon(id uint) error
+}
+
+type couponService struct {
+	couponRepo repository.CouponRepository
+}
+
+func NewCouponService(couponRepo repository.CouponRepository) CouponService {
+	return &couponService{
+		couponRepo: couponRepo,
+	}
+}
+
+func (s *couponService) CreateCoupon(req models.CouponRequest) (*models.Coupon, error) {
+	coupon := &models.Coupon{}
+	applyCouponRequest(coupon, req)
+
+	if err := s.couponRepo.Create(coupon); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (s *couponService) GetCouponByID(id uint) (*models.Coupon, error) {
+	return s.couponRepo.GetByID(id)
+}
+
+func (s *couponService) GetAllCoupons(activeOnly bool) ([]models.Coupon, error) {
+	return s.couponRepo.GetAll(activeOnly)
+}
+
+// UpdateCoupon replaces a coupon's terms. Subscriptions that already redeemed it are billed on
+// the new terms from their next renewal, times_redeemed is left as it is.
+func (s *couponService) UpdateCoupon(id uint, req models.CouponRequest) (*models.Coupon, error) {
+	existingCoupon, err := s.couponRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyCouponRequest(existingCoupon, req)
+
+	if err := s.couponRepo.Update(existingCoupon); err != nil {
+		return nil, err
+	}
+
+	return existingCoupon, nil
+}
+
+// DeleteCoupon soft-deletes a coupon, it can no longer be redeemed
+func (s *couponService) DeleteCoupon(id uint) error {
+	// Check if the coupon exists
+	if _, err := s.couponRepo.GetByID(id); err != nil {
+		return err
+	}
+
+	return s.couponRepo.Delete(id)
+}
+
+func applyCouponRequest(coupon *models.Coupon, req models.CouponRequest) {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	coupon.Description = req.Description
+	coupon.DiscountType = req.DiscountType
+	coupon.Amount = req.Amount
+	coupon.Duration = req.Duration
+	coupon.DurationCycles = 0
+	if req.Duration == models.CouponRepeating {
+		coupon.DurationCycles = req.DurationCycles
+	}
+	coupon.PlanIDsJSON = req.PlanIDs
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.ExpiresAt = req.ExpiresAt
+	coupon.IsActive = req.IsActive
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"platform/client"
+)
+
+// NotificationService emails tutors about their subscription
+type NotificationService interface {
+	NotifyTutor(ctx context.Context, tutorID uint, title, body string) error
+}
+
+type notificationService struct {
+	users  *client.UserClient
+	emails *client.EmailClient
+}
+
+// NewNotificationService looks addresses up in user-service and sends through google-service
+func NewNotificationService(userServiceURL, googleServiceURL, apiKey string) NotificationService {
+	return &notificationService{
+		users:  client.NewUserClient(userServiceURL, apiKey),
+		emails: client.NewEmailClient(googleServiceURL, apiKey),
+	}
+}
+
+// NotifyTutor emails the account behind tutorID, tutors share their id with their user
+func (s *notificationService) NotifyTutor(ctx context.Context, tutorID uint, title, body string) error {
+	user, err := s.users.ByID(ctx, tutorID)
+	if err != nil {
+		return fmt.Errorf("failed to look up tutor %d: %w", tutorID, err)
+	}
+	if err := s.emails.Send(ctx, user.Email, title, body); err != nil {
+		return fmt.Errorf("failed to email tutor %d: %w", tutorID, err)
+	}
+	return nil
+}
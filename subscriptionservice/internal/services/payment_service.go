@@ -1,119 +1,65 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"subscription/utils"
+	"platform/client"
+	"platform/contracts"
 	"time"
-
-	"github.com/valyala/fasthttp"
 )
 
 type PaymentService interface {
-	CreatePayment(tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error)
-	GetPaymentByOrderID(orderID string) (*PaymentInfo, error)
-	ValidatePaymentStatus(orderID string) (bool, error)
+	CreatePayment(ctx context.Context, tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error)
+	GetPaymentByOrderID(ctx context.Context, orderID string) (*PaymentInfo, error)
+	ValidatePaymentStatus(ctx context.Context, orderID string) (bool, error)
 }
 
 // PaymentInfo represents payment information returned from payment service
-type PaymentInfo struct {
-	OrderID       string    `json:"order_id"`
-	Amount        float64   `json:"amount"`
-	Status        string    `json:"status"`
-	PaymentMethod string    `json:"payment_method"`
-	TransactionID string    `json:"transaction_id"`
-	CreatedAt     time.Time `json:"created_at"`
-}
+type PaymentInfo = contracts.Payment
 
 type paymentService struct {
-	paymentServiceURL string
-	apiKey            string
+	payments *client.PaymentClient
 }
 
 // NewPaymentService creates a new instance of PaymentService
 func NewPaymentService(paymentServiceURL, apiKey string) PaymentService {
 	return &paymentService{
-		paymentServiceURL: paymentServiceURL,
-		apiKey:            apiKey,
+		payments: client.NewPaymentClient(paymentServiceURL, apiKey),
 	}
 }
 
 // CreatePayment creates a new payment for a subscription
-func (s *paymentService) CreatePayment(tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error) {
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
-
+func (s *paymentService) CreatePayment(ctx context.Context, tutorID uint, planID uint, amount float64, billingCycle string) (string, string, error) {
 	// Generate order ID, format SUB-{tutorID}-{planID}-{timestamp}
 	orderID := fmt.Sprintf("SUB-%d-%d-%d", tutorID, planID, time.Now().Unix())
+	description := fmt.Sprintf("Subscription payment - %s plan (%s)", getPlanName(planID), billingCycle)
 
-	query := fmt.Sprintf("?amount=%f&description=%s&orderId=%s",
-		amount,
-		fmt.Sprintf("Subscription payment - %s plan (%s)", getPlanName(planID), billingCycle),
-		orderID)
-
-	url := fmt.Sprintf("%s/api/payment/paypal/create%s", s.paymentServiceURL, query)
-	fmt.Println(url)
-	// Send request to payment service
-	utils.BuildRequest(req, "POST", nil, s.apiKey, url)
-
-	if err := fasthttp.Do(req, resp); err != nil {
-		return "", "", fmt.Errorf("payment service unavailable: %w", err)
-	}
-	if resp.StatusCode() != fasthttp.StatusOK {
-		return "", "", fmt.Errorf("failed to create payment: %s", resp.Body())
-	}
-
-	// Parse response to get redirect URL
-	var response struct {
-		RedirectURL string `json:"redirectUrl"`
-		PaymentID   string `json:"paymentId"`
-	}
-	if err := json.Unmarshal(resp.Body(), &response); err != nil {
-		return "", "", fmt.Errorf("failed to parse response: %w", err)
+	redirectURL, err := s.payments.CreatePayPal(ctx, orderID, amount, description)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create payment: %w", err)
 	}
 
 	// Return the order ID and redirect URL
-	return orderID, response.RedirectURL, nil
+	return orderID, redirectURL, nil
 }
 
 // GetPaymentByOrderID gets payment details by order ID
-func (s *paymentService) GetPaymentByOrderID(orderID string) (*PaymentInfo, error) {
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
-
-	// Send request to payment service
-	utils.BuildRequest(req, "GET", nil, s.apiKey, fmt.Sprintf("%s/api/payment/order/%s", s.paymentServiceURL, orderID))
-
-	if err := fasthttp.Do(req, resp); err != nil {
-		return nil, fmt.Errorf("payment service unavailable: %w", err)
-	}
-
-	if resp.StatusCode() != fasthttp.StatusOK {
-		return nil, fmt.Errorf("failed to get payment: %s", resp.Body())
-	}
-
-	// Parse response
-	var payment PaymentInfo
-	if err := json.Unmarshal(resp.Body(), &payment); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+func (s *paymentService) GetPaymentByOrderID(ctx context.Context, orderID string) (*PaymentInfo, error) {
+	payment, err := s.payments.Order(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
 	}
-
-	return &payment, nil
+	return payment, nil
 }
 
 // ValidatePaymentStatus checks if a payment has been completed successfully
-func (s *paymentService) ValidatePaymentStatus(orderID string) (bool, error) {
-	payment, err := s.GetPaymentByOrderID(orderID)
+func (s *paymentService) ValidatePaymentStatus(ctx context.Context, orderID string) (bool, error) {
+	payment, err := s.GetPaymentByOrderID(ctx, orderID)
 	if err != nil {
 		return false, err
 	}
 
-	// Check if payment status is "COMPLETED"
-	return payment.Status == "COMPLETED", nil
+	return payment.Status == contracts.PaymentCompleted, nil
 }
 
 // Helper function to map plan IDs to names
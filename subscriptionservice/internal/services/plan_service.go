@@ -32,6 +32,7 @@ func (s *planService) CreatePlan(req models.PlanRequest) (*models.SubscriptionPl
 		PriceAnnually:  req.PriceAnnually,
 		MaxCourses:     req.MaxCourses,
 		CommissionRate: req.CommissionRate,
+		TrialDays:      req.TrialDays,
 		FeaturesJSON:   req.Features,
 		IsActive:       req.IsActive,
 	}
@@ -66,6 +67,7 @@ func (s *planService) UpdatePlan(id uint, req models.PlanRequest) (*models.Subsc
 	existingPlan.PriceAnnually = req.PriceAnnually
 	existingPlan.MaxCourses = req.MaxCourses
 	existingPlan.CommissionRate = req.CommissionRate
+	existingPlan.TrialDays = req.TrialDays
 	existingPlan.FeaturesJSON = req.Features
 	existingPlan.IsActive = req.IsActive
 
@@ -1,43 +1,57 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"log/slog"
+	"math"
+	"platform/contracts"
 	"strings"
+	"subscription/internal/dunning"
+	"subscription/internal/metrics"
 	"subscription/internal/models"
+	"subscription/internal/proration"
 	"subscription/internal/repository"
 	"time"
 )
 
 type SubscriptionService interface {
 	InitiateSubscription(req models.SubscriptionRequest) (*models.SubscriptionResponse, error)
-	ConfirmSubscription(req models.PaymentConfirmationRequest) (*models.SubscriptionResponse, error)
+	ConfirmSubscription(ctx context.Context, req models.PaymentConfirmationRequest) (*models.SubscriptionResponse, error)
 	GetSubscriptionByID(id uint) (*models.SubscriptionResponse, error)
 	GetTutorSubscription(tutorID uint) (*models.SubscriptionResponse, error)
 	GetAllSubscriptions(page, pageSize int, filters map[string]interface{}) ([]models.SubscriptionResponse, int64, error)
 	CancelSubscription(id uint) error
-	ChangePlan(id uint, req models.ChangePlanRequest) (*models.SubscriptionResponse, error)
+	ChangePlan(ctx context.Context, id uint, req models.ChangePlanRequest) (*models.SubscriptionResponse, error)
+	PreviewPlanChange(id uint, req models.ChangePlanRequest) (*models.PlanChangeQuote, error)
 	UpdateSubscriptionStatus(id uint, status models.SubscriptionStatus) error
-	ProcessPaymentWebhook(payload models.PaymentWebhookPayload) error
+	ProcessPaymentWebhook(ctx context.Context, payload models.PaymentWebhookPayload) error
 	GetExpiringSoonSubscriptions(days int) ([]models.SubscriptionResponse, error)
+	GetSubscriptionEvents(id uint) ([]models.SubscriptionEvent, error)
 }
 
 type subscriptionService struct {
 	subscriptionRepo repository.SubscriptionRepository
 	planRepo         repository.PlanRepository
+	couponRepo       repository.CouponRepository
 	paymentService   PaymentService
+	dunning          dunning.Policy
 }
 
 func NewSubscriptionService(
 	subscriptionRepo repository.SubscriptionRepository,
 	planRepo repository.PlanRepository,
+	couponRepo repository.CouponRepository,
 	paymentService PaymentService,
+	policy dunning.Policy,
 ) SubscriptionService {
 	return &subscriptionService{
 		subscriptionRepo: subscriptionRepo,
 		planRepo:         planRepo,
+		couponRepo:       couponRepo,
 		paymentService:   paymentService,
+		dunning:          policy,
 	}
 }
 
@@ -60,6 +74,24 @@ func (s *subscriptionService) InitiateSubscription(req models.SubscriptionReques
 		}
 	}
 
+	var coupon *models.Coupon
+	if req.CouponCode != "" {
+		if coupon, err = s.redeemableCoupon(req.CouponCode, req.TutorID, plan.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A plan with a free trial starts without a payment, once per tutor
+	if plan.TrialDays > 0 {
+		hadTrial, err := s.subscriptionRepo.HasHadTrial(req.TutorID)
+		if err != nil {
+			return nil, err
+		}
+		if !hadTrial {
+			return s.startTrial(req, plan, coupon)
+		}
+	}
+
 	// Calculate subscription period
 	now := time.Now()
 	var endDate time.Time
@@ -88,8 +120,22 @@ func (s *subscriptionService) InitiateSubscription(req models.SubscriptionReques
 		PaymentOrderID:     orderID, // Reference to payment order ID
 	}
 
-	if err := s.subscriptionRepo.Create(subscription); err != nil {
-		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	// The first period is the coupon's first discounted one
+	var discount float64
+	notes := fmt.Sprintf("Subscription initiated with %s billing cycle", req.BillingCycle)
+	if coupon != nil {
+		discount = coupon.Discount(amount)
+		subscription.CouponID = &coupon.ID
+		subscription.PeriodDiscountRate = models.DiscountRate(discount, amount)
+		if cycles := coupon.Cycles(); cycles != nil {
+			left := *cycles - 1
+			subscription.CouponCyclesLeft = &left
+		}
+		notes += fmt.Sprintf(", coupon %s takes %.2f off", coupon.Code, discount)
+	}
+
+	if err := s.createSubscription(subscription); err != nil {
+		return nil, err
 	}
 
 	// Log subscription creation event
@@ -97,11 +143,11 @@ func (s *subscriptionService) InitiateSubscription(req models.SubscriptionReques
 		SubscriptionID: subscription.ID,
 		EventType:      "initiated",
 		CurrentStatus:  models.SubscriptionIncomplete,
-		Notes:          fmt.Sprintf("Subscription initiated with %s billing cycle", req.BillingCycle),
+		Notes:          notes,
 	}
 	if err := s.subscriptionRepo.LogEvent(event); err != nil {
 		// Just log the error but continue
-		fmt.Printf("Failed to log subscription event: %v\n", err)
+		slog.Error("Failed to log subscription event", "error", err)
 	}
 
 	// Create response
@@ -120,102 +166,54 @@ func (s *subscriptionService) InitiateSubscription(req models.SubscriptionReques
 		CommissionRate:     plan.CommissionRate,
 		PaymentOrderID:     orderID,
 	}
+	s.withStatus(response, subscription)
+	if coupon != nil {
+		response.CouponCode = coupon.Code
+		response.Discount = discount
+	}
+	amountDue := math.Round((amount-discount)*100) / 100
+	response.AmountDue = &amountDue
 
 	return response, nil
 }
 
-// ConfirmSubscription finalizes a subscription after successful payment
-func (s *subscriptionService) ConfirmSubscription(req models.PaymentConfirmationRequest) (*models.SubscriptionResponse, error) {
-	// Find subscription by payment order ID
-	//var subscriptions []models.TutorSubscription
-	//var count int64
-	//filters := map[string]interface{}{
-	//	"payment_order_id": req.OrderID,
-	//}
-
-	//subscriptions, count, err := s.subscriptionRepo.GetAll(1, 1, filters)
-	//if err != nil {
-	//	return nil, err
-	//}
-
-	//if count == 0 || len(subscriptions) == 0 {
-	//	return nil, errors.New("subscription not found for this payment order ID")
-	//}
-
-	order_id_split := strings.Split(req.OrderID, "-")
-	tutor_id_str := order_id_split[1]
-	tutor_id, _ := strconv.Atoi(tutor_id_str)
-	fmt.Println(tutor_id, "DEBUG SubscriptionFOUND")
-
-	subscription, _ := s.subscriptionRepo.GetByTutorID(uint(tutor_id))
-	fmt.Println(subscription.PaymentOrderID, "DEBUG SubscriptionFOUND")
-
-	// Make sure it's not already active
-	if subscription.Status == models.SubscriptionActive {
-		plan, err := s.planRepo.GetByID(subscription.PlanID)
-		if err != nil {
-			return nil, err
-		}
-
-		var price float64
-		if subscription.BillingCycle == models.BillingMonthly {
-			price = plan.PriceMonthly
-		} else {
-			price = plan.PriceAnnually
-		}
+// startTrial creates a trialing subscription, the scheduler bills its first period when it ends.
+// A coupon's discounted periods start with that first billed one.
+func (s *subscriptionService) startTrial(req models.SubscriptionRequest, plan *models.SubscriptionPlan, coupon *models.Coupon) (*models.SubscriptionResponse, error) {
+	now := time.Now()
+	trialEnd := now.AddDate(0, 0, plan.TrialDays)
 
-		return &models.SubscriptionResponse{
-			ID:                 subscription.ID,
-			TutorID:            subscription.TutorID,
-			PlanName:           plan.Name,
-			Status:             subscription.Status,
-			CurrentPeriodStart: subscription.CurrentPeriodStart,
-			CurrentPeriodEnd:   subscription.CurrentPeriodEnd,
-			CancelAtPeriodEnd:  subscription.CancelAtPeriodEnd,
-			BillingCycle:       subscription.BillingCycle,
-			Price:              price,
-			Features:           plan.FeaturesJSON,
-			MaxCourses:         plan.MaxCourses,
-			CommissionRate:     plan.CommissionRate,
-		}, nil
+	subscription := &models.TutorSubscription{
+		TutorID:            req.TutorID,
+		PlanID:             plan.ID,
+		Status:             models.SubscriptionTrialing,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   trialEnd,
+		BillingCycle:       req.BillingCycle,
+		TrialEndsAt:        &trialEnd,
+	}
+	notes := fmt.Sprintf("%d day free trial until %s, then %s billing", plan.TrialDays, trialEnd.Format("2006-01-02"), req.BillingCycle)
+	if coupon != nil {
+		subscription.CouponID = &coupon.ID
+		subscription.CouponCyclesLeft = coupon.Cycles()
+		notes += " with coupon " + coupon.Code
 	}
 
-	// Update subscription status to active
-	oldStatus := subscription.Status
-	subscription.Status = models.SubscriptionActive
-
-	if err := s.subscriptionRepo.Update(subscription); err != nil {
-		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	if err := s.createSubscription(subscription); err != nil {
+		return nil, err
 	}
+	metrics.RecordTrialStarted(string(req.BillingCycle))
 
-	// Log status change event
 	event := &models.SubscriptionEvent{
 		SubscriptionID: subscription.ID,
-		EventType:      "payment_confirmed",
-		PreviousStatus: oldStatus,
-		CurrentStatus:  models.SubscriptionActive,
-		Notes:          fmt.Sprintf("Payment confirmed with PaymentID: %s, PayerID: %s", req.PaymentID, req.PayerID),
+		EventType:      "trial_started",
+		CurrentStatus:  models.SubscriptionTrialing,
+		Notes:          notes,
 	}
-
 	if err := s.subscriptionRepo.LogEvent(event); err != nil {
-		fmt.Printf("Failed to log subscription event: %v\n", err)
-	}
-
-	// Get plan details for response
-	plan, err := s.planRepo.GetByID(subscription.PlanID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Determine price based on billing cycle
-	var price float64
-	if subscription.BillingCycle == models.BillingMonthly {
-		price = plan.PriceMonthly
-	} else {
-		price = plan.PriceAnnually
+		slog.Error("Failed to log subscription event", "error", err)
 	}
 
-	// Create response
 	response := &models.SubscriptionResponse{
 		ID:                 subscription.ID,
 		TutorID:            subscription.TutorID,
@@ -225,15 +223,185 @@ func (s *subscriptionService) ConfirmSubscription(req models.PaymentConfirmation
 		CurrentPeriodEnd:   subscription.CurrentPeriodEnd,
 		CancelAtPeriodEnd:  subscription.CancelAtPeriodEnd,
 		BillingCycle:       subscription.BillingCycle,
-		Price:              price,
+		Price:              proration.Price(plan, req.BillingCycle),
 		Features:           plan.FeaturesJSON,
 		MaxCourses:         plan.MaxCourses,
 		CommissionRate:     plan.CommissionRate,
 	}
+	s.withStatus(response, subscription)
+	if coupon != nil {
+		response.CouponCode = coupon.Code
+		response.Discount = coupon.Discount(response.Price)
+	}
+	// Nothing is paid until the trial converts
+	var amountDue float64
+	response.AmountDue = &amountDue
 
 	return response, nil
 }
 
+// createSubscription saves a new subscription. Its coupon was only checked, it is redeemed once
+// the first period is paid for.
+func (s *subscriptionService) createSubscription(subscription *models.TutorSubscription) error {
+	if err := s.subscriptionRepo.Create(subscription); err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return nil
+}
+
+// redeemableCoupon finds the coupon behind code and checks the tutor can redeem it on planID
+func (s *subscriptionService) redeemableCoupon(code string, tutorID, planID uint) (*models.Coupon, error) {
+	coupon, err := s.couponRepo.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if err := coupon.Redeemable(planID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	redeemed, err := s.couponRepo.HasRedeemed(coupon.ID, tutorID)
+	if err != nil {
+		return nil, err
+	}
+	if redeemed {
+		return nil, errors.New("coupon has already been redeemed")
+	}
+	return coupon, nil
+}
+
+// ErrPaymentOrderNotFound is a confirmation for an order no subscription is waiting on, such as
+// a stale renewal payment the scheduler has since replaced
+var ErrPaymentOrderNotFound = errors.New("subscription not found for this payment order ID")
+
+// ErrPaymentNotCompleted is a confirmation for an order payment-service does not have as paid
+var ErrPaymentNotCompleted = errors.New("payment has not been completed")
+
+// ErrPaymentAmountMismatch is a completed order for a different amount than was quoted
+var ErrPaymentAmountMismatch = errors.New("payment amount does not match the amount due")
+
+// ConfirmSubscription finalizes a subscription after successful payment
+func (s *subscriptionService) ConfirmSubscription(ctx context.Context, req models.PaymentConfirmationRequest) (*models.SubscriptionResponse, error) {
+	// A plan change is paid for separately from the subscription itself
+	change, err := s.subscriptionRepo.GetPlanChangeByOrderID(req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if change != nil {
+		return s.confirmPlanChange(ctx, change)
+	}
+
+	// Find subscription by payment order ID, only the order it is currently waiting on confirms it
+	filters := map[string]interface{}{
+		"payment_order_id": req.OrderID,
+	}
+	subscriptions, count, err := s.subscriptionRepo.GetAll(1, 1, filters)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || len(subscriptions) == 0 {
+		return nil, ErrPaymentOrderNotFound
+	}
+	subscription := &subscriptions[0]
+
+	switch {
+	case subscription.Status == models.SubscriptionCanceled:
+		return nil, errors.New("subscription has been canceled")
+
+	// A renewal the scheduler started is paid for while the subscription stays active
+	case subscription.Status == models.SubscriptionActive && subscription.RenewalDueAt != nil:
+		// Checked like the scheduler's collect, the order id alone proves nothing was paid
+		paid, err := s.paymentService.ValidatePaymentStatus(ctx, req.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if !paid {
+			return nil, ErrPaymentNotCompleted
+		}
+		if err := s.confirmTransition(subscription, map[string]interface{}{
+			"renewal_due_at": nil,
+		}, "renewed", fmt.Sprintf("Renewal payment %s confirmed", req.OrderID)); err != nil {
+			return nil, err
+		}
+		subscription.RenewalDueAt = nil
+
+	// Already confirmed, nothing left to do
+	case subscription.Status == models.SubscriptionActive:
+
+	default:
+		// Paying during dunning ends it. A renewal that could not be billed left the period over,
+		// the tutor gets a full one from now rather than being renewed again at the next sweep.
+		updates := map[string]interface{}{
+			"status":           models.SubscriptionActive,
+			"renewal_due_at":   nil,
+			"past_due_since":   nil,
+			"dunning_attempts": 0,
+			"next_dunning_at":  nil,
+		}
+		if now := time.Now(); subscription.Status == models.SubscriptionPastDue && !subscription.CurrentPeriodEnd.After(now) {
+			updates["current_period_start"] = now
+			updates["current_period_end"] = proration.PeriodEnd(now, subscription.BillingCycle)
+		}
+		if err := s.confirmTransition(subscription, updates, "payment_confirmed",
+			fmt.Sprintf("Payment confirmed with PaymentID: %s, PayerID: %s", req.PaymentID, req.PayerID)); err != nil {
+			return nil, err
+		}
+		metrics.RecordSubscriptionActivated(string(subscription.BillingCycle))
+
+		subscription.Status = models.SubscriptionActive
+		subscription.RenewalDueAt = nil
+		subscription.PastDueSince = nil
+		subscription.DunningAttempts = 0
+		subscription.NextDunningAt = nil
+		if start, ok := updates["current_period_start"].(time.Time); ok {
+			subscription.CurrentPeriodStart = start
+			subscription.CurrentPeriodEnd = updates["current_period_end"].(time.Time)
+		}
+	}
+
+	return s.subscriptionResponse(subscription), nil
+}
+
+// confirmTransition applies a confirmed payment to subscription through the same guarded
+// transition the scheduler uses, so neither overwrites a change the other just made. The first
+// paid period redeems the subscription's coupon.
+func (s *subscriptionService) confirmTransition(subscription *models.TutorSubscription, updates map[string]interface{}, eventType, notes string) error {
+	event := &models.SubscriptionEvent{
+		SubscriptionID: subscription.ID,
+		EventType:      eventType,
+		PreviousStatus: subscription.Status,
+		CurrentStatus:  models.SubscriptionActive,
+		Notes:          notes,
+	}
+	applied, err := s.subscriptionRepo.TransitionPaid(subscription, updates, event)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	if !applied {
+		return errors.New("subscription changed while confirming its payment, please try again")
+	}
+	return nil
+}
+
+// subscriptionResponse describes subscription on its current plan, which has to be preloaded
+func (s *subscriptionService) subscriptionResponse(subscription *models.TutorSubscription) *models.SubscriptionResponse {
+	response := &models.SubscriptionResponse{
+		ID:                 subscription.ID,
+		TutorID:            subscription.TutorID,
+		PlanName:           subscription.Plan.Name,
+		Status:             subscription.Status,
+		CurrentPeriodStart: subscription.CurrentPeriodStart,
+		CurrentPeriodEnd:   subscription.CurrentPeriodEnd,
+		CancelAtPeriodEnd:  subscription.CancelAtPeriodEnd,
+		BillingCycle:       subscription.BillingCycle,
+		Price:              proration.Price(&subscription.Plan, subscription.BillingCycle),
+		Features:           subscription.Plan.FeaturesJSON,
+		MaxCourses:         subscription.Plan.MaxCourses,
+		CommissionRate:     subscription.Plan.CommissionRate,
+	}
+	s.withStatus(response, subscription)
+	return response
+}
+
 // GetSubscriptionByID retrieves a subscription by its ID
 func (s *subscriptionService) GetSubscriptionByID(id uint) (*models.SubscriptionResponse, error) {
 	subscription, err := s.subscriptionRepo.GetByID(id)
@@ -248,7 +416,7 @@ func (s *subscriptionService) GetSubscriptionByID(id uint) (*models.Subscription
 		price = subscription.Plan.PriceAnnually
 	}
 
-	return &models.SubscriptionResponse{
+	response := &models.SubscriptionResponse{
 		ID:                 subscription.ID,
 		TutorID:            subscription.TutorID,
 		PlanName:           subscription.Plan.Name,
@@ -261,7 +429,9 @@ func (s *subscriptionService) GetSubscriptionByID(id uint) (*models.Subscription
 		Features:           subscription.Plan.FeaturesJSON,
 		MaxCourses:         subscription.Plan.MaxCourses,
 		CommissionRate:     subscription.Plan.CommissionRate,
-	}, nil
+	}
+	s.withStatus(response, subscription)
+	return response, nil
 }
 
 // GetTutorSubscription retrieves a tutor's active subscription
@@ -278,7 +448,7 @@ func (s *subscriptionService) GetTutorSubscription(tutorID uint) (*models.Subscr
 		price = subscription.Plan.PriceAnnually
 	}
 
-	return &models.SubscriptionResponse{
+	response := &models.SubscriptionResponse{
 		ID:                 subscription.ID,
 		TutorID:            subscription.TutorID,
 		PlanName:           subscription.Plan.Name,
@@ -292,7 +462,9 @@ func (s *subscriptionService) GetTutorSubscription(tutorID uint) (*models.Subscr
 		MaxCourses:         subscription.Plan.MaxCourses,
 		CommissionRate:     subscription.Plan.CommissionRate,
 		PaymentOrderID:     subscription.PaymentOrderID,
-	}, nil
+	}
+	s.withStatus(response, subscription)
+	return response, nil
 }
 
 // GetAllSubscriptions retrieves all subscriptions with pagination and filters
@@ -325,6 +497,7 @@ func (s *subscriptionService) GetAllSubscriptions(page, pageSize int, filters ma
 			MaxCourses:         subscription.Plan.MaxCourses,
 			CommissionRate:     subscription.Plan.CommissionRate,
 		}
+		s.withStatus(&responses[i], &subscription)
 	}
 
 	return responses, total, nil
@@ -356,7 +529,7 @@ func (s *subscriptionService) CancelSubscription(id uint) error {
 		}
 
 		if err := s.subscriptionRepo.LogEvent(event); err != nil {
-			fmt.Printf("Failed to log subscription event: %v\n", err)
+			slog.Error("Failed to log subscription event", "error", err)
 		}
 
 		return nil
@@ -377,90 +550,260 @@ func (s *subscriptionService) CancelSubscription(id uint) error {
 	}
 
 	if err := s.subscriptionRepo.LogEvent(event); err != nil {
-		fmt.Printf("Failed to log subscription event: %v\n", err)
+		slog.Error("Failed to log subscription event", "error", err)
 	}
 
 	return nil
 }
 
-// ChangePlan changes a subscription's plan
-func (s *subscriptionService) ChangePlan(id uint, req models.ChangePlanRequest) (*models.SubscriptionResponse, error) {
-	subscription, err := s.subscriptionRepo.GetByID(id)
+// ChangePlan moves a subscription to another plan or billing cycle at a prorated price. When
+// something is due a payment is created and the change applies once it is confirmed, otherwise
+// it applies straight away.
+func (s *subscriptionService) ChangePlan(ctx context.Context, id uint, req models.ChangePlanRequest) (*models.SubscriptionResponse, error) {
+	subscription, change, err := s.quotePlanChange(id, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if change.AmountDue == 0 {
+		if err := s.applyPlanChange(subscription, change, "Nothing due"); err != nil {
+			return nil, err
+		}
+		return s.planChangeResponse(subscription.ID, change, "")
+	}
+
+	orderID, paymentURL, err := s.paymentService.CreatePayment(ctx, subscription.TutorID, change.ToPlanID, change.AmountDue, string(req.BillingCycle))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan change payment: %w", err)
+	}
+	change.PaymentOrderID = &orderID
+
+	if err := s.subscriptionRepo.CreatePlanChange(change); err != nil {
+		return nil, fmt.Errorf("failed to save plan change: %w", err)
+	}
+
+	// Log plan change initiated event
+	event := &models.SubscriptionEvent{
+		SubscriptionID: subscription.ID,
+		EventType:      "plan_change_initiated",
+		PreviousStatus: subscription.Status,
+		CurrentStatus:  subscription.Status,
+		Notes: fmt.Sprintf("Plan change from ID %d (%s) to ID %d (%s): credit %.2f, charge %.2f, discount %.2f, %.2f due. Order ID: %s",
+			change.FromPlanID, change.FromBillingCycle, change.ToPlanID, change.ToBillingCycle,
+			change.Credit, change.Charge, change.Discount, change.AmountDue, orderID),
+	}
+
+	if err := s.subscriptionRepo.LogEvent(event); err != nil {
+		slog.Error("Failed to log subscription event", "error", err)
+	}
+
+	return s.planChangeResponse(subscription.ID, change, paymentURL)
+}
+
+// PreviewPlanChange quotes a plan change without starting it
+func (s *subscriptionService) PreviewPlanChange(id uint, req models.ChangePlanRequest) (*models.PlanChangeQuote, error) {
+	_, change, err := s.quotePlanChange(id, req, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
+	quote := change.Contract("").PlanChangeQuote
+	return &quote, nil
+}
+
+// quotePlanChange checks that the subscription can change to the requested plan and prices it
+// as a pending change
+func (s *subscriptionService) quotePlanChange(id uint, req models.ChangePlanRequest, now time.Time) (*models.TutorSubscription, *models.PlanChange, error) {
+	subscription, err := s.subscriptionRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Only active subscriptions can be changed
 	if subscription.Status != models.SubscriptionActive {
-		return nil, errors.New("only active subscriptions can be changed")
+		return nil, nil, errors.New("only active subscriptions can be changed")
+	}
+	// The credit is for a period that has to have been paid for
+	if subscription.RenewalDueAt != nil {
+		return nil, nil, errors.New("the renewal payment has to be completed before changing plan")
 	}
 
 	// Get the new plan
 	newPlan, err := s.planRepo.GetByID(req.NewPlanID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if !newPlan.IsActive {
+		return nil, nil, errors.New("plan is not available")
+	}
+	if newPlan.ID == subscription.PlanID && req.BillingCycle == subscription.BillingCycle {
+		return nil, nil, errors.New("subscription is already on this plan and billing cycle")
 	}
 
-	// Calculate the new end date and price
-	var newEndDate time.Time
-	var amount float64
-
-	if req.BillingCycle == models.BillingMonthly {
-		newEndDate = time.Now().AddDate(0, 1, 0) // Add 1 month
-		amount = newPlan.PriceMonthly
-	} else {
-		newEndDate = time.Now().AddDate(1, 0, 0) // Add 1 year
-		amount = newPlan.PriceAnnually
+	coupon, err := s.planChangeCoupon(subscription, newPlan.ID, req.CouponCode)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	fmt.Println(newEndDate)
+	quote := proration.Calculate(subscription, newPlan, req.BillingCycle, coupon, now)
+	change := &models.PlanChange{
+		SubscriptionID:   subscription.ID,
+		FromPlanID:       subscription.PlanID,
+		ToPlanID:         newPlan.ID,
+		FromBillingCycle: subscription.BillingCycle,
+		ToBillingCycle:   req.BillingCycle,
+		FromPeriodEnd:    subscription.CurrentPeriodEnd,
+		Credit:           quote.Credit,
+		Charge:           quote.Charge,
+		Discount:         quote.Discount,
+		AmountDue:        quote.AmountDue,
+		PeriodStart:      quote.PeriodStart,
+		PeriodEnd:        quote.PeriodEnd,
+		Status:           models.PlanChangePending,
+		ToPlan:           *newPlan,
+		Coupon:           coupon,
+	}
+	if coupon != nil {
+		change.CouponID = &coupon.ID
+	}
+	return subscription, change, nil
+}
 
-	// Create a new payment for the plan change
-	orderID := fmt.Sprintf("SUB-%d-%d-%d", subscription.TutorID, req.NewPlanID, time.Now().Unix())
+// planChangeCoupon is the coupon a change to planID is discounted with: the one behind code
+// when it is a new one for the subscription, otherwise the subscription's own while it has
+// cycles left and covers the new plan
+func (s *subscriptionService) planChangeCoupon(subscription *models.TutorSubscription, planID uint, code string) (*models.Coupon, error) {
+	current := subscription.Coupon
+	code = strings.TrimSpace(code)
+	if code != "" && (current == nil || !strings.EqualFold(code, current.Code)) {
+		return s.redeemableCoupon(code, subscription.TutorID, planID)
+	}
+	if current == nil {
+		return nil, nil
+	}
 
-	// Store the old plan ID for event logging
-	oldPlanID := subscription.PlanID
-	oldBillingCycle := subscription.BillingCycle
+	var err error
+	switch {
+	case subscription.CouponCyclesLeft != nil && *subscription.CouponCyclesLeft <= 0:
+		err = errors.New("coupon has been used up")
+	case !current.AppliesTo(planID):
+		err = errors.New("coupon does not apply to this plan")
+	default:
+		return current, nil
+	}
+	// Only a coupon asked for again is an error, the subscription's own simply stops applying
+	if code == "" {
+		return nil, nil
+	}
+	return nil, err
+}
 
-	// The actual plan change will happen after payment confirmation
-	// Update the subscription with new payment order ID
-	subscription.PaymentOrderID = orderID
+// applyPlanChange switches the subscription over to change, how is why it applies now for the event
+func (s *subscriptionService) applyPlanChange(subscription *models.TutorSubscription, change *models.PlanChange, how string) error {
+	updates := map[string]interface{}{
+		"plan_id":              change.ToPlanID,
+		"billing_cycle":        change.ToBillingCycle,
+		"current_period_start": change.PeriodStart,
+		"current_period_end":   change.PeriodEnd,
+		// What is left of the period, or the new one, was paid for at the change's discount
+		"period_discount_rate": models.DiscountRate(change.Discount, change.Charge),
+	}
+	notes := fmt.Sprintf("%s, plan changed from ID %d (%s) to ID %d (%s) for %s to %s",
+		how, change.FromPlanID, change.FromBillingCycle, change.ToPlanID, change.ToBillingCycle,
+		change.PeriodStart.Format("2006-01-02"), change.PeriodEnd.Format("2006-01-02"))
+	if change.PaymentOrderID != nil {
+		updates["payment_order_id"] = *change.PaymentOrderID
+	}
 
-	if err := s.subscriptionRepo.Update(subscription); err != nil {
-		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	// A coupon new to the subscription is redeemed and replaces its own, which otherwise only
+	// uses up a cycle when the change bills a new period at a discount
+	var redeemed *models.Coupon
+	switch {
+	case change.Coupon == nil:
+	case subscription.CouponID == nil || *subscription.CouponID != change.Coupon.ID:
+		redeemed = change.Coupon
+		cycles := change.Coupon.Cycles()
+		if cycles != nil && change.Discount > 0 {
+			*cycles--
+		}
+		updates["coupon_id"] = change.Coupon.ID
+		updates["coupon_cycles_left"] = cycles
+		notes += fmt.Sprintf(", coupon %s redeemed", change.Coupon.Code)
+	case change.ToBillingCycle != change.FromBillingCycle && change.Discount > 0 && subscription.CouponCyclesLeft != nil:
+		updates["coupon_cycles_left"] = *subscription.CouponCyclesLeft - 1
 	}
 
-	// Log plan change initiated event
 	event := &models.SubscriptionEvent{
 		SubscriptionID: subscription.ID,
-		EventType:      "plan_change_initiated",
+		EventType:      "plan_changed",
 		PreviousStatus: subscription.Status,
 		CurrentStatus:  subscription.Status,
-		Notes: fmt.Sprintf("Plan change initiated from ID %d to ID %d with billing cycle from %s to %s. Order ID: %s",
-			oldPlanID, req.NewPlanID, req.BillingCycle, oldBillingCycle, orderID),
+		Notes:          notes,
 	}
 
-	if err := s.subscriptionRepo.LogEvent(event); err != nil {
-		fmt.Printf("Failed to log subscription event: %v\n", err)
+	applied, err := s.subscriptionRepo.ApplyPlanChange(subscription, change, redeemed, updates, event)
+	if err != nil {
+		return fmt.Errorf("failed to change plan: %w", err)
+	}
+	if !applied {
+		return errors.New("subscription changed while changing plan, please try again")
 	}
+	return nil
+}
 
-	// Create a response with the current plan details but including payment URL
-	response := &models.SubscriptionResponse{
-		ID:                 subscription.ID,
-		TutorID:            subscription.TutorID,
-		PlanName:           subscription.Plan.Name,
-		Status:             subscription.Status,
-		CurrentPeriodStart: subscription.CurrentPeriodStart,
-		CurrentPeriodEnd:   subscription.CurrentPeriodEnd,
-		CancelAtPeriodEnd:  subscription.CancelAtPeriodEnd,
-		BillingCycle:       subscription.BillingCycle,
-		Price:              amount,
-		Features:           subscription.Plan.FeaturesJSON,
-		MaxCourses:         subscription.Plan.MaxCourses,
-		CommissionRate:     subscription.Plan.CommissionRate,
-		PaymentOrderID:     orderID,
+// confirmPlanChange applies a plan change whose payment went through. A change that no longer
+// matches the subscription is not applied over it, the payment is left for an admin to refund.
+func (s *subscriptionService) confirmPlanChange(ctx context.Context, change *models.PlanChange) (*models.SubscriptionResponse, error) {
+	if change.Status == models.PlanChangeApplied {
+		return s.planChangeResponse(change.SubscriptionID, change, "")
+	}
+
+	// The order id is handed to the client and anyone signed in can confirm it, only
+	// payment-service can say the change was paid for
+	payment, err := s.paymentService.GetPaymentByOrderID(ctx, *change.PaymentOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != contracts.PaymentCompleted {
+		return nil, ErrPaymentNotCompleted
+	}
+	if math.Abs(payment.Amount-change.AmountDue) >= 0.005 {
+		return nil, fmt.Errorf("%w: paid %.2f, %.2f due", ErrPaymentAmountMismatch, payment.Amount, change.AmountDue)
+	}
+
+	subscription, err := s.subscriptionRepo.GetByID(change.SubscriptionID)
+	if err != nil {
+		return nil, err
 	}
 
+	if !change.Matches(subscription) {
+		event := &models.SubscriptionEvent{
+			SubscriptionID: subscription.ID,
+			EventType:      "plan_change_failed",
+			PreviousStatus: subscription.Status,
+			CurrentStatus:  subscription.Status,
+			Notes: fmt.Sprintf("Payment %s for a change to plan ID %d was confirmed after the subscription changed, not applied and due a refund",
+				*change.PaymentOrderID, change.ToPlanID),
+		}
+		if err := s.subscriptionRepo.LogEvent(event); err != nil {
+			slog.Error("Failed to log subscription event", "error", err)
+		}
+		return nil, errors.New("the subscription changed since this plan change was quoted")
+	}
+
+	if err := s.applyPlanChange(subscription, change, "Payment "+*change.PaymentOrderID+" confirmed"); err != nil {
+		return nil, err
+	}
+	return s.planChangeResponse(subscription.ID, change, "")
+}
+
+func (s *subscriptionService) planChangeResponse(id uint, change *models.PlanChange, paymentURL string) (*models.SubscriptionResponse, error) {
+	response, err := s.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	planChange := change.Contract(paymentURL)
+	response.PlanChange = &planChange
 	return response, nil
 }
 
@@ -487,20 +830,42 @@ func (s *subscriptionService) UpdateSubscriptionStatus(id uint, status models.Su
 	}
 
 	if err := s.subscriptionRepo.LogEvent(event); err != nil {
-		fmt.Printf("Failed to log subscription event: %v\n", err)
+		slog.Error("Failed to log subscription event", "error", err)
 	}
 
 	return nil
 }
 
 // ProcessPaymentWebhook handles payment webhooks from the payment service
-func (s *subscriptionService) ProcessPaymentWebhook(payload models.PaymentWebhookPayload) error {
+func (s *subscriptionService) ProcessPaymentWebhook(ctx context.Context, payload models.PaymentWebhookPayload) error {
+	// A plan change that is not paid for leaves the subscription as it is
+	switch payload.Event {
+	case "payment.failed", "payment.error", "payment.cancelled", "payment.canceled":
+		change, err := s.subscriptionRepo.GetPlanChangeByOrderID(payload.OrderID)
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			event := &models.SubscriptionEvent{
+				SubscriptionID: change.SubscriptionID,
+				EventType:      "plan_change_payment_failed",
+				PreviousStatus: models.SubscriptionActive,
+				CurrentStatus:  models.SubscriptionActive,
+				Notes:          fmt.Sprintf("Payment %s for a change to plan ID %d reported %s, plan unchanged", payload.OrderID, change.ToPlanID, payload.Event),
+			}
+			if err := s.subscriptionRepo.LogEvent(event); err != nil {
+				slog.Error("Failed to log subscription event", "error", err)
+			}
+			return nil
+		}
+	}
+
 	// Handle different event types
 	switch payload.Event {
 	case "payment.completed", "payment.success":
 		// When payment completes, activate the subscription
-		fmt.Println(payload, "DEBUG PAYMENT WEBHOOK =========================")
-		_, err := s.ConfirmSubscription(models.PaymentConfirmationRequest{
+		slog.Debug("Payment webhook received", "payload", payload)
+		_, err := s.ConfirmSubscription(ctx, models.PaymentConfirmationRequest{
 			OrderID: payload.OrderID,
 		})
 		return err
@@ -524,25 +889,38 @@ func (s *subscriptionService) ProcessPaymentWebhook(payload models.PaymentWebhoo
 
 		subscription := subscriptions[0]
 
-		// Update subscription status to failed
-		oldStatus := subscription.Status
-		subscription.Status = models.SubscriptionPastDue
-
-		if err := s.subscriptionRepo.Update(&subscription); err != nil {
-			return err
+		// A dunning retry that fails leaves dunning to carry on with its schedule
+		if subscription.Status == models.SubscriptionPastDue {
+			event := &models.SubscriptionEvent{
+				SubscriptionID: subscription.ID,
+				EventType:      "payment_failed",
+				PreviousStatus: subscription.Status,
+				CurrentStatus:  subscription.Status,
+				Notes:          fmt.Sprintf("Payment %s failed during dunning", payload.OrderID),
+			}
+			if err := s.subscriptionRepo.LogEvent(event); err != nil {
+				slog.Error("Failed to log subscription event", "error", err)
+			}
+			return nil
 		}
 
-		// Log payment failure event
+		// Update subscription status to past due, which starts dunning
+		updates := s.dunning.Start(time.Now())
+		updates["renewal_due_at"] = nil
 		event := &models.SubscriptionEvent{
 			SubscriptionID: subscription.ID,
 			EventType:      "payment_failed",
-			PreviousStatus: oldStatus,
+			PreviousStatus: subscription.Status,
 			CurrentStatus:  models.SubscriptionPastDue,
 			Notes:          "Payment failed, subscription marked as past due",
 		}
 
-		if err := s.subscriptionRepo.LogEvent(event); err != nil {
-			fmt.Printf("Failed to log subscription event: %v\n", err)
+		applied, err := s.subscriptionRepo.Transition(&subscription, updates, event)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			slog.Warn("Subscription changed before its payment failure was recorded", "subscription_id", subscription.ID, "order_id", payload.OrderID)
 		}
 
 		return nil
@@ -585,7 +963,7 @@ func (s *subscriptionService) ProcessPaymentWebhook(payload models.PaymentWebhoo
 			}
 
 			if err := s.subscriptionRepo.LogEvent(event); err != nil {
-				fmt.Printf("Failed to log subscription event: %v\n", err)
+				slog.Error("Failed to log subscription event", "error", err)
 			}
 		}
 
@@ -626,7 +1004,30 @@ func (s *subscriptionService) GetExpiringSoonSubscriptions(days int) ([]models.S
 			MaxCourses:         subscription.Plan.MaxCourses,
 			CommissionRate:     subscription.Plan.CommissionRate,
 		}
+		s.withStatus(&responses[i], &subscription)
 	}
 
 	return responses, nil
 }
+
+// GetSubscriptionEvents returns a subscription's history, oldest first
+func (s *subscriptionService) GetSubscriptionEvents(id uint) ([]models.SubscriptionEvent, error) {
+	if _, err := s.subscriptionRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.subscriptionRepo.GetEvents(id)
+}
+
+// withStatus fills in whether the plan's features are on, when a trial ends, the coupon discount
+// on the next renewal and, while past_due, where dunning is
+func (s *subscriptionService) withStatus(response *models.SubscriptionResponse, subscription *models.TutorSubscription) {
+	response.FeaturesEnabled = s.dunning.FeaturesEnabled(subscription, time.Now())
+	response.PastDueSince = subscription.PastDueSince
+	response.DunningAttempts = subscription.DunningAttempts
+	response.NextDunningAt = subscription.NextDunningAt
+	response.TrialEndsAt = subscription.TrialEndsAt
+	if subscription.CouponApplies() {
+		response.CouponCode = subscription.Coupon.Code
+		response.Discount = subscription.Discount(response.Price)
+	}
+}
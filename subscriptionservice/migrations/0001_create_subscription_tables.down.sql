@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `SubscriptionEvent`;
+DROP TABLE IF EXISTS `TutorSubscriptions`;
+DROP TABLE IF EXISTS `SubscriptionPlan`;
@@ -0,0 +1,54 @@
+-- The tables exactly as prisma db push left them, so existing databases are adopted as
+-- they are. 0002 then brings them in line with what this service reads and writes.
+CREATE TABLE IF NOT EXISTS `SubscriptionPlan` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(191) NOT NULL,
+    `description` VARCHAR(191) NULL,
+    `price_monthly` DOUBLE NOT NULL,
+    `price_annually` DOUBLE NOT NULL,
+    `max_courses` INTEGER NOT NULL,
+    `commission_rate` DOUBLE NOT NULL,
+    `features` TEXT NOT NULL,
+    `is_active` BOOLEAN NOT NULL DEFAULT true,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `TutorSubscriptions` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `tutor_id` INTEGER NOT NULL,
+    `plan_id` INTEGER NOT NULL,
+    `status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NOT NULL,
+    `current_period_start` DATETIME(3) NOT NULL,
+    `current_period_end` DATETIME(3) NOT NULL,
+    `cancel_at_period_end` BOOLEAN NOT NULL DEFAULT false,
+    `billing_cycle` ENUM('MONTHLY', 'ANNUALLY') NOT NULL,
+    `payment_order_id` VARCHAR(191) NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    INDEX `TutorSubscriptions_tutor_id_idx`(`tutor_id`),
+    INDEX `TutorSubscriptions_plan_id_idx`(`plan_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `TutorSubscriptions_plan_id_fkey` FOREIGN KEY (`plan_id`) REFERENCES `SubscriptionPlan`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `SubscriptionEvent` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `subscription_id` INTEGER NOT NULL,
+    `event_type` VARCHAR(191) NOT NULL,
+    `previous_status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NULL,
+    `current_status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NOT NULL,
+    `notes` TEXT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    INDEX `SubscriptionEvent_subscription_id_idx`(`subscription_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `SubscriptionEvent_subscription_id_fkey` FOREIGN KEY (`subscription_id`) REFERENCES `TutorSubscriptions`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
@@ -0,0 +1,31 @@
+CREATE TABLE `SubscriptionEvent` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `subscription_id` INTEGER NOT NULL,
+    `event_type` VARCHAR(191) NOT NULL,
+    `previous_status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NULL,
+    `current_status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NOT NULL,
+    `notes` TEXT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    INDEX `SubscriptionEvent_subscription_id_idx`(`subscription_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `SubscriptionEvent_subscription_id_fkey` FOREIGN KEY (`subscription_id`) REFERENCES `TutorSubscriptions`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+INSERT INTO `SubscriptionEvent` (`id`, `subscription_id`, `event_type`, `previous_status`, `current_status`, `notes`, `created_at`, `updated_at`, `deleted_at`)
+    SELECT `id`, `subscription_id`, `event_type`, UPPER(`previous_status`), UPPER(`current_status`), `notes`, `created_at`, `updated_at`, `deleted_at`
+    FROM `SubscriptionEvents`;
+
+DROP TABLE `SubscriptionEvents`;
+
+ALTER TABLE `TutorSubscriptions`
+    MODIFY `status` VARCHAR(20) NOT NULL,
+    MODIFY `billing_cycle` VARCHAR(20) NOT NULL;
+
+UPDATE `TutorSubscriptions` SET `status` = UPPER(`status`), `billing_cycle` = UPPER(`billing_cycle`);
+
+ALTER TABLE `TutorSubscriptions`
+    MODIFY `status` ENUM('ACTIVE', 'CANCELED', 'PAST_DUE', 'TRIALING', 'INCOMPLETE') NOT NULL,
+    MODIFY `billing_cycle` ENUM('MONTHLY', 'ANNUALLY') NOT NULL;
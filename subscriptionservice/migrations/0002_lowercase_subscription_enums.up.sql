@@ -0,0 +1,34 @@
+-- The Go models store lowercase statuses and billing cycles and write events to
+-- SubscriptionEvents, the uppercase enums and SubscriptionEvent table only matched Prisma.
+-- The enums go through VARCHAR so existing rows can be lowercased before the new labels apply.
+ALTER TABLE `TutorSubscriptions`
+    MODIFY `status` VARCHAR(20) NOT NULL,
+    MODIFY `billing_cycle` VARCHAR(20) NOT NULL;
+
+UPDATE `TutorSubscriptions` SET `status` = LOWER(`status`), `billing_cycle` = LOWER(`billing_cycle`);
+
+ALTER TABLE `TutorSubscriptions`
+    MODIFY `status` ENUM('active', 'canceled', 'past_due', 'trialing', 'incomplete') NOT NULL,
+    MODIFY `billing_cycle` ENUM('monthly', 'annually') NOT NULL;
+
+CREATE TABLE IF NOT EXISTS `SubscriptionEvents` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `subscription_id` INTEGER NOT NULL,
+    `event_type` VARCHAR(50) NOT NULL,
+    `previous_status` ENUM('active', 'canceled', 'past_due', 'trialing', 'incomplete') NULL,
+    `current_status` ENUM('active', 'canceled', 'past_due', 'trialing', 'incomplete') NOT NULL,
+    `notes` TEXT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    INDEX `SubscriptionEvents_subscription_id_idx`(`subscription_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `SubscriptionEvents_subscription_id_fkey` FOREIGN KEY (`subscription_id`) REFERENCES `TutorSubscriptions`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+INSERT INTO `SubscriptionEvents` (`id`, `subscription_id`, `event_type`, `previous_status`, `current_status`, `notes`, `created_at`, `updated_at`, `deleted_at`)
+    SELECT `id`, `subscription_id`, `event_type`, LOWER(`previous_status`), LOWER(`current_status`), `notes`, `created_at`, `updated_at`, `deleted_at`
+    FROM `SubscriptionEvent`;
+
+DROP TABLE `SubscriptionEvent`;
@@ -0,0 +1,3 @@
+ALTER TABLE `TutorSubscriptions`
+    DROP INDEX `TutorSubscriptions_status_current_period_end_idx`,
+    DROP COLUMN `renewal_due_at`;
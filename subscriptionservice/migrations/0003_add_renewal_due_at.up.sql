@@ -0,0 +1,5 @@
+-- Set when the scheduler starts a renewal payment, the subscription goes past_due if that
+-- payment is still not completed by then. Cleared once it is.
+ALTER TABLE `TutorSubscriptions`
+    ADD COLUMN `renewal_due_at` DATETIME(3) NULL AFTER `payment_order_id`,
+    ADD INDEX `TutorSubscriptions_status_current_period_end_idx`(`status`, `current_period_end`);
@@ -0,0 +1,5 @@
+ALTER TABLE `TutorSubscriptions`
+    DROP INDEX `TutorSubscriptions_status_next_dunning_at_idx`,
+    DROP COLUMN `next_dunning_at`,
+    DROP COLUMN `dunning_attempts`,
+    DROP COLUMN `past_due_since`;
@@ -0,0 +1,12 @@
+-- Dunning state of a past_due subscription: when it went past_due, how many payment retries
+-- have been made and when the scheduler takes the next step
+ALTER TABLE `TutorSubscriptions`
+    ADD COLUMN `past_due_since` DATETIME(3) NULL AFTER `renewal_due_at`,
+    ADD COLUMN `dunning_attempts` INTEGER NOT NULL DEFAULT 0 AFTER `past_due_since`,
+    ADD COLUMN `next_dunning_at` DATETIME(3) NULL AFTER `dunning_attempts`,
+    ADD INDEX `TutorSubscriptions_status_next_dunning_at_idx`(`status`, `next_dunning_at`);
+
+-- Subscriptions that were already past_due start their dunning on the next sweep
+UPDATE `TutorSubscriptions`
+    SET `past_due_since` = CURRENT_TIMESTAMP(3), `next_dunning_at` = CURRENT_TIMESTAMP(3)
+    WHERE `status` = 'past_due';
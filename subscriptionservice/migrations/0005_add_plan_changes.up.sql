@@ -0,0 +1,28 @@
+-- Prorated plan changes. A change waits here as pending until its payment is confirmed, the
+-- from_* columns are what the subscription looked like when it was quoted so a stale change
+-- is never applied over a newer state.
+CREATE TABLE IF NOT EXISTS `SubscriptionPlanChanges` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `subscription_id` INTEGER NOT NULL,
+    `from_plan_id` INTEGER NOT NULL,
+    `to_plan_id` INTEGER NOT NULL,
+    `from_billing_cycle` ENUM('monthly', 'annually') NOT NULL,
+    `to_billing_cycle` ENUM('monthly', 'annually') NOT NULL,
+    `from_period_end` DATETIME(3) NOT NULL,
+    `credit` DOUBLE NOT NULL,
+    `charge` DOUBLE NOT NULL,
+    `amount_due` DOUBLE NOT NULL,
+    `period_start` DATETIME(3) NOT NULL,
+    `period_end` DATETIME(3) NOT NULL,
+    `payment_order_id` VARCHAR(191) NULL,
+    `status` ENUM('pending', 'applied', 'superseded') NOT NULL,
+    `applied_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    UNIQUE INDEX `SubscriptionPlanChanges_payment_order_id_key`(`payment_order_id`),
+    INDEX `SubscriptionPlanChanges_subscription_id_status_idx`(`subscription_id`, `status`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `SubscriptionPlanChanges_subscription_id_fkey` FOREIGN KEY (`subscription_id`) REFERENCES `TutorSubscriptions`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+ALTER TABLE `TutorSubscriptions`
+    DROP COLUMN `trial_reminded_at`,
+    DROP COLUMN `trial_ends_at`;
+
+ALTER TABLE `SubscriptionPlan`
+    DROP COLUMN `trial_days`;
@@ -0,0 +1,9 @@
+-- Free trials: how long a plan's trial lasts, when a subscription's trial ends and when the
+-- tutor was reminded of it. A tutor with any subscription that has trial_ends_at set has
+-- had their trial.
+ALTER TABLE `SubscriptionPlan`
+    ADD COLUMN `trial_days` INTEGER NOT NULL DEFAULT 0 AFTER `commission_rate`;
+
+ALTER TABLE `TutorSubscriptions`
+    ADD COLUMN `trial_ends_at` DATETIME(3) NULL AFTER `next_dunning_at`,
+    ADD COLUMN `trial_reminded_at` DATETIME(3) NULL AFTER `trial_ends_at`;
@@ -0,0 +1,11 @@
+ALTER TABLE `SubscriptionPlanChanges`
+    DROP COLUMN `discount`,
+    DROP COLUMN `coupon_id`;
+
+ALTER TABLE `TutorSubscriptions`
+    DROP FOREIGN KEY `TutorSubscriptions_coupon_id_fkey`,
+    DROP COLUMN `coupon_cycles_left`,
+    DROP COLUMN `coupon_id`;
+
+DROP TABLE `CouponRedemptions`;
+DROP TABLE `Coupons`;
@@ -0,0 +1,50 @@
+-- Coupons tutors can redeem at checkout or on a plan change. A coupon is redeemed once per
+-- tutor, times_redeemed counts redemptions against max_redemptions (0 for no cap) and
+-- plan_ids is a JSON array of the plans it is limited to, empty for every plan.
+CREATE TABLE IF NOT EXISTS `Coupons` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `code` VARCHAR(50) NOT NULL,
+    `description` TEXT NULL,
+    `discount_type` ENUM('percent', 'fixed') NOT NULL,
+    `amount` DOUBLE NOT NULL,
+    `duration` ENUM('once', 'repeating', 'forever') NOT NULL,
+    `duration_cycles` INTEGER NOT NULL DEFAULT 0,
+    `plan_ids` TEXT NULL,
+    `max_redemptions` INTEGER NOT NULL DEFAULT 0,
+    `times_redeemed` INTEGER NOT NULL DEFAULT 0,
+    `expires_at` DATETIME(3) NULL,
+    `is_active` BOOLEAN NOT NULL DEFAULT true,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    UNIQUE INDEX `Coupons_code_key`(`code`),
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `CouponRedemptions` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `coupon_id` INTEGER NOT NULL,
+    `tutor_id` INTEGER NOT NULL,
+    `subscription_id` INTEGER NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+
+    UNIQUE INDEX `CouponRedemptions_coupon_id_tutor_id_key`(`coupon_id`, `tutor_id`),
+    INDEX `CouponRedemptions_subscription_id_idx`(`subscription_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `CouponRedemptions_coupon_id_fkey` FOREIGN KEY (`coupon_id`) REFERENCES `Coupons`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT `CouponRedemptions_subscription_id_fkey` FOREIGN KEY (`subscription_id`) REFERENCES `TutorSubscriptions`(`id`) ON DELETE RESTRICT ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- The coupon a subscription is billed with and how many more billed periods it discounts,
+-- NULL for a coupon that lasts forever
+ALTER TABLE `TutorSubscriptions`
+    ADD COLUMN `coupon_id` INTEGER NULL AFTER `trial_reminded_at`,
+    ADD COLUMN `coupon_cycles_left` INTEGER NULL AFTER `coupon_id`,
+    ADD CONSTRAINT `TutorSubscriptions_coupon_id_fkey` FOREIGN KEY (`coupon_id`) REFERENCES `Coupons`(`id`) ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE `SubscriptionPlanChanges`
+    ADD COLUMN `coupon_id` INTEGER NULL AFTER `charge`,
+    ADD COLUMN `discount` DOUBLE NOT NULL DEFAULT 0 AFTER `coupon_id`;
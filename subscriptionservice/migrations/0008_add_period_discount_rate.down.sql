@@ -0,0 +1,2 @@
+ALTER TABLE `TutorSubscriptions`
+    DROP COLUMN `period_discount_rate`;
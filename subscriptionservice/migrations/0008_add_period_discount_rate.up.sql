@@ -0,0 +1,4 @@
+-- The share of the current period's price a coupon took off, so a plan change credits the
+-- unused time at what was actually paid for it
+ALTER TABLE `TutorSubscriptions`
+    ADD COLUMN `period_discount_rate` DOUBLE NOT NULL DEFAULT 0 AFTER `coupon_cycles_left`;
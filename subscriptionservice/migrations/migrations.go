@@ -0,0 +1,8 @@
+// Package migrations holds subscription-service's schema history. nodeservice seeds these
+// tables through Prisma, whose schema mirrors them and must be kept in step with these files.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
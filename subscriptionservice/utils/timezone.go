@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"time"
 )
@@ -15,11 +15,11 @@ func SetupTimeZone() {
 
 	loc, err := time.LoadLocation(tz)
 	if err != nil {
-		log.Printf("Failed to load %s location: %v", tz, err)
+		slog.Warn("Failed to load time zone", "tz", tz, "error", err)
 		return
 	}
 
 	// Set the default timezone
 	time.Local = loc
-	log.Printf("Default timezone set to: %s", time.Local.String())
+	slog.Info("Default time zone set", "tz", time.Local.String())
 }
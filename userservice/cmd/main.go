@@ -2,11 +2,20 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"platform/health"
+	"platform/logging"
+	"platform/metrics"
+	"platform/migrate"
+	"platform/shutdown"
+	"platform/tracing"
+	"user-service/internal/config"
 	"user-service/internal/handlers"
 	"user-service/internal/models"
 	"user-service/internal/repository"
 	"user-service/internal/services"
+	"user-service/migrations"
 	"user-service/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -19,18 +28,41 @@ var (
 
 func init() {
 	API_KEY = os.Getenv("API_KEY")
+	utils.SetupTimeZone()
+}
+
+// initAdmin runs once the schema check has passed, the User table may not exist before
+func initAdmin() {
 	err := services.AddUser(models.UserCreationParams{Username: "admin", Password: "admin", Role: "Admin", Email: "thaiphienn@gmail.com"}, had_admin, "", repository.DB)
 	if err != nil {
-		fmt.Println("Init admin account failed:", err)
-		had_admin = false
-		return
+		slog.Warn("Init admin account failed", "error", err)
 	}
 	had_admin = false
-
-	utils.SetupTimeZone()
 }
 
 func main() {
+	logging.Init("user-service")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(repository.DB, "user-service", migrations.FS, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrate.OnStart(repository.DB, "user-service", migrations.FS); err != nil {
+		slog.Error("Refusing to start against this schema", "error", err)
+		os.Exit(1)
+	}
+	initAdmin()
+
+	shutdownTracing, err := tracing.Init("user-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up tracing: %v", err))
+	}
+
+	cfg := config.New()
+
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -43,17 +75,26 @@ func main() {
 		},
 	})
 
+	app.Use(tracing.Middleware("user-service"), logging.Middleware(), metrics.Middleware())
+
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status":  "ok",
 			"service": "user-service",
 		})
 	})
+	app.Get("/livez", health.Livez("user-service"))
+	// google-service delivers password reset and verification emails
+	app.Get("/readyz", health.Readyz("user-service",
+		health.Database(repository.DB),
+		health.HTTP("google-service", cfg.Email.GoogleServiceURL+"/livez"),
+	))
+	app.Get("/metrics", metrics.Handler())
 
 	user := app.Group("/user", Middleware(API_KEY))
 
 	// Routes
-	user.Post("/get", handlers.GetUserWithUsernamePasswordHandler(repository.DB))
+	user.Post("/get", handlers.GetUserWithUsernamePasswordHandler(repository.DB, cfg.Login))
 	user.Post("/add", handlers.AddUser(repository.DB, had_admin))
 	user.Get("/get-public-user", handlers.GetPublicUser(repository.DB))
 	user.Get("/get-all-user", handlers.GetAllUser(repository.DB))
@@ -67,15 +108,29 @@ func main() {
 	user.Put("/verify", handlers.VerifyUserHandler(repository.DB))
 	user.Delete("/admin/delete", handlers.AdminDeleteUserHandler(repository.DB))
 	user.Post("/admin/role", handlers.AdminAssignRoleHandler(repository.DB))
+	user.Patch("/admin/unlock", handlers.AdminUnlockUserHandler(repository.DB))
 	user.Get("/check-status", handlers.CheckUserStatusHandler(repository.DB))
 	user.Get("/get/id", handlers.GetUserIDWithEmail(repository.DB))
+	user.Get("/get/by-id", handlers.GetUserWithID(repository.DB))
+	user.Get("/get/email", handlers.GetActiveUserWithEmail(repository.DB))
+	user.Post("/verify-email/send", handlers.SendEmailVerificationHandler(repository.DB, cfg))
+	user.Post("/verify-email/verify", handlers.VerifyEmailCodeHandler(repository.DB, cfg))
+	user.Post("/password/forgot", handlers.ForgotPasswordHandler(repository.DB, cfg))
+	user.Post("/password/reset", handlers.ResetPasswordHandler(repository.DB))
+	user.Post("/2fa/enroll", handlers.EnrollTwoFactorHandler(repository.DB, cfg.TwoFactor))
+	user.Post("/2fa/activate", handlers.ActivateTwoFactorHandler(repository.DB))
+	user.Post("/2fa/verify", handlers.VerifyTwoFactorHandler(repository.DB))
+	user.Post("/2fa/disable", handlers.DisableTwoFactorHandler(repository.DB))
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8085" // default port
 	}
 
-	if err := app.Listen(":" + port); err != nil {
+	if err := shutdown.Serve(app, ":"+port,
+		shutdown.CloseDB(repository.DB),
+		shutdown.Step{Name: "tracing", Run: shutdownTracing},
+	); err != nil {
 		panic(fmt.Sprintf("Failed to start server: %v", err))
 	}
 }
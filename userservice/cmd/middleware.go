@@ -1,17 +1,16 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func Middleware(apiKey string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		log.Println("Address", c.IP(), "Calling", c.Method(), "Request", c.OriginalURL())
 		requestKey := c.Get("API_KEY")
 		if requestKey != apiKey {
-			log.Println("Unauthorized access attempt at", c.IP(), "with API key:", requestKey)
+			slog.WarnContext(c.UserContext(), "Rejected request with an invalid API key", "ip", c.IP(), "path", c.Path())
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Unauthorized",
 			})
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	Login             LoginPolicy
+	TwoFactor         TwoFactorConfig
+	PasswordReset     PasswordResetPolicy
+	EmailVerification EmailVerificationPolicy
+	Email             EmailConfig
+}
+
+// LoginPolicy controls how many wrong passwords lock an account and for how long
+type LoginPolicy struct {
+	MaxFailedAttempts int
+	LockDuration      time.Duration
+}
+
+// TwoFactorConfig names the issuer shown in authenticator apps
+type TwoFactorConfig struct {
+	Issuer string
+}
+
+// PasswordResetPolicy controls reset link lifetime and how many links one email can ask for per window
+type PasswordResetPolicy struct {
+	TokenTTL    time.Duration
+	MaxRequests int
+	Window      time.Duration
+	// ResetURL is the frontend page the emailed link opens, the token is appended as ?token=
+	ResetURL string
+}
+
+// EmailVerificationPolicy bounds the verification code: how long it lives, how many guesses
+// it allows and how soon another one can be sent
+type EmailVerificationPolicy struct {
+	CodeTTL        time.Duration
+	MaxAttempts    int
+	ResendCooldown time.Duration
+}
+
+// EmailConfig points at google-service, which owns the SMTP account
+type EmailConfig struct {
+	GoogleServiceURL string
+	APIKey           string
+}
+
+func New() *Config {
+	return &Config{
+		Login:             loadLoginPolicy(),
+		TwoFactor:         loadTwoFactorConfig(),
+		PasswordReset:     loadPasswordResetPolicy(),
+		EmailVerification: loadEmailVerificationPolicy(),
+		Email:             loadEmailConfig(),
+	}
+}
+
+func loadLoginPolicy() LoginPolicy {
+	return LoginPolicy{
+		MaxFailedAttempts: getEnvInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+		LockDuration:      time.Duration(getEnvInt("LOGIN_LOCK_MINUTES", 10)) * time.Minute,
+	}
+}
+
+func loadTwoFactorConfig() TwoFactorConfig {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "VNVoDich"
+	}
+	return TwoFactorConfig{
+		Issuer: issuer,
+	}
+}
+
+func loadPasswordResetPolicy() PasswordResetPolicy {
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = os.Getenv("REDIRECT_URL") + "/reset-password"
+	}
+	return PasswordResetPolicy{
+		TokenTTL:    time.Duration(getEnvInt("PASSWORD_RESET_TTL_MINUTES", 30)) * time.Minute,
+		MaxRequests: getEnvInt("PASSWORD_RESET_MAX_REQUESTS", 3),
+		Window:      time.Duration(getEnvInt("PASSWORD_RESET_WINDOW_MINUTES", 60)) * time.Minute,
+		ResetURL:    resetURL,
+	}
+}
+
+func loadEmailVerificationPolicy() EmailVerificationPolicy {
+	return EmailVerificationPolicy{
+		CodeTTL:        time.Duration(getEnvInt("EMAIL_OTP_TTL_MINUTES", 10)) * time.Minute,
+		MaxAttempts:    getEnvInt("EMAIL_OTP_MAX_ATTEMPTS", 5),
+		ResendCooldown: time.Duration(getEnvInt("EMAIL_OTP_RESEND_SECONDS", 60)) * time.Second,
+	}
+}
+
+func loadEmailConfig() EmailConfig {
+	return EmailConfig{
+		GoogleServiceURL: os.Getenv("GOOGLE_SERVICE_URL"),
+		APIKey:           os.Getenv("API_KEY"),
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"platform/validation"
+	"user-service/internal/config"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code"`
+}
+
+func emailVerificationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrEmailAlreadyVerified):
+		return fiber.StatusConflict
+	case errors.Is(err, services.ErrVerificationResendTooSoon), errors.Is(err, services.ErrTooManyVerifyAttempts):
+		return fiber.StatusTooManyRequests
+	case errors.Is(err, services.ErrNoVerificationPending), errors.Is(err, services.ErrVerificationExpired),
+		errors.Is(err, services.ErrInvalidVerificationCode):
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusInternalServerError
+}
+
+func SendEmailVerificationHandler(db *gorm.DB, cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req EmailVerificationRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		if err := services.SendEmailVerification(c.UserContext(), req.Email, cfg.EmailVerification, cfg.Email, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Send Email Verification", "error", err)
+			status := emailVerificationErrorStatus(err)
+			if status == fiber.StatusTooManyRequests {
+				c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(cfg.EmailVerification.ResendCooldown.Seconds())))
+			}
+			message := err.Error()
+			if status == fiber.StatusInternalServerError {
+				message = "Could not send verification email"
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Verification code sent",
+		})
+	}
+}
+
+func VerifyEmailCodeHandler(db *gorm.DB, cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req EmailVerificationRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+		if req.Code == "" {
+			return validation.Missing("code").Send(c)
+		}
+
+		if err := services.VerifyEmailCode(req.Email, req.Code, cfg.EmailVerification, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Verify Email Code", "error", err)
+			status := emailVerificationErrorStatus(err)
+			message := err.Error()
+			if status == fiber.StatusInternalServerError {
+				message = "Could not verify email"
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": message,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Email has been verified",
+		})
+	}
+}
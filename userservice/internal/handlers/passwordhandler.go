@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"platform/contracts"
+	"platform/validation"
+	"user-service/internal/config"
+	"user-service/internal/models"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type ForgotPasswordRequest = contracts.ForgotPasswordRequest
+
+type ResetPasswordRequest = contracts.ResetPasswordRequest
+
+func ForgotPasswordHandler(db *gorm.DB, cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ForgotPasswordRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		// A rate limit or a failed send answers like an unknown email, only the log tells them apart
+		if err := services.RequestPasswordReset(c.UserContext(), req.Email, cfg.PasswordReset, cfg.Email, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Request Password Reset", "error", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "If the email belongs to an account, a reset link has been sent",
+		})
+	}
+}
+
+func ResetPasswordHandler(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ResetPasswordRequest
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
+		}
+
+		user, err := services.ResetPassword(req.Token, req.NewPassword, db)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Reset Password", "error", err)
+			if errors.Is(err, services.ErrInvalidResetToken) || errors.Is(err, models.ErrWeakPassword) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not reset password",
+			})
+		}
+		return c.JSON(user.Contract())
+	}
+}
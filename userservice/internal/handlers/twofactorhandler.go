@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"platform/validation"
+	"user-service/internal/config"
+	"user-service/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		return fiber.StatusConflict
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusInternalServerError
+}
+
+func parseTwoFactorCode(c *fiber.Ctx) (string, *validation.Error) {
+	var req TwoFactorCodeRequest
+	if verr := validation.ParseBody(c, &req); verr != nil {
+		return "", verr
+	}
+	return req.Code, nil
+}
+
+func EnrollTwoFactorHandler(db *gorm.DB, cfg config.TwoFactorConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		enrollment, err := services.EnrollTwoFactor(c.Query("username"), cfg.Issuer, db)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Enroll Two Factor", "error", err)
+			return c.Status(twoFactorErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(enrollment)
+	}
+}
+
+func ActivateTwoFactorHandler(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		code, verr := parseTwoFactorCode(c)
+		if verr != nil {
+			return verr.Send(c)
+		}
+		if err := services.ActivateTwoFactor(c.Query("username"), code, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Activate Two Factor", "error", err)
+			return c.Status(twoFactorErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Two-factor authentication has been enabled",
+		})
+	}
+}
+
+func VerifyTwoFactorHandler(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		code, verr := parseTwoFactorCode(c)
+		if verr != nil {
+			return verr.Send(c)
+		}
+		if err := services.VerifyTwoFactor(c.Query("username"), code, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Verify Two Factor", "error", err)
+			return c.Status(twoFactorErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Two-factor code accepted",
+		})
+	}
+}
+
+func DisableTwoFactorHandler(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		code, verr := parseTwoFactorCode(c)
+		if verr != nil {
+			return verr.Send(c)
+		}
+		if err := services.DisableTwoFactor(c.Query("username"), code, db); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Disable Two Factor", "error", err)
+			return c.Status(twoFactorErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Two-factor authentication has been disabled",
+		})
+	}
+}
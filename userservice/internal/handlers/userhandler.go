@@ -1,8 +1,12 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
+	"platform/contracts"
+	"platform/validation"
 	"strconv"
+	"user-service/internal/config"
 	"user-service/internal/models"
 	"user-service/internal/services"
 
@@ -19,33 +23,32 @@ type RequestParam struct {
 	Phone     string `json:"phone"`
 }
 
-func GetUserWithUsernamePasswordHandler(db *gorm.DB) fiber.Handler {
+func GetUserWithUsernamePasswordHandler(db *gorm.DB, policy config.LoginPolicy) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req RequestParam
-		if err := c.BodyParser(&req); err != nil {
-			fmt.Println("Error Get User:", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request",
-			})
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
-		user, err := services.FindUserWithUsernamePassword(req.Username, req.Password, db)
+		user, err := services.FindUserWithUsernamePassword(req.Username, req.Password, policy, db)
 		if err != nil {
-			fmt.Println("Error Get User:", err)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err,
+			slog.ErrorContext(c.UserContext(), "Error Get User", "error", err)
+			status := fiber.StatusUnauthorized
+			if errors.Is(err, models.ErrAccountLocked) {
+				status = fiber.StatusLocked
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
-		return c.JSON(user)
+		return c.JSON(user.Contract())
 	}
 }
 
 func AddUser(db *gorm.DB, had_admin bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var req RequestParam
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request",
-			})
+		var req contracts.NewUser
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
 		google_access_token := c.Query("google_token")
 
@@ -54,9 +57,18 @@ func AddUser(db *gorm.DB, had_admin bool) fiber.Handler {
 			Username: req.Username,
 			Password: req.Password,
 			Email:    req.Email,
-			Role:     req.Role,
-			FullName: req.Full_name,
+			Role:     string(req.Role),
+			FullName: req.FullName,
 			Phone:    req.Phone,
+			Picture:  req.Picture,
+		}
+		// A Google sign-up has no password of its own
+		var skip []string
+		if google_access_token != "" {
+			skip = append(skip, "Password")
+		}
+		if verr := validation.StructExcept(params, skip...); verr != nil {
+			return verr.Send(c)
 		}
 
 		// Use our updated AddUser function that handles role-specific records
@@ -69,7 +81,7 @@ func AddUser(db *gorm.DB, had_admin bool) fiber.Handler {
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"status":  "OK",
-			"message": "User created successfully with " + req.Role + " role",
+			"message": "User created successfully with " + string(req.Role) + " role",
 		})
 	}
 }
@@ -77,16 +89,13 @@ func AddUser(db *gorm.DB, had_admin bool) fiber.Handler {
 func GetPublicUser(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req RequestParam
-		if err := c.BodyParser(&req); err != nil {
-			fmt.Println("Error Get User:", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request",
-			})
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
 
 		userData, err := services.FindUserWithUsername(req.Username, db)
 		if err != nil {
-			fmt.Println("Error Get User:", err)
+			slog.ErrorContext(c.UserContext(), "Error Get User", "error", err)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": err.Error(),
 			})
@@ -140,7 +149,7 @@ func GetAllUser(db *gorm.DB) fiber.Handler {
 		// Get paginated users with filters
 		response, err := services.GetAllUser(db, page, limit, filters)
 		if err != nil {
-			fmt.Println("Error getting users:", err)
+			slog.ErrorContext(c.UserContext(), "Error getting users", "error", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to retrieve users: " + err.Error(),
 			})
@@ -155,7 +164,7 @@ func GetUserwithUsername(db *gorm.DB) fiber.Handler {
 		username := c.Query("username")
 		userData, err := services.FindUserWithUsername(username, db)
 		if err != nil {
-			fmt.Println("Error Get User:", err)
+			slog.ErrorContext(c.UserContext(), "Error Get User", "error", err)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": err.Error(),
 			})
@@ -170,10 +179,8 @@ func UpdateUser(db *gorm.DB) fiber.Handler {
 		userid := c.Query("id")
 
 		var updateReq models.UserUpdateParams
-		if err := c.BodyParser(&updateReq); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
+		if verr := validation.ParseBody(c, &updateReq); verr != nil {
+			return verr.Send(c)
 		}
 
 		updatedUser, err := services.UpdateUser(username, userid, updateReq, db)
@@ -183,7 +190,7 @@ func UpdateUser(db *gorm.DB) fiber.Handler {
 			})
 		}
 
-		return c.JSON(updatedUser)
+		return c.JSON(updatedUser.Contract())
 	}
 }
 
@@ -199,7 +206,7 @@ func UpdateUserStatus(db *gorm.DB) fiber.Handler {
 			})
 		}
 
-		return c.JSON(updatedUser)
+		return c.JSON(updatedUser.Contract())
 	}
 }
 
@@ -253,10 +260,8 @@ func AdminUpdateUserHandler(db *gorm.DB) fiber.Handler {
 		username := c.Query("name")
 
 		var updateReq models.UserUpdateParams
-		if err := c.BodyParser(&updateReq); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
+		if verr := validation.ParseBody(c, &updateReq); verr != nil {
+			return verr.Send(c)
 		}
 
 		updatedUser, err := services.UpdateUser(username, userid, updateReq, db)
@@ -323,19 +328,10 @@ func AdminAssignRoleHandler(db *gorm.DB) fiber.Handler {
 
 		// Parse the request body
 		var req struct {
-			Role string `json:"role"`
+			Role string `json:"role" validate:"required"`
 		}
-
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
-		}
-
-		if req.Role == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Role is required",
-			})
+		if verr := validation.ParseBody(c, &req); verr != nil {
+			return verr.Send(c)
 		}
 
 		// Call the service to assign the role
@@ -439,3 +435,74 @@ func GetUserIDWithEmail(db *gorm.DB) fiber.Handler {
 		})
 	}
 }
+
+func GetUserWithID(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user ID",
+			})
+		}
+		user, err := services.FindUserByID(uint(id), db)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Get User", "error", err)
+			status := fiber.StatusInternalServerError
+			if errors.Is(err, services.ErrUserNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(user.Contract())
+	}
+}
+
+func GetActiveUserWithEmail(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		email := c.Query("email")
+		if email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Email is required",
+			})
+		}
+
+		user, err := services.FindActiveUserByEmail(email, db)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Get User", "error", err)
+			switch {
+			case errors.Is(err, services.ErrUserNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.Is(err, services.ErrAccountInvalid):
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to retrieve user",
+			})
+		}
+		return c.JSON(user.Contract())
+	}
+}
+
+func AdminUnlockUserHandler(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		username := c.Query("username")
+		user, err := services.UnlockUser(username, db)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error Unlock User", "error", err)
+			status := fiber.StatusBadRequest
+			if errors.Is(err, services.ErrUserNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(user.Contract())
+	}
+}
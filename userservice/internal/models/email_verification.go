@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerification is the pending code for one email address. Sending a new code replaces
+// the row, so there is never more than one live code per address.
+type EmailVerification struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	Email      string    `gorm:"type:varchar(255);uniqueIndex;not null"`
+	CodeHash   string    `gorm:"type:varchar(255);not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	Attempts   int       `gorm:"default:0"`
+	LastSentAt time.Time `gorm:"not null"`
+	CreatedAt  time.Time
+
+	UserID uint `gorm:"index;not null"`
+	User   User `gorm:"foreignKey:UserID"`
+}
+
+func (EmailVerification) TableName() string {
+	return "EmailVerification"
+}
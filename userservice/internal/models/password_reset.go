@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken is one emailed reset link. Only the SHA-256 of the token is stored,
+// so a database leak does not hand out working links.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Email     string     `gorm:"type:varchar(255);not null;default:''"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"null"`
+	CreatedAt time.Time  `gorm:"index"`
+
+	UserID uint `gorm:"index;not null"`
+	User   User `gorm:"foreignKey:UserID"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "PasswordResetToken"
+}
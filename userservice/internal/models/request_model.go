@@ -1,5 +1,7 @@
 package models
 
+import "platform/contracts"
+
 type UserCreationParams struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Password string `json:"password" validate:"required,min=8"`
@@ -7,12 +9,7 @@ type UserCreationParams struct {
 	Role     string `json:"role"`
 	FullName string `json:"full_name"`
 	Phone    string `json:"phone"`
-}
-
-type UserUpdateParams struct {
-	Email    string `json:"email"`
-	FullName string `json:"full_name"`
-	Phone    string `json:"phone"`
 	Picture  string `json:"picture"`
-	Status   string `json:"status"`
 }
+
+type UserUpdateParams = contracts.UserUpdate
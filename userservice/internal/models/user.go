@@ -3,7 +3,8 @@ package models
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"platform/contracts"
 	"regexp"
 	"strings"
 	"time"
@@ -12,21 +13,22 @@ import (
 	"gorm.io/gorm"
 )
 
-type UserRole string
+type UserRole = contracts.Role
 
 const (
-	RoleParent UserRole = "Parent"
-	RoleKid    UserRole = "Kid"
-	RoleTutor  UserRole = "Tutor"
-	RoleAdmin  UserRole = "Admin"
+	RoleParent   = contracts.RoleParent
+	RoleChildren = contracts.RoleChildren
+	RoleTutor    = contracts.RoleTutor
+	RoleAdmin    = contracts.RoleAdmin
 )
 
-type UserStatus string
+type UserStatus = contracts.Status
 
 const (
-	StatusActive  UserStatus = "Active"
-	StatusDeleted UserStatus = "Deleted"
-	StatusBanned  UserStatus = "Banned"
+	StatusActive    = contracts.StatusActive
+	StatusSuspended = contracts.StatusSuspended
+	StatusBanned    = contracts.StatusBanned
+	StatusDeleted   = contracts.StatusDeleted
 )
 
 type User struct {
@@ -34,43 +36,68 @@ type User struct {
 	Username    string   `gorm:"uniqueIndex;not null" json:"username" validate:"required,min=3,max=50,alphanum"`
 	Password    string   `gorm:"not null" json:"-" validate:"required,min=8"`
 	Email       string   `gorm:"uniqueIndex;not null" json:"email" validate:"required,email"`
-	Role        UserRole `gorm:"type:varchar(50);not null;default:'user'" json:"role"`
+	Role        UserRole `gorm:"type:enum('Parent','Children','Tutor','Admin');not null;default:'Parent'" json:"role"`
 	Phone       *string  `gorm:"type:varchar(20)" json:"phone" validate:"omitempty,e164"`
 	FullName    *string  `gorm:"type:varchar(255)" json:"full_name" validate:"omitempty,min=2,max=100"`
 	Picture     string   `gorm:"type:varchar(255)" json:"picture"`
 	GoogleToken string   `gorm:"type:text" json:"google_token"`
 
 	IsVerified bool       `gorm:"default:false" json:"is_verified"`
-	Status     UserStatus `gorm:"type:varchar(20);default:'Active'" json:"status"`
-
-	LastLoginAt       *int64 `json:"last_login_at,omitempty"`
-	AccountLocked     bool   `gorm:"default:false" json:"account_locked"`
-	PasswordChangedAt *int64 `json:"password_changed_at"`
+	Status     UserStatus `gorm:"type:enum('Active','Suspended','Banned','Deleted');not null;default:'Active'" json:"status"`
+
+	LastLoginAt         *int64 `json:"last_login_at,omitempty"`
+	AccountLocked       bool   `gorm:"default:false" json:"account_locked"`
+	FailedLoginAttempts int    `gorm:"default:0" json:"failed_login_attempts"`
+	LockedUntil         *int64 `json:"locked_until,omitempty"`
+	PasswordChangedAt   *int64 `json:"password_changed_at"`
+
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret  string `gorm:"type:varchar(64)" json:"-"`
+	// JSON array of SHA-256 hashes of the unused recovery codes
+	TwoFactorRecoveryCodes string `gorm:"type:text" json:"-"`
+	// Last TOTP time step accepted, so a code cannot be replayed within its window
+	TwoFactorLastStep int64 `gorm:"default:0" json:"-"`
 }
 
 func (User) TableName() string {
 	return "User"
 }
 
+// Contract is the user as other services see it, without the password hash, 2FA secrets
+// or Google token
+func (u *User) Contract() contracts.User {
+	return contracts.User{
+		ID:                  u.ID,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
+		Username:            u.Username,
+		Email:               u.Email,
+		Role:                u.Role,
+		Phone:               u.Phone,
+		FullName:            u.FullName,
+		Picture:             u.Picture,
+		IsVerified:          u.IsVerified,
+		Status:              u.Status,
+		LastLoginAt:         u.LastLoginAt,
+		AccountLocked:       u.AccountLocked,
+		FailedLoginAttempts: u.FailedLoginAttempts,
+		LockedUntil:         u.LockedUntil,
+		PasswordChangedAt:   u.PasswordChangedAt,
+		TwoFactorEnabled:    u.TwoFactorEnabled,
+	}
+}
+
 // BeforeSave handles any necessary modifications before saving to database
 func (u *User) BeforeSave(tx *gorm.DB) error {
-	log.Printf("BeforeSave hook called for user: %s", u.Username)
-	log.Printf("Password before hash: %s", u.Password)
-
 	if tx.Statement.Changed("Password") || tx.Statement.ReflectValue.FieldByName("ID").IsZero() {
-		log.Println("Password field has changed, hashing...")
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 		if err != nil {
-			log.Printf("Error hashing password: %v", err)
+			slog.Error("Hashing password failed", "username", u.Username, "error", err)
 			return err
 		}
 		u.Password = string(hashedPassword)
 		now := time.Now().Unix()
 		u.PasswordChangedAt = &now
-		log.Printf("Password after hash: %s", u.Password)
-		log.Printf("Password changed at %s:%v ", u.Username, u.PasswordChangedAt)
-	} else {
-		log.Println("Password field has not changed")
 	}
 	return nil
 }
@@ -112,10 +139,10 @@ func (u *User) Validate() error {
 
 	// Role validation
 	validRoles := map[UserRole]bool{
-		RoleParent: true,
-		RoleKid:    true,
-		RoleTutor:  true,
-		RoleAdmin:  true,
+		RoleParent:   true,
+		RoleChildren: true,
+		RoleTutor:    true,
+		RoleAdmin:    true,
 	}
 	if !validRoles[u.Role] || u.Role == RoleAdmin {
 		return errors.New("invalid user role")
@@ -123,9 +150,10 @@ func (u *User) Validate() error {
 
 	// Status validation
 	validStatuses := map[UserStatus]bool{
-		StatusActive:  true,
-		StatusDeleted: true,
-		StatusBanned:  true,
+		StatusActive:    true,
+		StatusSuspended: true,
+		StatusBanned:    true,
+		StatusDeleted:   true,
 	}
 	if !validStatuses[u.Status] {
 		return errors.New("invalid user status")
@@ -139,8 +167,8 @@ func (u *User) CheckPassword(password string) error {
 }
 
 func (u *User) IsAccountValid() error {
-	if u.AccountLocked {
-		return errors.New("account is locked due to too many failed login attempts")
+	if u.IsLocked() {
+		return ErrAccountLocked
 	}
 
 	if u.Status != StatusActive {
@@ -150,24 +178,71 @@ func (u *User) IsAccountValid() error {
 	return nil
 }
 
-func (u *User) Lock(lock_time int) error {
+var (
+	ErrAccountLocked = errors.New("account is locked due to too many failed login attempts")
+	ErrWeakPassword  = errors.New("password must be at least 8 characters")
+)
+
+// IsLocked reports whether a lock is still in force. A lock without an end time lasts until an admin unlocks it.
+func (u *User) IsLocked() bool {
+	if !u.AccountLocked {
+		return false
+	}
+	return u.LockedUntil == nil || time.Now().Unix() < *u.LockedUntil
+}
+
+// Lock persists a lock on the account until the given time
+func (u *User) Lock(tx *gorm.DB, until time.Time) error {
+	lockedUntil := until.Unix()
+	err := tx.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"account_locked": true,
+		"locked_until":   lockedUntil,
+	}).Error
+	if err != nil {
+		return err
+	}
 	u.AccountLocked = true
-	fmt.Println("Lock account", u.Username, "for", lock_time, "minutes")
-	go func() {
-		time.Sleep(time.Minute * time.Duration(lock_time))
-		if u.AccountLocked {
-			u.AccountLocked = false
-		}
-		fmt.Println("Account", u.Username, "is unlocked")
-	}()
+	u.LockedUntil = &lockedUntil
+	slog.Info("Account locked", "username", u.Username, "until", until)
 	return nil
 }
 
-func (u *User) Unlock() error {
-	if u.AccountLocked {
-		u.AccountLocked = false
-		fmt.Println("Account unlocked")
-		return nil
+// SetPassword hashes and stores a new password. PasswordChangedAt moves with it, which is
+// what auth-service compares token issue times against.
+func (u *User) SetPassword(tx *gorm.DB, password string) error {
+	if len(password) < 8 {
+		return ErrWeakPassword
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("account is already unlocked")
+	now := time.Now().Unix()
+	err = tx.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"password":            string(hashedPassword),
+		"password_changed_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	u.Password = string(hashedPassword)
+	u.PasswordChangedAt = &now
+	return nil
+}
+
+// Unlock clears the lock and the failed attempt counter
+func (u *User) Unlock(tx *gorm.DB) error {
+	err := tx.Model(&User{}).Where("id = ?", u.ID).UpdateColumns(map[string]interface{}{
+		"account_locked":        false,
+		"locked_until":          nil,
+		"failed_login_attempts": 0,
+	}).Error
+	if err != nil {
+		return err
+	}
+	u.AccountLocked = false
+	u.LockedUntil = nil
+	u.FailedLoginAttempts = 0
+	slog.Info("Account unlocked", "username", u.Username)
+	return nil
 }
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"platform/metrics"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -22,7 +24,7 @@ func initMySQLDB() *gorm.DB {
 	dbName := os.Getenv("DB_NAME")
 
 	if dbUser == "" || dbPass == "" || dbHost == "" || dbName == "" {
-		fmt.Println("Warning: Some database environment variables are missing. Using default values.")
+		slog.Warn("Some database environment variables are missing, using default values")
 		// Fallback to default values if env vars are not set
 		dbUser = "appuser"
 		dbPass = "apppassword"
@@ -40,6 +42,9 @@ func initMySQLDB() *gorm.DB {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to connect to database: %v", err))
 	}
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		panic(fmt.Sprintf("Failed to register query metrics: %v", err))
+	}
 
 	return db
 }
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"user-service/internal/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLite has no enum type, so the User table is written out by hand with the MySQL enums as text
+const userTable = `CREATE TABLE User (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	created_at datetime, updated_at datetime, deleted_at datetime,
+	username text NOT NULL UNIQUE, password text NOT NULL, email text NOT NULL UNIQUE,
+	role text NOT NULL DEFAULT 'Parent', phone text, full_name text, picture text, google_token text,
+	is_verified numeric DEFAULT false, status text NOT NULL DEFAULT 'Active',
+	last_login_at integer, account_locked numeric DEFAULT false, failed_login_attempts integer DEFAULT 0,
+	locked_until integer, password_changed_at integer,
+	two_factor_enabled numeric DEFAULT false, two_factor_secret text, two_factor_recovery_codes text,
+	two_factor_last_step integer DEFAULT 0
+)`
+
+// newTestDB opens an in-memory SQLite database of its own with the user tables created
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a new database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Exec(userTable).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrator().CreateTable(&models.PasswordResetToken{}, &models.EmailVerification{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createUser adds an active user whose password is "password123"
+func createUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Username: username,
+		Password: "password123",
+		Email:    username + "@example.com",
+		Role:     models.RoleTutor,
+		Status:   models.StatusActive,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"context"
+	"platform/client"
+	"user-service/internal/config"
+)
+
+// SendEmail delivers a plain text email through google-service, which holds the SMTP credentials
+func SendEmail(ctx context.Context, cfg config.EmailConfig, to, title, body string) error {
+	return client.NewEmailClient(cfg.GoogleServiceURL, cfg.APIKey).Send(ctx, to, title, body)
+}
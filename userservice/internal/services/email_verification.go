@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"user-service/internal/config"
+	"user-service/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEmailAlreadyVerified      = errors.New("email is already verified")
+	ErrNoVerificationPending     = errors.New("no verification code has been sent to this email")
+	ErrVerificationExpired       = errors.New("verification code has expired, request a new one")
+	ErrTooManyVerifyAttempts     = errors.New("too many wrong codes, request a new one")
+	ErrInvalidVerificationCode   = errors.New("wrong verification code")
+	ErrVerificationResendTooSoon = errors.New("a code was sent recently, wait before asking for another")
+)
+
+// SendEmailVerification emails a fresh 6 digit code to the address, replacing any earlier code
+func SendEmailVerification(ctx context.Context, email string, policy config.EmailVerificationPolicy, emailCfg config.EmailConfig, db *gorm.DB) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email cannot be empty")
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if user.IsVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	var pending models.EmailVerification
+	err := db.Where("email = ?", email).First(&pending).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error finding verification: %w", err)
+	}
+	if err == nil && time.Since(pending.LastSentAt) < policy.ResendCooldown {
+		return ErrVerificationResendTooSoon
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	verification := models.EmailVerification{
+		Email:      email,
+		CodeHash:   string(codeHash),
+		ExpiresAt:  now.Add(policy.CodeTTL),
+		Attempts:   0,
+		LastSentAt: now,
+		UserID:     user.ID,
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "expires_at", "attempts", "last_sent_at", "user_id"}),
+	}).Create(&verification).Error; err != nil {
+		return fmt.Errorf("failed to save verification code: %w", err)
+	}
+
+	body := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(policy.CodeTTL.Minutes()))
+	if err := SendEmail(ctx, emailCfg, email, "Email Verification", body); err != nil {
+		// The code never arrived, so it must not hold the cooldown either
+		db.Where("email = ?", email).Delete(&models.EmailVerification{})
+		return err
+	}
+	return nil
+}
+
+// VerifyEmailCode checks a code against the one sent to the address. Each wrong guess
+// counts against MaxAttempts; once they run out only a new code helps.
+func VerifyEmailCode(email, code string, policy config.EmailVerificationPolicy, db *gorm.DB) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email cannot be empty")
+	}
+
+	var wrongCode bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var pending models.EmailVerification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("email = ?", email).First(&pending).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoVerificationPending
+			}
+			return err
+		}
+		if time.Now().After(pending.ExpiresAt) {
+			return ErrVerificationExpired
+		}
+		if pending.Attempts >= policy.MaxAttempts {
+			return ErrTooManyVerifyAttempts
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(pending.CodeHash), []byte(strings.TrimSpace(code))) != nil {
+			// The counter has to be committed, so the mismatch is reported after the transaction
+			wrongCode = true
+			return tx.Model(&pending).UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ? AND email = ?", pending.UserID, email).
+			UpdateColumn("is_verified", true).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&pending).Error
+	})
+	if err != nil {
+		return err
+	}
+	if wrongCode {
+		return ErrInvalidVerificationCode
+	}
+	return nil
+}
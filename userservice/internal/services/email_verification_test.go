@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+	"user-service/internal/config"
+	"user-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	testVerificationPolicy = config.EmailVerificationPolicy{CodeTTL: 10 * time.Minute, MaxAttempts: 3, ResendCooldown: time.Minute}
+	verificationCode       = regexp.MustCompile(`code is (\d{6})`)
+)
+
+// sendCode sends alice a verification code and returns it
+func sendCode(t *testing.T, box *mailbox, emailCfg config.EmailConfig, db *gorm.DB) string {
+	t.Helper()
+	if err := SendEmailVerification(context.Background(), "alice@example.com", testVerificationPolicy, emailCfg, db); err != nil {
+		t.Fatal(err)
+	}
+	return box.last(t, verificationCode)
+}
+
+// wrongCode is any six digits other than code
+func wrongCode(code string) string {
+	var n int
+	fmt.Sscanf(code, "%d", &n)
+	return fmt.Sprintf("%06d", (n+1)%1000000)
+}
+
+func isVerified(t *testing.T, db *gorm.DB) bool {
+	t.Helper()
+	var user models.User
+	if err := db.First(&user, "username = ?", "alice").Error; err != nil {
+		t.Fatal(err)
+	}
+	return user.IsVerified
+}
+
+func TestVerifyEmailCode(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	code := sendCode(t, box, emailCfg, db)
+
+	if err := VerifyEmailCode("alice@example.com", wrongCode(code), testVerificationPolicy, db); !errors.Is(err, ErrInvalidVerificationCode) {
+		t.Errorf("wrong code: %v, want ErrInvalidVerificationCode", err)
+	}
+	if err := VerifyEmailCode("alice@example.com", code, testVerificationPolicy, db); err != nil {
+		t.Fatalf("right code: %v", err)
+	}
+	if !isVerified(t, db) {
+		t.Error("user not verified")
+	}
+	if err := VerifyEmailCode("alice@example.com", code, testVerificationPolicy, db); !errors.Is(err, ErrNoVerificationPending) {
+		t.Errorf("code used twice: %v, want ErrNoVerificationPending", err)
+	}
+}
+
+func TestVerifyEmailCodeAttemptLimit(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	code := sendCode(t, box, emailCfg, db)
+
+	for i := 1; i <= testVerificationPolicy.MaxAttempts; i++ {
+		if err := VerifyEmailCode("alice@example.com", wrongCode(code), testVerificationPolicy, db); !errors.Is(err, ErrInvalidVerificationCode) {
+			t.Fatalf("wrong code %d: %v, want ErrInvalidVerificationCode", i, err)
+		}
+	}
+	// Once the guesses run out even the right code is refused
+	if err := VerifyEmailCode("alice@example.com", code, testVerificationPolicy, db); !errors.Is(err, ErrTooManyVerifyAttempts) {
+		t.Errorf("right code after the limit: %v, want ErrTooManyVerifyAttempts", err)
+	}
+	if isVerified(t, db) {
+		t.Error("user verified after the attempt limit")
+	}
+
+	// A new code comes with a fresh count
+	db.Model(&models.EmailVerification{}).Where("1 = 1").Update("last_sent_at", time.Now().Add(-testVerificationPolicy.ResendCooldown))
+	code = sendCode(t, box, emailCfg, db)
+	if err := VerifyEmailCode("alice@example.com", code, testVerificationPolicy, db); err != nil {
+		t.Errorf("new code after the limit: %v", err)
+	}
+}
+
+func TestVerifyEmailCodeRejectsAnExpiredCode(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	code := sendCode(t, box, emailCfg, db)
+
+	db.Model(&models.EmailVerification{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second))
+
+	if err := VerifyEmailCode("alice@example.com", code, testVerificationPolicy, db); !errors.Is(err, ErrVerificationExpired) {
+		t.Errorf("expired code: %v, want ErrVerificationExpired", err)
+	}
+	if isVerified(t, db) {
+		t.Error("user verified with an expired code")
+	}
+}
+
+func TestSendEmailVerificationResendCooldown(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	first := sendCode(t, box, emailCfg, db)
+
+	err := SendEmailVerification(context.Background(), "alice@example.com", testVerificationPolicy, emailCfg, db)
+	if !errors.Is(err, ErrVerificationResendTooSoon) {
+		t.Fatalf("resend within the cooldown: %v, want ErrVerificationResendTooSoon", err)
+	}
+	if box.count() != 1 {
+		t.Errorf("%d emails sent, want the resend refused without one", box.count())
+	}
+
+	db.Model(&models.EmailVerification{}).Where("1 = 1").Update("last_sent_at", time.Now().Add(-testVerificationPolicy.ResendCooldown))
+	second := sendCode(t, box, emailCfg, db)
+
+	// The new code replaces the old one
+	if first != second {
+		if err := VerifyEmailCode("alice@example.com", first, testVerificationPolicy, db); !errors.Is(err, ErrInvalidVerificationCode) {
+			t.Errorf("replaced code: %v, want ErrInvalidVerificationCode", err)
+		}
+	}
+	if err := VerifyEmailCode("alice@example.com", second, testVerificationPolicy, db); err != nil {
+		t.Errorf("new code: %v", err)
+	}
+}
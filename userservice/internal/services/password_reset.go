@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"user-service/internal/config"
+	"user-service/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTooManyResetRequests = errors.New("too many password reset requests, try again later")
+	ErrInvalidResetToken    = errors.New("reset link is invalid or has expired")
+)
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset emails a single use reset link. Requests are limited per normalized
+// email. An unknown email is not an error, but callers must also answer the same way when
+// this fails, or the limit and send errors tell which addresses have an account.
+func RequestPasswordReset(ctx context.Context, email string, policy config.PasswordResetPolicy, emailCfg config.EmailConfig, db *gorm.DB) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return errors.New("email cannot be empty")
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if user.Status != models.StatusActive {
+		return nil
+	}
+
+	var recent int64
+	if err := db.Model(&models.PasswordResetToken{}).
+		Where("email = ? AND created_at > ?", email, time.Now().Add(-policy.Window)).
+		Count(&recent).Error; err != nil {
+		return fmt.Errorf("error counting reset requests: %w", err)
+	}
+	if int(recent) >= policy.MaxRequests {
+		return ErrTooManyResetRequests
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	resetToken := models.PasswordResetToken{
+		TokenHash: hashResetToken(token),
+		Email:     email,
+		ExpiresAt: time.Now().Add(policy.TokenTTL),
+		UserID:    user.ID,
+	}
+	if err := db.Create(&resetToken).Error; err != nil {
+		return fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	link := policy.ResetURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+		"Open the link below within %d minutes to choose a new one:\n\n%s\n\n"+
+		"If it was not you, ignore this email and your password will stay the same.",
+		user.Username, int(policy.TokenTTL.Minutes()), link)
+	if err := SendEmail(ctx, emailCfg, user.Email, "Reset your password", body); err != nil {
+		// Without the email the token is useless, drop it so it does not count against the limit
+		db.Delete(&resetToken)
+		return err
+	}
+	return nil
+}
+
+// ResetPassword spends a reset token on a new password. Every other outstanding token
+// for the user is spent with it.
+func ResetPassword(token, newPassword string, db *gorm.DB) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidResetToken
+	}
+
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashResetToken(token)).
+			First(&resetToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			return err
+		}
+		if user.Status != models.StatusActive {
+			return ErrInvalidResetToken
+		}
+
+		if err := user.SetPassword(tx, newPassword); err != nil {
+			return err
+		}
+		// Proving control of the email is enough to lift a failed login lock
+		if user.AccountLocked {
+			if err := user.Unlock(tx); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return &user, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+	"user-service/internal/config"
+	"user-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// mailbox stands in for google-service and keeps the bodies of the emails it is asked to send
+type mailbox struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newMailbox(t *testing.T) (*mailbox, config.EmailConfig) {
+	t.Helper()
+	box := &mailbox{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		box.mu.Lock()
+		defer box.mu.Unlock()
+		box.bodies = append(box.bodies, r.URL.Query().Get("body"))
+	}))
+	t.Cleanup(server.Close)
+	return box, config.EmailConfig{GoogleServiceURL: server.URL, APIKey: "test"}
+}
+
+func (b *mailbox) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.bodies)
+}
+
+// last returns the first match of pattern's group in the latest email
+func (b *mailbox) last(t *testing.T, pattern *regexp.Regexp) string {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.bodies) == 0 {
+		t.Fatal("no email sent")
+	}
+	match := pattern.FindStringSubmatch(b.bodies[len(b.bodies)-1])
+	if match == nil {
+		t.Fatalf("email %q does not match %s", b.bodies[len(b.bodies)-1], pattern)
+	}
+	return match[1]
+}
+
+var (
+	testResetPolicy = config.PasswordResetPolicy{TokenTTL: 30 * time.Minute, MaxRequests: 3, Window: time.Hour, ResetURL: "https://app.test/reset"}
+	resetTokenLink  = regexp.MustCompile(`\?token=(\S+)`)
+)
+
+// requestReset asks for a reset link for alice and returns the token it carries
+func requestReset(t *testing.T, box *mailbox, emailCfg config.EmailConfig, db *gorm.DB) string {
+	t.Helper()
+	if err := RequestPasswordReset(context.Background(), "alice@example.com", testResetPolicy, emailCfg, db); err != nil {
+		t.Fatal(err)
+	}
+	token, err := url.QueryUnescape(box.last(t, resetTokenLink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestResetPasswordIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	token := requestReset(t, box, emailCfg, db)
+
+	user, err := ResetPassword(token, "new-password", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored models.User
+	db.First(&stored, user.ID)
+	if stored.CheckPassword("new-password") != nil {
+		t.Error("password was not changed")
+	}
+
+	if _, err := ResetPassword(token, "third-password", db); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("second use of the token: %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestResetPasswordRejectsAnExpiredToken(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	token := requestReset(t, box, emailCfg, db)
+
+	db.Model(&models.PasswordResetToken{}).Where("1 = 1").Update("expires_at", time.Now().Add(-time.Second))
+
+	if _, err := ResetPassword(token, "new-password", db); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expired token: %v, want ErrInvalidResetToken", err)
+	}
+	var stored models.User
+	db.First(&stored, "username = ?", "alice")
+	if stored.CheckPassword("password123") != nil {
+		t.Error("an expired token changed the password")
+	}
+}
+
+func TestResetPasswordSpendsEarlierTokens(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	earlier := requestReset(t, box, emailCfg, db)
+	later := requestReset(t, box, emailCfg, db)
+
+	if _, err := ResetPassword(later, "new-password", db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResetPassword(earlier, "other-password", db); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("earlier token after a reset: %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestRequestPasswordResetLimitsRequestsPerEmail(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	box, emailCfg := newMailbox(t)
+	ctx := context.Background()
+
+	// The limit counts the normalized address, however it is typed
+	for _, email := range []string{"alice@example.com", "ALICE@example.com", " alice@example.com "} {
+		if err := RequestPasswordReset(ctx, email, testResetPolicy, emailCfg, db); err != nil {
+			t.Fatalf("request for %q: %v", email, err)
+		}
+	}
+	if err := RequestPasswordReset(ctx, "Alice@Example.com", testResetPolicy, emailCfg, db); !errors.Is(err, ErrTooManyResetRequests) {
+		t.Fatalf("request past the limit: %v, want ErrTooManyResetRequests", err)
+	}
+	if box.count() != testResetPolicy.MaxRequests {
+		t.Errorf("%d emails sent, want %d", box.count(), testResetPolicy.MaxRequests)
+	}
+
+	// Requests older than the window stop counting
+	db.Model(&models.PasswordResetToken{}).Where("1 = 1").Update("created_at", time.Now().Add(-testResetPolicy.Window-time.Minute))
+	if err := RequestPasswordReset(ctx, "alice@example.com", testResetPolicy, emailCfg, db); err != nil {
+		t.Errorf("request after the window: %v", err)
+	}
+}
+
+func TestRequestPasswordResetForAnUnknownEmail(t *testing.T) {
+	db := newTestDB(t)
+	box, emailCfg := newMailbox(t)
+
+	if err := RequestPasswordReset(context.Background(), "nobody@example.com", testResetPolicy, emailCfg, db); err != nil {
+		t.Errorf("unknown email: %v, want no error", err)
+	}
+	if box.count() != 0 {
+		t.Errorf("%d emails sent to an unknown address", box.count())
+	}
+}
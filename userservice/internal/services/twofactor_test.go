@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// The RFC 6238 Appendix B secret for HMAC-SHA1
+var rfcKey = []byte("12345678901234567890")
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// Appendix B lists 8 digit codes, a 6 digit code is their last six digits
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		if got := totpCode(rfcKey, tc.unix/totpPeriod); got != tc.want[2:] {
+			t.Errorf("code at %d = %s, want %s", tc.unix, got, tc.want[2:])
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := base32NoPadding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	for _, tc := range []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", totpCode(rfcKey, step), 0, step, true},
+		{"one step behind", totpCode(rfcKey, step-1), 0, step - 1, true},
+		{"one step ahead", totpCode(rfcKey, step+1), 0, step + 1, true},
+		{"two steps behind", totpCode(rfcKey, step-2), 0, 0, false},
+		{"replayed step", totpCode(rfcKey, step), step, 0, false},
+		{"step before the last one used", totpCode(rfcKey, step-1), step, 0, false},
+		{"wrong length", "12345", 0, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gotStep, ok := verifyTOTP(secret, tc.code, tc.lastStep, now)
+			if ok != tc.wantOK || gotStep != tc.wantStep {
+				t.Errorf("verifyTOTP = %d %v, want %d %v", gotStep, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+}
+
+// enableTwoFactor enrolls the user and activates 2FA with the current code, returning the
+// enrollment and the step that activation used up
+func enableTwoFactor(t *testing.T, db *gorm.DB, username string) (*TwoFactorEnrollment, int64) {
+	t.Helper()
+	enrollment, err := EnrollTwoFactor(username, "Test", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := base32NoPadding.DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := time.Now().Unix() / totpPeriod
+	if err := ActivateTwoFactor(username, totpCode(key, step), db); err != nil {
+		t.Fatal(err)
+	}
+	return enrollment, step
+}
+
+func TestVerifyTwoFactorRejectsAReplayedCode(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	enrollment, step := enableTwoFactor(t, db, "alice")
+	key, _ := base32NoPadding.DecodeString(enrollment.Secret)
+
+	if err := VerifyTwoFactor("alice", totpCode(key, step), db); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("code used for activation accepted again at login: %v", err)
+	}
+
+	next := totpCode(key, step+1)
+	if err := VerifyTwoFactor("alice", next, db); err != nil {
+		t.Fatalf("next step's code: %v", err)
+	}
+	if err := VerifyTwoFactor("alice", next, db); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("same code twice: %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	var user models.User
+	db.First(&user, "username = ?", "alice")
+	if user.TwoFactorLastStep != step+1 {
+		t.Errorf("two_factor_last_step = %d, want %d", user.TwoFactorLastStep, step+1)
+	}
+}
+
+func TestConsumeTwoFactorCodeLosesTheRaceOnAStaleRead(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	enrollment, step := enableTwoFactor(t, db, "alice")
+	key, _ := base32NoPadding.DecodeString(enrollment.Secret)
+
+	// Two logins read the user before either has stored the step
+	var first, second models.User
+	db.First(&first, "username = ?", "alice")
+	db.First(&second, "username = ?", "alice")
+
+	code := totpCode(key, step+1)
+	if err := consumeTwoFactorCode(&first, code, db); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if err := consumeTwoFactorCode(&second, code, db); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("second login with the same code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+	enrollment, _ := enableTwoFactor(t, db, "alice")
+	codes := enrollment.RecoveryCodes
+
+	if err := VerifyTwoFactor("alice", codes[0], db); err != nil {
+		t.Fatalf("first use of a recovery code: %v", err)
+	}
+	if err := VerifyTwoFactor("alice", codes[0], db); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("second use of a recovery code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	// The others stay usable, however they are typed
+	if err := VerifyTwoFactor("alice", strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")), db); err != nil {
+		t.Errorf("another recovery code without its dash: %v", err)
+	}
+	if err := VerifyTwoFactor("alice", codes[1], db); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("recovery code reused with its dash: %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	var user models.User
+	db.First(&user, "username = ?", "alice")
+	var left []string
+	if err := json.Unmarshal([]byte(user.TwoFactorRecoveryCodes), &left); err != nil || len(left) != recoveryCodeCount-2 {
+		t.Errorf("%d recovery codes left (%v), want %d", len(left), err, recoveryCodeCount-2)
+	}
+}
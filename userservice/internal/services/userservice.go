@@ -4,17 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
+	"log/slog"
+	"platform/contracts"
 	"strings"
+	"time"
+	"user-service/internal/config"
 	"user-service/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var LoginAttempt = make(map[string]int)
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrAccountInvalid = errors.New("account invalid")
+)
 
 // Login
-func FindUserWithUsernamePassword(username, password string, db *gorm.DB) (*models.User, error) {
+func FindUserWithUsernamePassword(username, password string, policy config.LoginPolicy, db *gorm.DB) (*models.User, error) {
 	var user models.User
 	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -23,28 +30,126 @@ func FindUserWithUsernamePassword(username, password string, db *gorm.DB) (*mode
 		return nil, fmt.Errorf("database error: %v", err)
 	}
 	if err := user.IsAccountValid(); err != nil {
+		if errors.Is(err, models.ErrAccountLocked) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("account invalid: %v", err)
 	}
 
 	// Check password
 	if err := user.CheckPassword(password); err != nil {
-		if login_attempt, exist := LoginAttempt[user.Username]; exist {
-			if login_attempt == 5 {
-				user.Lock(10)
-				return nil, fmt.Errorf("account is temporary locked due to exceeding number of logins allowed")
-			}
-			LoginAttempt[user.Username] = login_attempt + 1
-		} else {
-			LoginAttempt[user.Username] = 0
+		locked, recordErr := recordFailedLogin(user.ID, policy, db)
+		if recordErr != nil {
+			slog.Error("Record failed login failed", "username", username, "error", recordErr)
+		}
+		if locked {
+			return nil, models.ErrAccountLocked
 		}
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
+	if err := recordSuccessfulLogin(&user, db); err != nil {
+		slog.Error("Record successful login failed", "username", username, "error", err)
+	}
+
 	// Clear password before returning
 	user.Password = ""
 	return &user, nil
 }
 
+// recordFailedLogin bumps the counter under a row lock so concurrent attempts on
+// several replicas cannot slip past the threshold, and locks the account once it is reached
+func recordFailedLogin(userID uint, policy config.LoginPolicy, db *gorm.DB) (bool, error) {
+	locked := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		// A lock that has run out starts a fresh count
+		attempts := user.FailedLoginAttempts
+		if user.AccountLocked && !user.IsLocked() {
+			attempts = 0
+		}
+		attempts++
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			UpdateColumn("failed_login_attempts", attempts).Error; err != nil {
+			return err
+		}
+
+		if attempts >= policy.MaxFailedAttempts {
+			locked = true
+			return user.Lock(tx, time.Now().Add(policy.LockDuration))
+		}
+		return nil
+	})
+	return locked, err
+}
+
+func recordSuccessfulLogin(user *models.User, db *gorm.DB) error {
+	now := time.Now().Unix()
+	err := db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"account_locked":        false,
+		"locked_until":          nil,
+		"last_login_at":         now,
+	}).Error
+	if err != nil {
+		return err
+	}
+	user.FailedLoginAttempts = 0
+	user.AccountLocked = false
+	user.LockedUntil = nil
+	user.LastLoginAt = &now
+	return nil
+}
+
+// UnlockUser lets an admin lift a lock before it runs out
+func UnlockUser(username string, db *gorm.DB) (*models.User, error) {
+	if username == "" {
+		return nil, errors.New("username cannot be empty")
+	}
+
+	var user models.User
+	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+
+	if err := user.Unlock(db); err != nil {
+		return nil, fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	user.Password = ""
+	return &user, nil
+}
+
+// FindActiveUserByEmail is the sign-in lookup for callers that authenticated the user elsewhere (Google).
+// It applies the same account checks as a password login.
+func FindActiveUserByEmail(email string, db *gorm.DB) (*models.User, error) {
+	if email == "" {
+		return nil, errors.New("email cannot be empty")
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if err := user.IsAccountValid(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAccountInvalid, err)
+	}
+
+	user.Password = ""
+	return &user, nil
+}
+
 func RegisterUserWithRole(params models.UserCreationParams, google_access_token string, db *gorm.DB) error {
 	// Start a database transaction
 	return db.Transaction(func(tx *gorm.DB) error {
@@ -54,6 +159,7 @@ func RegisterUserWithRole(params models.UserCreationParams, google_access_token
 			Role:     models.UserRole(params.Role),
 			Status:   models.StatusActive,
 			Password: params.Password,
+			Picture:  params.Picture,
 		}
 
 		// Set optional fields if provided
@@ -93,7 +199,7 @@ func RegisterUserWithRole(params models.UserCreationParams, google_access_token
 
 func AddUser(params models.UserCreationParams, had_admin bool, google_access_token string, db *gorm.DB) error {
 	// Check for existing username
-	fmt.Printf("Adding user with username: %s\n", params.Username)
+	slog.Info("Adding user", "username", params.Username)
 
 	var existingUser models.User
 	if err := db.Where("username = ?", params.Username).First(&existingUser).Error; err == nil {
@@ -197,7 +303,7 @@ func FindUserWithUsername(username string, db *gorm.DB) (*map[string]interface{}
 			var specialties []models.TutorSpecialty
 			if err := db.Where("tutor_id = ?", user.ID).Find(&specialties).Error; err != nil {
 				// Just log the error but continue
-				fmt.Printf("Error fetching tutor specialties: %v\n", err)
+				slog.Error("Fetching tutor specialties failed", "error", err)
 			} else if len(specialties) > 0 {
 				specialtiesData, _ := json.Marshal(specialties)
 				var specialtiesMap []map[string]interface{}
@@ -209,7 +315,7 @@ func FindUserWithUsername(username string, db *gorm.DB) (*map[string]interface{}
 			// Fetch availability
 			var availability models.Availability
 			if err := db.Where("tutor_id = ?", user.ID).First(&availability).Error; err != nil {
-				fmt.Printf("Error fetching tutor availability: %v\n", err)
+				slog.Error("Fetching tutor availability failed", "error", err)
 			} else {
 				// Fetch days of availability
 				var days []models.DayAvailability
@@ -251,7 +357,7 @@ func FindUserWithUsername(username string, db *gorm.DB) (*map[string]interface{}
 			// Fetch children
 			var children []models.Children
 			if err := db.Where("parent_id = ?", user.ID).Find(&children).Error; err != nil {
-				fmt.Printf("Error fetching children: %v\n", err)
+				slog.Error("Fetching children failed", "error", err)
 			} else if len(children) > 0 {
 				childrenData, _ := json.Marshal(children)
 				var childrenMap []map[string]interface{}
@@ -270,7 +376,7 @@ func FindUserWithUsername(username string, db *gorm.DB) (*map[string]interface{}
 	return &response, nil
 }
 
-func GetAllUser(db *gorm.DB, page, limit int, filters map[string]interface{}) (*models.PaginatedResponse, error) {
+func GetAllUser(db *gorm.DB, page, limit int, filters map[string]interface{}) (*contracts.Page[contracts.User], error) {
 	// Default values if not provided
 	if page <= 0 {
 		page = 1
@@ -345,22 +451,14 @@ func GetAllUser(db *gorm.DB, page, limit int, filters map[string]interface{}) (*
 		return nil, fmt.Errorf("error fetching users: %w", result.Error)
 	}
 
+	data := make([]contracts.User, len(users))
 	for i := range users {
-		users[i].Password = ""
+		data[i] = users[i].Contract()
 	}
 
-	// Calculate total pages
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
-
-	// Prepare paginated response
-	response := &models.PaginatedResponse{
-		Data: users,
-		Pagination: models.Pagination{
-			Total:      total,
-			Page:       page,
-			PageSize:   limit,
-			TotalPages: totalPages,
-		},
+	response := &contracts.Page[contracts.User]{
+		Data:       data,
+		Pagination: contracts.NewPagination(total, page, limit),
 	}
 
 	return response, nil
@@ -472,7 +570,7 @@ func UpdateUser(username string, userId string, params models.UserUpdateParams,
 	if params.Status != "" && user.Role == models.RoleAdmin {
 		// Validate status
 		switch models.UserStatus(params.Status) {
-		case models.StatusActive, models.StatusBanned, models.StatusDeleted:
+		case models.StatusActive, models.StatusSuspended, models.StatusBanned, models.StatusDeleted:
 			updates["status"] = params.Status
 		default:
 			return nil, fmt.Errorf("invalid status value")
@@ -625,7 +723,7 @@ func VerifyUser(username string, db *gorm.DB) error {
 
 	// Update verification status
 	if err := db.Model(&user).Update("is_verified", true).Error; err != nil {
-		fmt.Printf("Error updating user verification status: %v\n", err)
+		slog.Error("Updating user verification status failed", "username", username, "error", err)
 		return err
 	}
 	return nil
@@ -747,10 +845,10 @@ func AssignRole(username string, newRole string, db *gorm.DB) error {
 	// Validate role
 	newRoleEnum := models.UserRole(newRole)
 	validRoles := map[models.UserRole]bool{
-		models.RoleParent: true,
-		models.RoleTutor:  true,
-		models.RoleKid:    true,
-		models.RoleAdmin:  true,
+		models.RoleParent:   true,
+		models.RoleTutor:    true,
+		models.RoleChildren: true,
+		models.RoleAdmin:    true,
 	}
 
 	if !validRoles[newRoleEnum] {
@@ -912,6 +1010,19 @@ func IsUserActiveByID(userID uint, db *gorm.DB) (bool, error) {
 	return user.Status == models.StatusActive, nil
 }
 
+// FindUserByID looks an account up whatever its status, for services that only hold the id
+func FindUserByID(id uint, db *gorm.DB) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	user.Password = ""
+	return &user, nil
+}
+
 func FindUserIDByEmail(email string, db *gorm.DB) (uint, error) {
 	var user models.User
 	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"user-service/internal/config"
+	"user-service/internal/models"
+)
+
+var testLoginPolicy = config.LoginPolicy{MaxFailedAttempts: 3, LockDuration: 10 * time.Minute}
+
+func TestRecordFailedLogin(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Unix()
+	future := time.Now().Add(time.Minute).Unix()
+
+	for _, tc := range []struct {
+		name         string
+		attempts     int
+		locked       bool
+		lockedUntil  *int64
+		wantAttempts int
+		wantLocked   bool
+	}{
+		{"first failure", 0, false, nil, 1, false},
+		{"one below the threshold", 1, false, nil, 2, false},
+		{"reaching the threshold", 2, false, nil, 3, true},
+		{"past the threshold", 5, false, nil, 6, true},
+		{"while still locked", 3, true, &future, 4, true},
+		{"after the lock ran out", 3, true, &past, 1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			user := createUser(t, db, "alice")
+			db.Model(user).UpdateColumns(map[string]interface{}{
+				"failed_login_attempts": tc.attempts,
+				"account_locked":        tc.locked,
+				"locked_until":          tc.lockedUntil,
+			})
+
+			before := time.Now()
+			locked, err := recordFailedLogin(user.ID, testLoginPolicy, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locked != tc.wantLocked {
+				t.Errorf("locked = %v, want %v", locked, tc.wantLocked)
+			}
+
+			var got models.User
+			db.First(&got, user.ID)
+			if got.FailedLoginAttempts != tc.wantAttempts {
+				t.Errorf("failed_login_attempts = %d, want %d", got.FailedLoginAttempts, tc.wantAttempts)
+			}
+			if got.IsLocked() != tc.wantLocked {
+				t.Errorf("stored lock in force = %v, want %v", got.IsLocked(), tc.wantLocked)
+			}
+			if locked {
+				// The lock window starts at the failure that reached the threshold
+				end := before.Add(testLoginPolicy.LockDuration).Unix()
+				if got.LockedUntil == nil || *got.LockedUntil < end || *got.LockedUntil > end+1 {
+					t.Errorf("locked_until = %v, want %d", got.LockedUntil, end)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+
+	for i := 1; i < testLoginPolicy.MaxFailedAttempts; i++ {
+		if _, err := FindUserWithUsernamePassword("alice", "wrong", testLoginPolicy, db); err == nil || errors.Is(err, models.ErrAccountLocked) {
+			t.Fatalf("wrong password %d: %v, want invalid credentials", i, err)
+		}
+	}
+	if _, err := FindUserWithUsernamePassword("alice", "wrong", testLoginPolicy, db); !errors.Is(err, models.ErrAccountLocked) {
+		t.Fatalf("wrong password at the threshold: %v, want ErrAccountLocked", err)
+	}
+	if _, err := FindUserWithUsernamePassword("alice", "password123", testLoginPolicy, db); !errors.Is(err, models.ErrAccountLocked) {
+		t.Errorf("right password while locked: %v, want ErrAccountLocked", err)
+	}
+}
+
+func TestSuccessfulLoginResetsFailedAttempts(t *testing.T) {
+	db := newTestDB(t)
+	createUser(t, db, "alice")
+
+	for i := 1; i < testLoginPolicy.MaxFailedAttempts; i++ {
+		FindUserWithUsernamePassword("alice", "wrong", testLoginPolicy, db)
+	}
+	user, err := FindUserWithUsernamePassword("alice", "password123", testLoginPolicy, db)
+	if err != nil {
+		t.Fatalf("right password below the threshold: %v", err)
+	}
+	if user.FailedLoginAttempts != 0 || user.LastLoginAt == nil {
+		t.Errorf("returned user has %d failed attempts and last login %v, want 0 and set", user.FailedLoginAttempts, user.LastLoginAt)
+	}
+
+	// The count starts over, so one more wrong password does not lock the account
+	if _, err := FindUserWithUsernamePassword("alice", "wrong", testLoginPolicy, db); errors.Is(err, models.ErrAccountLocked) {
+		t.Error("wrong password after a successful login locked the account")
+	}
+	var stored models.User
+	db.First(&stored, "username = ?", "alice")
+	if stored.FailedLoginAttempts != 1 {
+		t.Errorf("failed_login_attempts = %d, want 1", stored.FailedLoginAttempts)
+	}
+}
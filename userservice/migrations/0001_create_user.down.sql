@@ -0,0 +1,4 @@
+-- Fails while nodeservice tables still reference User, drop those first
+DROP TABLE IF EXISTS `EmailVerification`;
+DROP TABLE IF EXISTS `PasswordResetToken`;
+DROP TABLE IF EXISTS `User`;
@@ -0,0 +1,65 @@
+-- Baseline, matching what prisma db push created before migrations existed.
+-- IF NOT EXISTS lets it adopt tables nodeservice has already pushed.
+CREATE TABLE IF NOT EXISTS `User` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `deleted_at` DATETIME(3) NULL,
+    `username` VARCHAR(50) NOT NULL,
+    `password` VARCHAR(255) NOT NULL,
+    `email` VARCHAR(255) NULL,
+    `role` ENUM('Parent', 'Children', 'Tutor', 'Admin') NOT NULL DEFAULT 'Parent',
+    `phone` VARCHAR(20) NULL,
+    `full_name` VARCHAR(255) NULL,
+    `google_token` TEXT NULL,
+    `picture` VARCHAR(191) NULL,
+    `is_verified` BOOLEAN NOT NULL DEFAULT false,
+    `status` ENUM('Active', 'Suspended', 'Banned') NOT NULL DEFAULT 'Active',
+    `last_login_at` BIGINT NULL,
+    `account_locked` BOOLEAN NOT NULL DEFAULT false,
+    `failed_login_attempts` INTEGER NOT NULL DEFAULT 0,
+    `locked_until` BIGINT NULL,
+    `password_changed_at` BIGINT NULL,
+    `two_factor_enabled` BOOLEAN NOT NULL DEFAULT false,
+    `two_factor_secret` VARCHAR(64) NULL,
+    `two_factor_recovery_codes` TEXT NULL,
+    `two_factor_last_step` BIGINT NOT NULL DEFAULT 0,
+
+    UNIQUE INDEX `User_username_key`(`username`),
+    UNIQUE INDEX `User_email_key`(`email`),
+    INDEX `User_status_idx`(`status`),
+    INDEX `User_role_idx`(`role`),
+    INDEX `User_deleted_at_idx`(`deleted_at`),
+    PRIMARY KEY (`id`)
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `PasswordResetToken` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `token_hash` VARCHAR(64) NOT NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    `used_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `user_id` INTEGER NOT NULL,
+
+    UNIQUE INDEX `PasswordResetToken_token_hash_key`(`token_hash`),
+    INDEX `PasswordResetToken_user_id_idx`(`user_id`),
+    INDEX `PasswordResetToken_created_at_idx`(`created_at`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `PasswordResetToken_user_id_fkey` FOREIGN KEY (`user_id`) REFERENCES `User`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `EmailVerification` (
+    `id` INTEGER NOT NULL AUTO_INCREMENT,
+    `email` VARCHAR(255) NOT NULL,
+    `code_hash` VARCHAR(255) NOT NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    `attempts` INTEGER NOT NULL DEFAULT 0,
+    `last_sent_at` DATETIME(3) NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `user_id` INTEGER NOT NULL,
+
+    UNIQUE INDEX `EmailVerification_email_key`(`email`),
+    INDEX `EmailVerification_user_id_idx`(`user_id`),
+    PRIMARY KEY (`id`),
+    CONSTRAINT `EmailVerification_user_id_fkey` FOREIGN KEY (`user_id`) REFERENCES `User`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
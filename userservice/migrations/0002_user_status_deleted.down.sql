@@ -0,0 +1,3 @@
+-- Deleted accounts become Suspended, the closest status the old enum has
+UPDATE `User` SET `status` = 'Suspended' WHERE `status` = 'Deleted';
+ALTER TABLE `User` MODIFY `status` ENUM('Active', 'Suspended', 'Banned') NOT NULL DEFAULT 'Active';
@@ -0,0 +1,2 @@
+-- user-service soft deletes by setting status to Deleted, which the enum did not allow
+ALTER TABLE `User` MODIFY `status` ENUM('Active', 'Suspended', 'Banned', 'Deleted') NOT NULL DEFAULT 'Active';
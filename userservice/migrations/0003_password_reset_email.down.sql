@@ -0,0 +1,2 @@
+DROP INDEX `PasswordResetToken_email_created_at_idx` ON `PasswordResetToken`;
+ALTER TABLE `PasswordResetToken` DROP COLUMN `email`;
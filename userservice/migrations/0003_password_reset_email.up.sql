@@ -0,0 +1,4 @@
+-- Reset requests are limited per normalized email, so the address a request was made for is kept with its token
+ALTER TABLE `PasswordResetToken` ADD COLUMN `email` VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE `PasswordResetToken` t JOIN `User` u ON u.`id` = t.`user_id` SET t.`email` = LOWER(TRIM(u.`email`));
+CREATE INDEX `PasswordResetToken_email_created_at_idx` ON `PasswordResetToken`(`email`, `created_at`);
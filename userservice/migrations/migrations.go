@@ -0,0 +1,8 @@
+// Package migrations holds user-service's schema history. The User table is shared with
+// nodeservice, whose Prisma schema mirrors it and must be kept in step with these files.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"time"
 )
@@ -10,11 +10,11 @@ func SetupTimeZone() {
 	// Set default timezone to Asia/Ho_Chi_Minh
 	loc, err := time.LoadLocation(os.Getenv("TZ"))
 	if err != nil {
-		log.Printf("Failed to load %s location: %v", loc.String(), err)
+		slog.Warn("Failed to load time zone", "tz", os.Getenv("TZ"), "error", err)
 		return
 	}
 
 	// Set the default timezone
 	time.Local = loc
-	log.Printf("Default timezone set to: %s", time.Local.String())
+	slog.Info("Default time zone set", "tz", time.Local.String())
 }